@@ -1,9 +1,15 @@
 package swarm
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // APITokensService handles communication to the apitokens API endpoint
@@ -11,12 +17,111 @@ type APITokensService service
 
 const apiTokensPath = "authenticated/apitokens"
 
-// APIToken type, currently just a string as no other metadata exists
+// APIToken is the value of an API token. It is masked when printed or encoded
+// to JSON, showing only its last 4 characters, so tokens do not leak into
+// logs; Reveal returns the value. Never save a token with encoding/json, see
+// MarshalJSON.
 type APIToken string
 
+// maskedTokenSuffix is the number of characters String leaves visible
+const maskedTokenSuffix = 4
+
+// Reveal returns the value of the token
+func (t APIToken) Reveal() string {
+	return string(t)
+}
+
+// String returns the token masked, such as "****F467". Tokens too short to
+// hide anything are masked entirely.
+func (t APIToken) String() string {
+	if len(t) <= maskedTokenSuffix*2 {
+		return "****"
+	}
+	return "****" + string(t[len(t)-maskedTokenSuffix:])
+}
+
+// Format prints the masked token for every verb, so the value cannot be
+// printed by accident with %v, %s, %q, %x or %#v
+func (t APIToken) Format(f fmt.State, verb rune) {
+	s := t.String()
+	if verb == 'q' {
+		s = fmt.Sprintf("%q", s)
+	}
+	if width, ok := f.Width(); ok && width > len(s) {
+		pad := strings.Repeat(" ", width-len(s))
+		if f.Flag('-') {
+			s += pad
+		} else {
+			s = pad + s
+		}
+	}
+	fmt.Fprint(f, s)
+}
+
+// Fingerprint returns the hex encoded SHA-256 hash of the token, which
+// identifies it in logs and audit records without revealing it
+func (t APIToken) Fingerprint() string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+// MarshalJSON encodes the masked token.
+//
+// Warning: this is lossy. A token encoded to JSON cannot be decoded back and
+// authenticates nothing, and the same goes for structs holding tokens, such
+// as APITokenInfo. To store or send a token, encode Reveal() instead.
+func (t APIToken) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes a token from a string, or from the token member of an
+// object as returned by APIs reporting metadata, see APITokenInfo
+func (t *APIToken) UnmarshalJSON(data []byte) error {
+	var info APITokenInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return err
+	}
+	*t = info.Token
+	return nil
+}
+
+// APITokenInfo is an API token with the metadata the API reports about it.
+// Metadata the API does not report is left empty, and members this client
+// does not know about are kept in Extra.
+type APITokenInfo struct {
+	Token      APIToken                   `json:"token"`
+	CreatedAt  *time.Time                 `json:"createdAt,omitempty"`
+	LastUsedAt *time.Time                 `json:"lastUsedAt,omitempty"`
+	Extra      map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes either a bare token string or an object
+func (i *APITokenInfo) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		var s string
+		if err := json.Unmarshal(trimmed, &s); err != nil {
+			return err
+		}
+		*i = APITokenInfo{Token: APIToken(s)}
+		return nil
+	}
+	type alias struct {
+		Token      string     `json:"token"`
+		CreatedAt  *time.Time `json:"createdAt,omitempty"`
+		LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	}
+	a := &alias{}
+	extra, err := unmarshalWithExtra(data, a)
+	if err != nil {
+		return err
+	}
+	*i = APITokenInfo{Token: APIToken(a.Token), CreatedAt: a.CreatedAt, LastUsedAt: a.LastUsedAt, Extra: extra}
+	return nil
+}
+
 // List all API tokens
 func (s *APITokensService) List(ctx context.Context) ([]*APIToken, *http.Response, error) {
-	req, err := s.client.NewRequestWithBaseURL("GET", apiTokensPath, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "GET", apiTokensPath, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -32,7 +137,7 @@ func (s *APITokensService) List(ctx context.Context) ([]*APIToken, *http.Respons
 
 // Create an API token
 func (s *APITokensService) Create(ctx context.Context) (*APIToken, *http.Response, error) {
-	req, err := s.client.NewRequestWithBaseURL("POST", apiTokensPath, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "POST", apiTokensPath, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -46,10 +151,26 @@ func (s *APITokensService) Create(ctx context.Context) (*APIToken, *http.Respons
 	return t, resp, nil
 }
 
+// ListInfo lists all API tokens with their metadata
+func (s *APITokensService) ListInfo(ctx context.Context) ([]*APITokenInfo, *http.Response, error) {
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "GET", apiTokensPath, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var t []*APITokenInfo
+	resp, err := s.client.DoRequest(ctx, req, &t)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return t, resp, nil
+}
+
 // Delete an API token by value
 func (s *APITokensService) Delete(ctx context.Context, token APIToken) (*http.Response, error) {
-	path := fmt.Sprintf("%s/%s", apiTokensPath, token)
-	req, err := s.client.NewRequestWithBaseURL("DELETE", path, nil)
+	path := fmt.Sprintf("%s/%s", apiTokensPath, token.Reveal())
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "DELETE", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -58,10 +179,24 @@ func (s *APITokensService) Delete(ctx context.Context, token APIToken) (*http.Re
 	return resp, err
 }
 
-// DeleteAll API tokens
-func (s *APITokensService) DeleteAll(ctx context.Context) (*http.Response, error) {
+// DeleteAll API tokens, see PipelinesService.DeleteAll for the options.
+// Backup is not supported, and a dry run lists the tokens masked.
+func (s *APITokensService) DeleteAll(ctx context.Context, opts ...DeleteAllOption) (*http.Response, error) {
+	ok, err := s.client.guardDeleteAll(ctx, "API token", opts, func() ([]string, error) {
+		tokens, _, err := s.List(ctx)
+		var out []string
+		for _, t := range tokens {
+			// never print the token itself
+			out = append(out, t.String())
+		}
+		return out, err
+	})
+	if !ok {
+		return nil, err
+	}
+
 	path := fmt.Sprintf("%s/all", apiTokensPath)
-	req, err := s.client.NewRequestWithBaseURL("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "DELETE", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -2,9 +2,11 @@ package swarm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -80,3 +82,51 @@ func TestAPITokens_DeleteAll(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestAPIToken_Masking(t *testing.T) {
+	var token APIToken = testAPIToken
+	require.Equal(t, "****F467", token.String())
+	for _, format := range []string{"%s", "%v", "%+v", "%#v", "%x", "%d"} {
+		require.Equal(t, "****F467", fmt.Sprintf(format, token), format)
+	}
+	require.Equal(t, `"****F467"`, fmt.Sprintf("%q", token))
+	require.Equal(t, "[****F467]", fmt.Sprintf("%v", []*APIToken{&token}))
+	require.Equal(t, "****F467  |", fmt.Sprintf("%-10s|", token))
+	require.Equal(t, "****", APIToken("SHORT").String())
+
+	b, err := json.Marshal(map[string]APIToken{"token": token})
+	require.NoError(t, err)
+	require.Equal(t, `{"token":"****F467"}`, string(b))
+	// encoding is lossy on purpose
+	var decoded map[string]APIToken
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.NotEqual(t, token, decoded["token"])
+
+	require.Equal(t, testAPIToken, token.Reveal())
+	require.Equal(t, "50d858e0985ecc7f60418aaf0cc5ab587f42c2570a884095a9e8ccacd0f6545c", APIToken("example").Fingerprint())
+}
+
+func TestAPITokens_ListInfo(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/authenticated/apitokens", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		fmt.Fprint(w, `["PLAINTOKEN01", {"token": "`+testAPIToken+`", "createdAt": "2022-07-01T12:00:00Z", "lastUsedAt": "2022-07-02T08:30:00Z", "label": "ci"}]`)
+	})
+
+	ctx := context.Background()
+	infos, _, err := client.APITokens.ListInfo(ctx)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, &APITokenInfo{Token: "PLAINTOKEN01"}, infos[0])
+	require.Equal(t, APIToken(testAPIToken), infos[1].Token)
+	require.Equal(t, time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC), *infos[1].CreatedAt)
+	require.Equal(t, time.Date(2022, 7, 2, 8, 30, 0, 0, time.UTC), *infos[1].LastUsedAt)
+	require.Equal(t, map[string]json.RawMessage{"label": json.RawMessage(`"ci"`)}, infos[1].Extra)
+
+	// List reads the tokens out of objects too
+	tokens, _, err := client.APITokens.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, APIToken(testAPIToken), *tokens[1])
+}
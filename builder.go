@@ -0,0 +1,158 @@
+package swarm
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PipelineBuilder assembles a Pipeline through chained calls, for example:
+//
+//	p, err := swarm.NewPipeline("orders").
+//		Filter("return message.type == 'order';").
+//		Transform("return { id: message.id, total: message.total };").
+//		OutputTo(action.ID).
+//		Retry(time.Minute, 5).
+//		Build()
+//
+// Steps are added in the order the calls are made. Build validates the result.
+// Errors are kept per field, so calling Retry again with a valid interval
+// clears the error of an earlier call.
+type PipelineBuilder struct {
+	p Pipeline
+	// errs holds the error message of each invalid field
+	errs map[string]string
+}
+
+// NewPipeline starts building a pipeline with the given name
+func NewPipeline(name string) *PipelineBuilder {
+	return &PipelineBuilder{
+		p: Pipeline{
+			Name:    name,
+			Steps:   []PipelineSteps{},
+			Outputs: []string{},
+		},
+	}
+}
+
+// Filter adds a required filter step
+func (b *PipelineBuilder) Filter(function string) *PipelineBuilder {
+	return b.Step(FilterStep(function))
+}
+
+// Transform adds a required transform step
+func (b *PipelineBuilder) Transform(function string) *PipelineBuilder {
+	return b.Step(TransformStep(function))
+}
+
+// Step adds an arbitrary step, for steps that are optional, have their own
+// outputs or use a step type without a dedicated builder method
+func (b *PipelineBuilder) Step(s PipelineSteps) *PipelineBuilder {
+	b.p.Steps = append(b.p.Steps, s)
+	return b
+}
+
+// OutputTo adds the IDs of webhook actions or pipelines that receive the
+// pipeline's output
+func (b *PipelineBuilder) OutputTo(ids ...string) *PipelineBuilder {
+	b.p.Outputs = append(b.p.Outputs, ids...)
+	return b
+}
+
+// OutputToAction adds a webhook action as an output of the pipeline
+func (b *PipelineBuilder) OutputToAction(a *WebhookAction) *PipelineBuilder {
+	return b.OutputTo(a.ID)
+}
+
+// PersistOutput stores the pipeline's output
+func (b *PipelineBuilder) PersistOutput() *PipelineBuilder {
+	b.p.PersistOutput = true
+	return b
+}
+
+// Stitch adds a stitch config joining messages with another pipeline on key.
+// The ttl must be a whole number of seconds.
+func (b *PipelineBuilder) Stitch(pipelineID string, key string, ttl time.Duration) *PipelineBuilder {
+	field := fmt.Sprintf("stitchConfigs[%d].ttl", len(b.p.StitchConfigs))
+	b.p.StitchConfigs = append(b.p.StitchConfigs, PipelineStitchConfig{
+		StitchPipelineID: pipelineID,
+		Key:              key,
+		TTL:              b.seconds(field, ttl),
+	})
+	return b
+}
+
+// Retry sets how often and how many times failed messages are retried. Use
+// MaxRetriesInfinite for maxRetries to retry forever. The interval must be a
+// whole number of seconds.
+func (b *PipelineBuilder) Retry(interval time.Duration, maxRetries int) *PipelineBuilder {
+	b.p.RetryIntervalSeconds = b.seconds("retryIntervalSeconds", interval)
+	b.p.MaxRetries = maxRetries
+	delete(b.errs, "maxRetries")
+	return b
+}
+
+// RetryPolicy sets how failed messages are retried from a RetryPolicy. A
+// limited policy with negative MaxRetries is an error.
+func (b *PipelineBuilder) RetryPolicy(r RetryPolicy) *PipelineBuilder {
+	maxRetries, err := r.wireMaxRetries()
+	b.Retry(r.Interval, maxRetries)
+	if err != nil {
+		b.fail("maxRetries", err)
+	}
+	return b
+}
+
+// seconds converts a duration to the whole seconds the API expects,
+// replacing the field's error with one when precision would be lost
+func (b *PipelineBuilder) seconds(field string, d time.Duration) int {
+	n, err := durationSeconds(d)
+	if err != nil {
+		b.fail(field, err)
+		return int(d / time.Second)
+	}
+	delete(b.errs, field)
+	return n
+}
+
+// fail replaces the field's error
+func (b *PipelineBuilder) fail(field string, err error) {
+	if b.errs == nil {
+		b.errs = map[string]string{}
+	}
+	b.errs[field] = err.Error()
+}
+
+// Build validates the pipeline and returns it, ready to pass to
+// PipelinesService.Create. Errors are returned as a *ValidationError. The
+// returned pipeline does not share memory with the builder, so a builder can
+// be reused as a template.
+func (b *PipelineBuilder) Build() (*Pipeline, error) {
+	p := b.p
+	p.Steps = make([]PipelineSteps, len(b.p.Steps))
+	for i, s := range b.p.Steps {
+		s.Outputs = append([]string{}, s.Outputs...)
+		p.Steps[i] = s
+	}
+	p.Outputs = append([]string{}, b.p.Outputs...)
+	if b.p.StitchConfigs != nil {
+		p.StitchConfigs = append([]PipelineStitchConfig{}, b.p.StitchConfigs...)
+	}
+
+	fields := make([]string, 0, len(b.errs))
+	for field := range b.errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	errs := make([]*FieldError, 0, len(fields))
+	for _, field := range fields {
+		errs = append(errs, &FieldError{Field: field, Message: b.errs[field]})
+	}
+	if err := p.Validate(); err != nil {
+		errs = append(errs, err.(*ValidationError).Errors...)
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	return &p, nil
+}
@@ -0,0 +1,75 @@
+package swarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineBuilder(t *testing.T) {
+	got, err := NewPipeline("pipeline1").
+		Filter("return message.hello == 'prod';").
+		OutputTo("01G2YYM9RRT8CNRGXPSBZSA1PY").
+		Retry(60*time.Second, -1).
+		Build()
+	require.NoError(t, err)
+
+	want := *testPipelineObj
+	want.ID = ""
+	require.Equal(t, &want, got)
+}
+
+func TestPipelineBuilder_Full(t *testing.T) {
+	action := &WebhookAction{ID: "01G7816ZYJT8CNRGXPSBZSA1PY"}
+	b := NewPipeline("orders").
+		Filter("return message.type == 'order'").
+		Transform("return { id: message.id }").
+		Step(PipelineSteps{Function: "return message", Type: "enrich"}).
+		OutputToAction(action).
+		PersistOutput().
+		Stitch("01G6XSSVAAT8CNRGXPSBZSA1PY", "orderId", 10*time.Minute).
+		Retry(30*time.Second, 5)
+
+	got, err := b.Build()
+	require.NoError(t, err)
+	require.Len(t, got.Steps, 3)
+	require.Equal(t, StepTypeTransform, got.Steps[1].Type)
+	require.Equal(t, []string{action.ID}, got.Outputs)
+	require.True(t, got.PersistOutput)
+	require.Equal(t, []PipelineStitchConfig{{StitchPipelineID: "01G6XSSVAAT8CNRGXPSBZSA1PY", Key: "orderId", TTL: 600}}, got.StitchConfigs)
+	require.Equal(t, 30, got.RetryIntervalSeconds)
+	require.Equal(t, 5, got.MaxRetries)
+
+	// built pipelines do not share memory with the builder
+	got.Outputs[0] = "changed"
+	again, err := b.Build()
+	require.NoError(t, err)
+	require.Equal(t, []string{action.ID}, again.Outputs)
+}
+
+func TestPipelineBuilder_Invalid(t *testing.T) {
+	_, err := NewPipeline("").
+		Filter("").
+		Retry(1500*time.Millisecond, 3).
+		Build()
+
+	got := fieldErrors(t, err)
+	require.Equal(t, map[string]string{
+		"retryIntervalSeconds": "1.5s is not a whole number of seconds",
+		"name":                 "is required",
+		"steps[0].function":    "is required",
+	}, got)
+}
+
+func TestPipelineBuilder_LastCallWins(t *testing.T) {
+	b := NewPipeline("orders").
+		Filter("return true;").
+		Retry(1500*time.Millisecond, 3)
+	_, err := b.Build()
+	require.Error(t, err)
+
+	p, err := b.Retry(2*time.Second, 3).Build()
+	require.NoError(t, err)
+	require.Equal(t, 2, p.RetryIntervalSeconds)
+}
@@ -0,0 +1,293 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the version of the bundle format written by Export.
+// Restore refuses bundles with a newer version.
+const BundleVersion = 1
+
+// Bundle is a snapshot of the webhook actions and pipelines of an account,
+// for backups and for copying an account's configuration into another
+type Bundle struct {
+	Version        int              `json:"version"`
+	ExportedAt     time.Time        `json:"exportedAt"`
+	WebhookActions []*WebhookAction `json:"webhookActions"`
+	Pipelines      []*Pipeline      `json:"pipelines"`
+}
+
+// Export lists every webhook action and pipeline in the account into a
+// bundle
+func (c *Client) Export(ctx context.Context) (*Bundle, error) {
+	actions, _, err := c.WebhookActions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook actions: %w", err)
+	}
+	pipelines, _, err := c.Pipelines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing pipelines: %w", err)
+	}
+	if actions == nil {
+		actions = []*WebhookAction{}
+	}
+	if pipelines == nil {
+		pipelines = []*Pipeline{}
+	}
+	return &Bundle{
+		Version:        BundleVersion,
+		ExportedAt:     time.Now().UTC(),
+		WebhookActions: actions,
+		Pipelines:      pipelines,
+	}, nil
+}
+
+// WriteJSON writes the bundle as indented JSON
+func (b *Bundle) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(b)
+}
+
+// WriteYAML writes the bundle as YAML, using the same field names as JSON
+func (b *Bundle) WriteYAML(w io.Writer) error {
+	buf, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// ReadBundle reads a bundle written by WriteJSON or WriteYAML
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, so both formats are decoded as YAML and converted
+	// to JSON to reuse the json tags and the handling of unknown fields
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	b := new(Bundle)
+	if err := json.NewDecoder(bytes.NewReader(buf)).Decode(b); err != nil {
+		return nil, err
+	}
+	if b.Version < 1 {
+		return nil, errors.New("not a bundle: version is missing")
+	}
+	if b.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than the supported version %d", b.Version, BundleVersion)
+	}
+	return b, nil
+}
+
+// RestoreState records the progress of a Restore so an interrupted restore
+// can be resumed. It encodes to JSON for saving between runs.
+type RestoreState struct {
+	// IDs maps the IDs in the bundle to the IDs of the resources created
+	// from them
+	IDs map[string]string `json:"ids"`
+	// Unlinked holds the bundle IDs of pipelines that were created without
+	// their outputs and stitch configs and still need updating
+	Unlinked map[string]bool `json:"unlinked,omitempty"`
+	// Dropped describes references to resources that are not in the bundle,
+	// which are left out of the restored pipelines
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// RestoreOptions configures Restore
+type RestoreOptions struct {
+	// State resumes an interrupted restore. It is updated in place.
+	State *RestoreState
+	// Checkpoint is called with the state after every write, to save it.
+	// An error stops the restore.
+	Checkpoint func(*RestoreState) error
+}
+
+// Restore recreates the webhook actions and pipelines of a bundle in the
+// client's account, which is usually a different one from where the bundle
+// was exported. New IDs are assigned, so references in Outputs, step Outputs
+// and StitchPipelineID are rewritten. Pipelines referring to pipelines that
+// do not exist yet, including cycles, are created without those references
+// first and updated once everything exists.
+//
+// The returned state maps bundle IDs to new IDs. To resume after an error,
+// pass the state back in opts; resources it records are not created again.
+// A resource whose create succeeded but was not recorded, because the
+// process died in between, is created twice. A nil opts uses the defaults.
+func (c *Client) Restore(ctx context.Context, b *Bundle, opts *RestoreOptions) (*RestoreState, error) {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+	if b.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than the supported version %d", b.Version, BundleVersion)
+	}
+	state := opts.State
+	if state == nil {
+		state = &RestoreState{}
+	}
+	if state.IDs == nil {
+		state.IDs = map[string]string{}
+	}
+	if state.Unlinked == nil {
+		state.Unlinked = map[string]bool{}
+	}
+	checkpoint := func() error {
+		if opts.Checkpoint == nil {
+			return nil
+		}
+		return opts.Checkpoint(state)
+	}
+
+	inBundle := map[string]bool{}
+	for _, a := range b.WebhookActions {
+		inBundle[a.ID] = true
+	}
+	for _, p := range b.Pipelines {
+		inBundle[p.ID] = true
+	}
+	r := &remapper{state: state, inBundle: inBundle}
+
+	for _, a := range b.WebhookActions {
+		if _, ok := state.IDs[a.ID]; ok {
+			continue
+		}
+		desired := *a
+		desired.ID = ""
+		created, _, err := c.WebhookActions.Create(ctx, &desired)
+		if err != nil {
+			return state, fmt.Errorf("restoring webhook action %q: %w", a.Name, err)
+		}
+		state.IDs[a.ID] = created.ID
+		if err := checkpoint(); err != nil {
+			return state, err
+		}
+	}
+
+	for _, p := range b.Pipelines {
+		if _, ok := state.IDs[p.ID]; ok {
+			continue
+		}
+		desired, complete, err := r.pipeline(p)
+		if err != nil {
+			return state, err
+		}
+		desired.ID = ""
+		created, _, err := c.Pipelines.Create(ctx, desired)
+		if err != nil {
+			return state, fmt.Errorf("restoring pipeline %q: %w", p.Name, err)
+		}
+		state.IDs[p.ID] = created.ID
+		if !complete {
+			state.Unlinked[p.ID] = true
+		}
+		if err := checkpoint(); err != nil {
+			return state, err
+		}
+	}
+
+	for _, p := range b.Pipelines {
+		if !state.Unlinked[p.ID] {
+			continue
+		}
+		desired, _, err := r.pipeline(p)
+		if err != nil {
+			return state, err
+		}
+		id := state.IDs[p.ID]
+		desired.ID = id
+		if _, _, err := c.Pipelines.UpdateByID(ctx, id, desired); err != nil {
+			return state, fmt.Errorf("linking pipeline %q: %w", p.Name, err)
+		}
+		delete(state.Unlinked, p.ID)
+		if err := checkpoint(); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+// remapper rewrites the references of bundle pipelines to restored IDs
+type remapper struct {
+	state    *RestoreState
+	inBundle map[string]bool
+}
+
+// pipeline returns a copy of p with references rewritten. References to
+// bundle resources that are not restored yet are left out and reported by
+// complete being false. References to resources outside the bundle are left
+// out and recorded in the state.
+func (r *remapper) pipeline(p *Pipeline) (out *Pipeline, complete bool, err error) {
+	out, err = clonePipeline(p)
+	if err != nil {
+		return nil, false, err
+	}
+	complete = true
+	remap := func(field string, id string) (string, bool) {
+		if newID, ok := r.state.IDs[id]; ok {
+			return newID, true
+		}
+		if r.inBundle[id] {
+			complete = false
+			return "", false
+		}
+		r.dropped(fmt.Sprintf("pipeline %q: %s refers to %s, which is not in the bundle", p.Name, field, id))
+		return "", false
+	}
+	remapAll := func(field string, ids []string) []string {
+		out := []string{}
+		for i, id := range ids {
+			if newID, ok := remap(fmt.Sprintf("%s[%d]", field, i), id); ok {
+				out = append(out, newID)
+			}
+		}
+		return out
+	}
+
+	out.Outputs = remapAll("outputs", p.Outputs)
+	for i := range out.Steps {
+		out.Steps[i].Outputs = remapAll(fmt.Sprintf("steps[%d].outputs", i), p.Steps[i].Outputs)
+	}
+	var stitches []PipelineStitchConfig
+	for i, sc := range out.StitchConfigs {
+		if newID, ok := remap(fmt.Sprintf("stitchConfigs[%d].stitchPipelineId", i), sc.StitchPipelineID); ok {
+			sc.StitchPipelineID = newID
+			stitches = append(stitches, sc)
+		}
+	}
+	out.StitchConfigs = stitches
+	return out, complete, nil
+}
+
+func (r *remapper) dropped(msg string) {
+	for _, d := range r.state.Dropped {
+		if d == msg {
+			return
+		}
+	}
+	r.state.Dropped = append(r.state.Dropped, msg)
+}
@@ -0,0 +1,116 @@
+package swarm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+// testBundleSource fills an account with an action and two pipelines that
+// stitch with each other, one of which outputs to the action and to a
+// deleted action
+func testBundleSource(t *testing.T) *swarm.Client {
+	server := swarmtest.NewServer()
+	t.Cleanup(server.Close)
+
+	action := server.AddWebhookAction(&swarm.WebhookAction{Name: "hook", URL: "https://example.com", Method: "POST"}).ID
+	server.AddPipeline(&swarm.Pipeline{
+		ID:            "P1",
+		Name:          "orders",
+		Steps:         []swarm.PipelineSteps{swarm.FilterStep("return true;", action)},
+		Outputs:       []string{action, "DELETED"},
+		StitchConfigs: []swarm.PipelineStitchConfig{{StitchPipelineID: "P2", Key: "id", TTL: 60}},
+	})
+	server.AddPipeline(&swarm.Pipeline{
+		ID:            "P2",
+		Name:          "payments",
+		Steps:         []swarm.PipelineSteps{swarm.FilterStep("return true;")},
+		Outputs:       []string{},
+		StitchConfigs: []swarm.PipelineStitchConfig{{StitchPipelineID: "P1", Key: "id", TTL: 60}},
+	})
+	return server.Client()
+}
+
+func TestExportRestore(t *testing.T) {
+	ctx := context.Background()
+	bundle, err := testBundleSource(t).Export(ctx)
+	require.NoError(t, err)
+	require.Equal(t, swarm.BundleVersion, bundle.Version)
+	require.Len(t, bundle.Pipelines, 2)
+
+	// the bundle survives both formats
+	for _, write := range []func(*swarm.Bundle, *bytes.Buffer) error{
+		func(b *swarm.Bundle, buf *bytes.Buffer) error { return b.WriteJSON(buf) },
+		func(b *swarm.Bundle, buf *bytes.Buffer) error { return b.WriteYAML(buf) },
+	} {
+		buf := &bytes.Buffer{}
+		require.NoError(t, write(bundle, buf))
+		read, err := swarm.ReadBundle(buf)
+		require.NoError(t, err)
+		require.Equal(t, bundle.ExportedAt.Unix(), read.ExportedAt.Unix())
+		require.Equal(t, bundle.Pipelines, read.Pipelines)
+		require.Equal(t, bundle.WebhookActions, read.WebhookActions)
+	}
+
+	server := swarmtest.NewServer()
+	defer server.Close()
+
+	state, err := server.Client().Restore(ctx, bundle, nil)
+	require.NoError(t, err)
+	require.Empty(t, state.Unlinked)
+	require.Equal(t, []string{`pipeline "orders": outputs[1] refers to DELETED, which is not in the bundle`}, state.Dropped)
+
+	action := server.WebhookActions()[0]
+	pipelines := server.Pipelines()
+	orders, payments := pipelines[0], pipelines[1]
+	require.Equal(t, state.IDs[bundle.WebhookActions[0].ID], action.ID)
+	require.Equal(t, state.IDs["P1"], orders.ID)
+	require.Equal(t, []string{action.ID}, orders.Outputs)
+	require.Equal(t, []string{action.ID}, orders.Steps[0].Outputs)
+	require.Equal(t, payments.ID, orders.StitchConfigs[0].StitchPipelineID)
+	require.Equal(t, orders.ID, payments.StitchConfigs[0].StitchPipelineID)
+}
+
+func TestRestore_Resume(t *testing.T) {
+	ctx := context.Background()
+	bundle, err := testBundleSource(t).Export(ctx)
+	require.NoError(t, err)
+
+	server := swarmtest.NewServer()
+	defer server.Close()
+	target := server.Client()
+
+	// stop after the action and the first pipeline are created
+	interrupted := errors.New("interrupted")
+	writes := 0
+	state, err := target.Restore(ctx, bundle, &swarm.RestoreOptions{Checkpoint: func(*swarm.RestoreState) error {
+		writes++
+		if writes == 2 {
+			return interrupted
+		}
+		return nil
+	}})
+	require.ErrorIs(t, err, interrupted)
+	require.Len(t, state.IDs, 2)
+	require.Equal(t, map[string]bool{"P1": true}, state.Unlinked)
+
+	state, err = target.Restore(ctx, bundle, &swarm.RestoreOptions{State: state})
+	require.NoError(t, err)
+	require.Len(t, state.IDs, 3)
+	require.Len(t, server.WebhookActions(), 1)
+	pipelines := server.Pipelines()
+	require.Len(t, pipelines, 2)
+	require.Equal(t, pipelines[1].ID, pipelines[0].StitchConfigs[0].StitchPipelineID)
+}
+
+func TestReadBundle_Version(t *testing.T) {
+	_, err := swarm.ReadBundle(bytes.NewBufferString(`{"version": 2}`))
+	require.EqualError(t, err, "bundle version 2 is newer than the supported version 1")
+	_, err = swarm.ReadBundle(bytes.NewBufferString(`pipelines: []`))
+	require.EqualError(t, err, "not a bundle: version is missing")
+}
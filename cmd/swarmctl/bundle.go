@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+func exportCommand() *command {
+	return &command{
+		name:    "export",
+		summary: "write every webhook action and pipeline to a bundle",
+		run:     runExport,
+	}
+}
+
+func restoreCommand() *command {
+	return &command{
+		name:    "restore",
+		summary: "recreate the resources of a bundle in the account",
+		run:     runRestore,
+	}
+}
+
+func runExport(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "export", "[flags]")
+	format := fs.String("format", "json", "bundle format: json or yaml")
+	out := fs.String("o", "", "write the bundle to a file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "yaml" {
+		return &exitError{code: 2, err: fmt.Errorf("unknown format %q", *format)}
+	}
+
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	bundle, err := client.Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = env.stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "yaml" {
+		err = bundle.WriteYAML(w)
+	} else {
+		err = bundle.WriteJSON(w)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stderr, "Exported %d webhook actions and %d pipelines.\n", len(bundle.WebhookActions), len(bundle.Pipelines))
+	return nil
+}
+
+func runRestore(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "restore", "[flags] bundle.json")
+	statePath := fs.String("state", "", "file recording progress; an existing file resumes an interrupted restore")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bundle, err := swarm.ReadBundle(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+
+	opts := &swarm.RestoreOptions{}
+	if *statePath != "" {
+		b, err := os.ReadFile(*statePath)
+		switch {
+		case err == nil:
+			opts.State = &swarm.RestoreState{}
+			if err := json.Unmarshal(b, opts.State); err != nil {
+				return fmt.Errorf("%s: %w", *statePath, err)
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+		opts.Checkpoint = func(state *swarm.RestoreState) error {
+			b, err := json.MarshalIndent(state, "", "  ")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(*statePath, b, 0o600)
+		}
+	}
+
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	state, err := client.Restore(ctx, bundle, opts)
+	if state != nil {
+		for _, d := range state.Dropped {
+			fmt.Fprintf(env.stderr, "warning: %s\n", d)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Restored %d resources.\n", len(state.IDs))
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRestore(t *testing.T) {
+	source := swarmtest.NewServer()
+	defer source.Close()
+	action := source.AddWebhookAction(&swarm.WebhookAction{Name: "hook", URL: "https://example.com", Method: "POST"})
+	source.AddPipeline(&swarm.Pipeline{Name: "orders", Steps: []swarm.PipelineSteps{swarm.FilterStep("return true;")}, Outputs: []string{action.ID}})
+
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bundle.yaml")
+	env, _, stderr := testEnv()
+	useServer(t, source)
+	require.Equal(t, 0, run(context.Background(), env, []string{"export", "-format", "yaml", "-o", bundle}))
+	require.Equal(t, "Exported 1 webhook actions and 1 pipelines.\n", stderr.String())
+
+	target := swarmtest.NewServer()
+	defer target.Close()
+	env, stdout, _ := testEnv()
+	useServer(t, target)
+	state := filepath.Join(dir, "state.json")
+	require.Equal(t, 0, run(context.Background(), env, []string{"restore", "-state", state, bundle}))
+	require.Equal(t, "Restored 2 resources.\n", stdout.String())
+	require.Equal(t, []string{target.WebhookActions()[0].ID}, target.Pipelines()[0].Outputs)
+
+	// running again with the state file creates nothing
+	require.Equal(t, 0, run(context.Background(), env, []string{"restore", "-state", state, bundle}))
+	require.Len(t, target.Pipelines(), 1)
+}
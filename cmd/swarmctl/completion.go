@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+func completionCommand() *command {
+	return &command{
+		name:    "completion",
+		summary: "print a shell completion script for bash, zsh or fish",
+		run:     runCompletion,
+	}
+}
+
+func runCompletion(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "completion", "bash|zsh|fish")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+	cmds := sortedCommands(commands())
+	switch fs.Arg(0) {
+	case "bash":
+		writeBashCompletion(env.stdout, cmds)
+	case "zsh":
+		// zsh runs the bash script through its bash compatibility layer
+		fmt.Fprintln(env.stdout, "autoload -U +X bashcompinit && bashcompinit")
+		writeBashCompletion(env.stdout, cmds)
+	case "fish":
+		writeFishCompletion(env.stdout, cmds)
+	default:
+		return &exitError{code: 2, err: fmt.Errorf("unknown shell %q", fs.Arg(0))}
+	}
+	return nil
+}
+
+func sortedCommands(cmds map[string]*command) []*command {
+	sorted := make([]*command, 0, len(cmds))
+	for _, c := range cmds {
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted
+}
+
+func commandNames(cmds []*command) string {
+	names := make([]string, len(cmds))
+	for i, c := range cmds {
+		names[i] = c.name
+	}
+	return strings.Join(names, " ")
+}
+
+// writeBashCompletion writes a script completing command and subcommand
+// names, skipping the global -profile flag, and files after them
+func writeBashCompletion(w io.Writer, cmds []*command) {
+	fmt.Fprintln(w, `_swarmctl() {
+    local cur i cmd=""
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    i=1
+    while [[ $i -lt $COMP_CWORD ]]; do
+        case "${COMP_WORDS[i]}" in
+            -profile) i=$((i + 2)); continue ;;
+            -*) i=$((i + 1)); continue ;;
+        esac
+        cmd="${COMP_WORDS[i]}"
+        break
+    done
+    if [[ -z "$cmd" ]]; then
+        COMPREPLY=($(compgen -W "`+commandNames(cmds)+`" -- "$cur"))
+        return
+    fi
+    case "$cmd" in`)
+	for _, c := range cmds {
+		if len(c.subcommands) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, `        %s)
+            if [[ $COMP_CWORD -eq $((i + 1)) ]]; then
+                COMPREPLY=($(compgen -W "%s" -- "$cur"))
+                return
+            fi
+            ;;
+`, c.name, commandNames(c.subcommands))
+	}
+	fmt.Fprintln(w, `    esac
+    COMPREPLY=($(compgen -f -- "$cur"))
+}
+complete -o filenames -F _swarmctl swarmctl`)
+}
+
+func writeFishCompletion(w io.Writer, cmds []*command) {
+	fmt.Fprintln(w, "complete -c swarmctl -o profile -r -d 'configuration profile to use'")
+	for _, c := range cmds {
+		fmt.Fprintf(w, "complete -c swarmctl -f -n __fish_use_subcommand -a %s -d %s\n", c.name, fishQuote(c.summary))
+	}
+	for _, c := range cmds {
+		if len(c.subcommands) == 0 {
+			continue
+		}
+		names := commandNames(c.subcommands)
+		for _, sub := range c.subcommands {
+			fmt.Fprintf(w, "complete -c swarmctl -f -n '__fish_seen_subcommand_from %s; and not __fish_seen_subcommand_from %s' -a %s -d %s\n",
+				c.name, names, sub.name, fishQuote(sub.summary))
+		}
+	}
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	env, stdout, _ := testEnv()
+	require.Equal(t, 0, run(context.Background(), env, []string{"completion", "bash"}))
+	require.Contains(t, stdout.String(), `compgen -W "apitokens apply completion drift export graph lint pipelines plan publish restore webhookactions"`)
+	require.Contains(t, stdout.String(), `compgen -W "list get create update delete delete-all"`)
+	require.Contains(t, stdout.String(), "complete -o filenames -F _swarmctl swarmctl\n")
+
+	env, stdout, _ = testEnv()
+	require.Equal(t, 0, run(context.Background(), env, []string{"completion", "zsh"}))
+	require.Contains(t, stdout.String(), "bashcompinit\n_swarmctl() {")
+
+	env, stdout, _ = testEnv()
+	require.Equal(t, 0, run(context.Background(), env, []string{"completion", "fish"}))
+	require.Contains(t, stdout.String(), "complete -c swarmctl -f -n __fish_use_subcommand -a pipelines -d 'list, get, create, update and delete pipelines'\n")
+	require.Contains(t, stdout.String(), "complete -c swarmctl -f -n '__fish_seen_subcommand_from apitokens; and not __fish_seen_subcommand_from list create delete delete-all' -a create -d 'create an API token and print it'\n")
+
+	env, _, _ = testEnv()
+	require.Equal(t, 2, run(context.Background(), env, []string{"completion", "tcsh"}))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/catalystsquad/swarm-client-go/manifest"
+)
+
+// exitDrift is the exit code of the drift command when drift is found, kept
+// apart from 1 for errors and 2 for usage mistakes
+const exitDrift = 3
+
+func driftCommand() *command {
+	return &command{
+		name:    "drift",
+		summary: "report how the account differs from manifests",
+		run:     runDrift,
+	}
+}
+
+func runDrift(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "drift", "[flags] manifest.yaml...")
+	format := fs.String("format", "text", "output format: text, json or junit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+	write := map[string]func(*manifest.DriftReport) error{
+		"text":  func(r *manifest.DriftReport) error { return r.WriteText(env.stdout) },
+		"json":  func(r *manifest.DriftReport) error { return r.WriteJSON(env.stdout) },
+		"junit": func(r *manifest.DriftReport) error { return r.WriteJUnit(env.stdout) },
+	}[*format]
+	if write == nil {
+		return &exitError{code: 2, err: fmt.Errorf("unknown format %q", *format)}
+	}
+
+	m, err := manifest.Load(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	report, err := manifest.CheckDrift(ctx, client, m)
+	if err != nil {
+		return err
+	}
+	if err := write(report); err != nil {
+		return err
+	}
+	if report.HasDrift() {
+		return &exitError{code: exitDrift}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/catalystsquad/swarm-client-go/graph"
+	"github.com/catalystsquad/swarm-client-go/manifest"
+)
+
+func graphCommand() *command {
+	return &command{
+		name:    "graph",
+		summary: "draw the dependencies between resources",
+		run:     runGraph,
+	}
+}
+
+func runGraph(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "graph", "[flags] [manifest.yaml...]")
+	format := fs.String("format", "dot", "output format: dot or mermaid")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	write := map[string]func(*graph.Graph) error{
+		"dot":     func(g *graph.Graph) error { return g.WriteDOT(env.stdout) },
+		"mermaid": func(g *graph.Graph) error { return g.WriteMermaid(env.stdout) },
+	}[*format]
+	if write == nil {
+		return &exitError{code: 2, err: fmt.Errorf("unknown format %q", *format)}
+	}
+
+	// without manifests the graph is of the account
+	var g *graph.Graph
+	if fs.NArg() > 0 {
+		m, err := manifest.Load(fs.Args()...)
+		if err != nil {
+			return err
+		}
+		g = graph.FromManifest(m)
+	} else {
+		client, err := env.newClient()
+		if err != nil {
+			return err
+		}
+		actions, _, err := client.WebhookActions.List(ctx)
+		if err != nil {
+			return err
+		}
+		pipelines, _, err := client.Pipelines.List(ctx)
+		if err != nil {
+			return err
+		}
+		g = graph.FromResources(actions, pipelines)
+	}
+
+	for _, e := range g.Dangling() {
+		fmt.Fprintf(env.stderr, "warning: %s refers to %s, which does not exist\n", nodeName(g, e.From), e.To)
+	}
+	for _, cycle := range g.StitchCycles() {
+		names := make([]string, len(cycle))
+		for i, n := range cycle {
+			names[i] = n.Name
+		}
+		fmt.Fprintf(env.stderr, "warning: stitch cycle: %s\n", strings.Join(names, ", "))
+	}
+	return write(g)
+}
+
+func nodeName(g *graph.Graph, id string) string {
+	if n := g.Node(id); n != nil {
+		return fmt.Sprintf("%s %q", n.Kind, n.Name)
+	}
+	return id
+}
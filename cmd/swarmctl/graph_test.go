@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph(t *testing.T) {
+	path := writeFile(t, "manifest.yaml", testManifest+`  - name: refunds
+    outputs: [missing]
+`)
+	env, stdout, stderr := testEnv()
+	require.Equal(t, 0, run(context.Background(), env, []string{"graph", "-format", "mermaid", path}))
+	require.Equal(t, `flowchart LR
+  n0(["hook"])
+  n1["orders"]
+  n2["refunds"]
+  n3{{"missing (missing)"}}
+  n1 --> n0
+  n2 --> n3
+`, stdout.String())
+	require.Equal(t, "warning: pipeline \"refunds\" refers to missing, which does not exist\n", stderr.String())
+
+	server := swarmtest.NewServer()
+	defer server.Close()
+	hook := server.AddWebhookAction(&swarm.WebhookAction{Name: "hook"})
+	server.AddPipeline(&swarm.Pipeline{Name: "orders", Outputs: []string{hook.ID}})
+
+	env, stdout, _ = testEnv()
+	useServer(t, server)
+	require.Equal(t, 0, run(context.Background(), env, []string{"graph"}))
+	require.Contains(t, stdout.String(), "digraph swarm {\n")
+	require.Contains(t, stdout.String(), `[label="orders", shape=box];`)
+}
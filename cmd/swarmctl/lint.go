@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+func lintCommand() *command {
+	return &command{
+		name:    "lint",
+		summary: "statically check pipeline definitions",
+		run:     runLint,
+	}
+}
+
+func runLint(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "lint", "[flags] pipeline.json...")
+	remote := fs.Bool("remote", false, "check outputs against the webhook actions and pipelines in the account")
+	samplePath := fs.String("sample", "", "JSON file with a sample message used to check field references")
+	asJSON := fs.Bool("json", false, "print diagnostics as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+
+	opts := &swarm.LintOptions{}
+	if *samplePath != "" {
+		b, err := os.ReadFile(*samplePath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &opts.SampleMessage); err != nil {
+			return fmt.Errorf("reading sample message: %w", err)
+		}
+	}
+	var client *swarm.Client
+	if *remote {
+		var err error
+		client, err = env.newClient()
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := false
+	var all []fileDiagnostic
+	for _, path := range fs.Args() {
+		pipelines, err := readPipelines(path)
+		if err != nil {
+			return err
+		}
+		for _, p := range pipelines {
+			var diags swarm.LintDiagnostics
+			if client != nil {
+				diags, err = client.Pipelines.Lint(ctx, p, opts)
+				if err != nil {
+					return err
+				}
+			} else {
+				diags = swarm.Lint(p, opts)
+			}
+			if diags.HasErrors() {
+				failed = true
+			}
+			for _, d := range diags {
+				all = append(all, fileDiagnostic{File: path, Pipeline: p.Name, LintDiagnostic: d})
+			}
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(env.stdout)
+		enc.SetIndent("", "  ")
+		if all == nil {
+			all = []fileDiagnostic{}
+		}
+		if err := enc.Encode(all); err != nil {
+			return err
+		}
+	} else {
+		for _, d := range all {
+			fmt.Fprintf(env.stdout, "%s: %s: %s\n", d.File, d.Pipeline, d.LintDiagnostic)
+		}
+	}
+
+	if failed {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+type fileDiagnostic struct {
+	File     string `json:"file"`
+	Pipeline string `json:"pipeline"`
+	swarm.LintDiagnostic
+}
+
+// readPipelines reads a file holding either a single pipeline or an array of
+// pipelines
+func readPipelines(path string) ([]*swarm.Pipeline, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%s: empty file", path)
+	}
+	if b[0] == '[' {
+		var ps []*swarm.Pipeline
+		if err := json.Unmarshal(b, &ps); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return ps, nil
+	}
+	p := new(swarm.Pipeline)
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return []*swarm.Pipeline{p}, nil
+}
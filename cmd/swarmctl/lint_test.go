@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func testEnv() (*environment, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	return newEnvironment(&bytes.Buffer{}, stdout, stderr), stdout, stderr
+}
+
+// useServer points the configuration at server for the rest of the test, so
+// commands load their client the way they do outside tests
+func useServer(t *testing.T, server *swarmtest.Server) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, server.WriteConfig(path))
+	t.Setenv(swarm.EnvConfig, path)
+	t.Setenv(swarm.EnvProfile, "")
+	t.Setenv(swarm.EnvCustomerID, "")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLint(t *testing.T) {
+	good := writeFile(t, "good.json", `{
+  "name": "good",
+  "steps": [{"function": "return message.hello == 'prod';", "type": "filter", "required": true}]
+}`)
+	bad := writeFile(t, "bad.json", `[{
+  "name": "bad",
+  "steps": [{"function": "return hello == 'prod';", "type": "filter", "required": true}]
+}]`)
+
+	env, stdout, _ := testEnv()
+	require.Equal(t, 0, run(context.Background(), env, []string{"lint", good}))
+	require.Empty(t, stdout.String())
+
+	env, stdout, _ = testEnv()
+	require.Equal(t, 1, run(context.Background(), env, []string{"lint", good, bad}))
+	require.Equal(t, bad+": bad: steps[0].function:1:8: error: hello is not declared (undeclared-variable)\n", stdout.String())
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	env, _, stderr := testEnv()
+	require.Equal(t, 2, run(context.Background(), env, []string{"bogus"}))
+	require.Contains(t, stderr.String(), `unknown command "bogus"`)
+}
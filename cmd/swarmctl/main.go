@@ -0,0 +1,198 @@
+// Command swarmctl is a command line client for the Swarm API built on the
+// swarm package.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+// command is a swarmctl subcommand. Commands grouping others, such as
+// "pipelines", list them in subcommands for usage and completion.
+type command struct {
+	name        string
+	summary     string
+	run         func(ctx context.Context, env *environment, args []string) error
+	subcommands []*command
+}
+
+// environment carries the streams a command writes to and how it connects
+// to the API, so commands can be tested without touching the process's
+// stdout or a real account
+type environment struct {
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+	newClient func() (*swarm.Client, error)
+	// profile is the configuration profile chosen with -profile
+	profile string
+}
+
+// exitError ends the program with a specific exit code. A nil err exits
+// without printing anything.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func commands() map[string]*command {
+	cmds := map[string]*command{}
+	for _, c := range []*command{
+		apiTokensCommand(),
+		applyCommand(),
+		completionCommand(),
+		driftCommand(),
+		exportCommand(),
+		graphCommand(),
+		lintCommand(),
+		pipelinesCommand(),
+		planCommand(),
+		publishCommand(),
+		restoreCommand(),
+		webhookActionsCommand(),
+	} {
+		cmds[c.name] = c
+	}
+	return cmds
+}
+
+func main() {
+	// commands stop cleanly on Ctrl-C, so publish can save its checkpoint
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	env := newEnvironment(os.Stdin, os.Stdout, os.Stderr)
+	code := run(ctx, env, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+// newEnvironment returns an environment using the given streams whose
+// clients come from the configuration, see newClient
+func newEnvironment(stdin io.Reader, stdout, stderr io.Writer) *environment {
+	env := &environment{stdin: stdin, stdout: stdout, stderr: stderr}
+	env.newClient = func() (*swarm.Client, error) { return newClient(env.profile) }
+	return env
+}
+
+func run(ctx context.Context, env *environment, args []string) int {
+	cmds := commands()
+	global := flag.NewFlagSet("swarmctl", flag.ContinueOnError)
+	global.SetOutput(env.stderr)
+	global.Usage = func() { usage(env.stderr, cmds) }
+	global.StringVar(&env.profile, "profile", "", "configuration profile to use, defaults to $SWARM_PROFILE")
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	args = global.Args()
+	if len(args) == 0 || args[0] == "help" {
+		usage(env.stderr, cmds)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "swarmctl: unknown command %q\n\n", args[0])
+		usage(env.stderr, cmds)
+		return 2
+	}
+
+	err := cmd.run(ctx, env, args[1:])
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	var exit *exitError
+	if errors.As(err, &exit) {
+		if exit.err != nil {
+			fmt.Fprintf(env.stderr, "swarmctl %s: %s\n", cmd.name, exit.err)
+		}
+		return exit.code
+	}
+	fmt.Fprintf(env.stderr, "swarmctl %s: %s\n", cmd.name, err)
+	return 1
+}
+
+// groupCommand returns a command that runs one of subcommands, named by its
+// first argument
+func groupCommand(name, summary string, subcommands ...*command) *command {
+	c := &command{name: name, summary: summary, subcommands: subcommands}
+	c.run = func(ctx context.Context, env *environment, args []string) error {
+		byName := map[string]*command{}
+		for _, sub := range subcommands {
+			byName[sub.name] = sub
+		}
+		if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+			fmt.Fprintf(env.stderr, "usage: swarmctl %s <command> [flags] [args]\n\n", name)
+			printCommands(env.stderr, byName)
+			if len(args) == 0 {
+				return &exitError{code: 2}
+			}
+			return nil
+		}
+		sub, ok := byName[args[0]]
+		if !ok {
+			return &exitError{code: 2, err: fmt.Errorf("unknown command %q", args[0])}
+		}
+		return sub.run(ctx, env, args[1:])
+	}
+	return c
+}
+
+func usage(w io.Writer, cmds map[string]*command) {
+	fmt.Fprintln(w, "usage: swarmctl [-profile name] <command> [flags] [args]")
+	fmt.Fprintln(w)
+	printCommands(w, cmds)
+}
+
+func printCommands(w io.Writer, cmds map[string]*command) {
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-16s %s\n", name, cmds[name].summary)
+	}
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting
+func newFlagSet(env *environment, name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet("swarmctl "+name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.stderr, "usage: swarmctl %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// newClient builds a client from the named profile in the configuration
+// file, or the SWARM_CUSTOMER_ID and SWARM_API_KEY environment variables, see
+// swarm.NewClientFromConfig
+func newClient(profile string) (*swarm.Client, error) {
+	return swarm.NewClientFromConfig(profile)
+}
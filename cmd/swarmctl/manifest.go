@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/catalystsquad/swarm-client-go/manifest"
+)
+
+func planCommand() *command {
+	return &command{
+		name:    "plan",
+		summary: "show the changes that make the account match manifests",
+		run:     runPlan,
+	}
+}
+
+func applyCommand() *command {
+	return &command{
+		name:    "apply",
+		summary: "change the account to match manifests",
+		run:     runApply,
+	}
+}
+
+func runPlan(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "plan", "[flags] manifest.yaml...")
+	prune := fs.Bool("prune", false, "delete webhook actions and pipelines the manifests do not declare")
+	asJSON := fs.Bool("json", false, "print the plan as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+
+	plan, err := loadPlan(ctx, env, fs.Args(), *prune)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(env.stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(plan)
+	}
+	return plan.WriteText(env.stdout)
+}
+
+func runApply(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "apply", "[flags] manifest.yaml...")
+	prune := fs.Bool("prune", false, "delete webhook actions and pipelines the manifests do not declare")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+
+	plan, err := loadPlan(ctx, env, fs.Args(), *prune)
+	if err != nil {
+		return err
+	}
+	if err := plan.WriteText(env.stdout); err != nil {
+		return err
+	}
+	if plan.Empty() {
+		return nil
+	}
+
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	if err := plan.Apply(ctx, client); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Applied %d changes.\n", len(plan.Changes))
+	return nil
+}
+
+// loadPlan reads manifests and computes their plan against the account
+func loadPlan(ctx context.Context, env *environment, paths []string, prune bool) (*manifest.Plan, error) {
+	m, err := manifest.Load(paths...)
+	if err != nil {
+		return nil, err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return manifest.NewPlan(ctx, client, m, &manifest.PlanOptions{Prune: prune})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `
+webhookActions:
+  - name: hook
+    url: https://example.com/hook
+    method: POST
+pipelines:
+  - name: orders
+    steps: [{type: filter, required: true, function: return true;}]
+    outputs: [hook]
+`
+
+func TestPlanAndApply(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	path := writeFile(t, "manifest.yaml", testManifest)
+
+	env, stdout, _ := testEnv()
+	useServer(t, server)
+	require.Equal(t, 0, run(context.Background(), env, []string{"plan", path}))
+	require.Equal(t, "+ webhookAction hook\n+ pipeline orders\nPlan: 2 to create, 0 to update, 0 to delete.\n", stdout.String())
+	require.Zero(t, server.Writes())
+
+	stdout.Reset()
+	require.Equal(t, 0, run(context.Background(), env, []string{"apply", path}))
+	require.Contains(t, stdout.String(), "Applied 2 changes.\n")
+	require.Len(t, server.Pipelines(), 1)
+
+	stdout.Reset()
+	require.Equal(t, 0, run(context.Background(), env, []string{"apply", "-prune", path}))
+	require.Equal(t, "No changes.\n", stdout.String())
+}
+
+func TestDrift(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	path := writeFile(t, "manifest.yaml", testManifest)
+
+	env, stdout, _ := testEnv()
+	useServer(t, server)
+	require.Equal(t, 3, run(context.Background(), env, []string{"drift", path}))
+	require.Equal(t, "missing webhookAction \"hook\"\nmissing pipeline \"orders\"\nDrift: 2 missing, 0 extra, 0 modified, 0 in sync.\n", stdout.String())
+
+	require.Equal(t, 0, run(context.Background(), env, []string{"apply", path}))
+	stdout.Reset()
+	require.Equal(t, 0, run(context.Background(), env, []string{"drift", "-format", "junit", path}))
+	require.Contains(t, stdout.String(), `<testsuite name="swarm drift" tests="2" failures="0">`)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormats are the values of the -o flag of resource commands
+var outputFormats = []string{"table", "json", "yaml"}
+
+// outputFlag adds the -o flag choosing how resources are printed
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", "table", "output format: "+strings.Join(outputFormats, ", "))
+}
+
+func checkOutputFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return &exitError{code: 2, err: fmt.Errorf("unknown output format %q", format)}
+}
+
+// table is a resource listing printed as aligned columns
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// writeOutput prints v, a resource or slice of resources, in the given
+// format. Tables are built by rows from the same value.
+func writeOutput(w io.Writer, format string, v interface{}, rows func() *table) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	case "yaml":
+		return writeYAML(w, v)
+	default:
+		t := rows()
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// writeYAML writes v as YAML using its JSON field names
+func writeYAML(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// readResource decodes a JSON or YAML file, or stdin when path is "-", into
+// v using its JSON field names. Unknown fields are rejected, also in the
+// resources that would otherwise keep them in Extra.
+func readResource(env *environment, path string, v interface{}) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(env.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	// resources keep unknown fields rather than failing on them, but in a
+	// file they are most likely typos
+	if fields := swarm.UnknownFields(v); len(fields) > 0 {
+		return fmt.Errorf("%s: unknown fields %s", path, strings.Join(fields, ", "))
+	}
+	return nil
+}
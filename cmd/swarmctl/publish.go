@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+// progressInterval is how often publish reports progress and saves its
+// checkpoint
+var progressInterval = time.Second
+
+func publishCommand() *command {
+	return &command{
+		name:    "publish",
+		summary: "publish JSON, NDJSON or CSV records to a pipeline",
+		run:     runPublish,
+	}
+}
+
+func runPublish(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "publish", "(-name name | -id id) [flags] [records.json|-]")
+	name := fs.String("name", "", "name of the pipeline to publish to")
+	id := fs.String("id", "", "ID of the pipeline to publish to")
+	format := fs.String("format", "", "json, ndjson or csv; defaults to the file extension, json for stdin")
+	concurrency := fs.Int("concurrency", 4, "number of records published at once")
+	rate := fs.Float64("rate", 0, "maximum records published per second, 0 for no limit")
+	rejectPath := fs.String("reject", "", "append records that fail to this file, one per line, and carry on; unparsable records are written as read, others as JSON")
+	checkpointPath := fs.String("checkpoint", "", "file recording progress; an existing file resumes an interrupted run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*name == "") == (*id == "") || fs.NArg() > 1 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+
+	path := "-"
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	opts := &swarm.PublishStreamOptions{
+		PipelineName: *name,
+		PipelineID:   *id,
+		Format:       swarm.StreamFormat(*format),
+		Concurrency:  *concurrency,
+		Rate:         *rate,
+	}
+	if opts.Format == "" {
+		opts.Format = swarm.StreamFormatFromPath(path)
+	}
+
+	var in io.Reader = env.stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// rejectedSince is set when a record is rejected after the last
+	// checkpoint, which is then saved at once so the checkpoint and the
+	// reject file agree
+	rejectedSince := false
+	if *rejectPath != "" {
+		f, err := os.OpenFile(*rejectPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		opts.Reject = func(rec swarm.Record, err error) error {
+			fmt.Fprintf(env.stderr, "rejected %s\n", err)
+			rejectedSince = true
+			_, werr := fmt.Fprintf(f, "%s\n", rec.Data)
+			return werr
+		}
+	}
+
+	saveCheckpoint := func(*swarm.PublishState) error { return nil }
+	if *checkpointPath != "" {
+		b, err := os.ReadFile(*checkpointPath)
+		switch {
+		case err == nil:
+			opts.State = &swarm.PublishState{}
+			if err := json.Unmarshal(b, opts.State); err != nil {
+				return fmt.Errorf("%s: %w", *checkpointPath, err)
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+		saveCheckpoint = func(state *swarm.PublishState) error {
+			b, err := json.MarshalIndent(state, "", "  ")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(*checkpointPath, b, 0o600)
+		}
+		// writing the file for every record would slow large runs down, so
+		// it is saved periodically, after every reject and once more at the
+		// end
+		var saved time.Time
+		opts.Checkpoint = func(state *swarm.PublishState) error {
+			if !rejectedSince && time.Since(saved) < progressInterval {
+				return nil
+			}
+			saved = time.Now()
+			rejectedSince = false
+			return saveCheckpoint(state)
+		}
+	}
+
+	start := time.Now()
+	reported := start
+	opts.Progress = func(state swarm.PublishState) {
+		if time.Since(reported) < progressInterval {
+			return
+		}
+		reported = time.Now()
+		fmt.Fprintf(env.stderr, "published %d, rejected %d, %.0f/s\n",
+			state.Published, state.Rejected, float64(state.Published+state.Rejected)/time.Since(start).Seconds())
+	}
+
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	state, err := client.Publish.PublishStream(ctx, in, opts)
+	if state != nil {
+		if cerr := saveCheckpoint(state); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	if errors.Is(err, context.Canceled) && state != nil {
+		return fmt.Errorf("interrupted after publishing %d messages and rejecting %d", state.Published, state.Rejected)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Published %d messages, rejected %d.\n", state.Published, state.Rejected)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	env, stdout, _ := testEnv()
+	useServer(t, server)
+	env.stdin = strings.NewReader(`{"hello": "world"}`)
+	require.Equal(t, 0, run(ctx, env, []string{"publish", "-name", "orders"}))
+	require.Equal(t, "Published 1 messages, rejected 0.\n", stdout.String())
+
+	path := writeFile(t, "messages.csv", "hello,n\nfile,1\nfile,2\n")
+	require.Equal(t, 0, run(ctx, env, []string{"publish", "-id", "ID1", "-concurrency", "1", path}))
+
+	published := server.Published()
+	require.Len(t, published, 3)
+	require.Equal(t, "orders", published[0].PipelineName)
+	require.JSONEq(t, `{"hello": "world"}`, string(published[0].Body))
+	require.Equal(t, "ID1", published[1].PipelineID)
+	require.JSONEq(t, `{"hello": "file", "n": "1"}`, string(published[1].Body))
+
+	env, _, stderr := testEnv()
+	useServer(t, server)
+	env.stdin = strings.NewReader(`{"hello":`)
+	require.Equal(t, 1, run(ctx, env, []string{"publish", "-name", "orders"}))
+	require.Equal(t, "swarmctl publish: unexpected EOF\n", stderr.String())
+
+	env, _, _ = testEnv()
+	require.Equal(t, 2, run(ctx, env, []string{"publish", "-name", "orders", "-id", "ID1"}))
+}
+
+func TestPublish_RejectAndCheckpoint(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	dir := t.TempDir()
+	rejects := filepath.Join(dir, "rejects.ndjson")
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	path := writeFile(t, "events.ndjson", "{\"n\": 1}\nnot json\n{\"n\": 3}\n")
+
+	env, stdout, stderr := testEnv()
+	useServer(t, server)
+	require.Equal(t, 0, run(ctx, env, []string{"publish", "-name", "orders", "-reject", rejects, "-checkpoint", checkpoint, path}))
+	require.Equal(t, "Published 2 messages, rejected 1.\n", stdout.String())
+	require.Equal(t, "rejected line 2: invalid JSON\n", stderr.String())
+	require.Len(t, server.Published(), 2)
+
+	b, err := os.ReadFile(rejects)
+	require.NoError(t, err)
+	require.Equal(t, "not json\n", string(b))
+	b, err = os.ReadFile(checkpoint)
+	require.NoError(t, err)
+	var state swarm.PublishState
+	require.NoError(t, json.Unmarshal(b, &state))
+	require.Equal(t, swarm.PublishState{Done: 3, Published: 2, Rejected: 1}, state)
+
+	// the checkpoint records everything as done, so nothing is published again
+	stdout.Reset()
+	require.Equal(t, 0, run(ctx, env, []string{"publish", "-name", "orders", "-checkpoint", checkpoint, path}))
+	require.Equal(t, "Published 2 messages, rejected 1.\n", stdout.String())
+	require.Len(t, server.Published(), 2)
+}
+
+func TestPublish_InterruptAndRejectCheckpoint(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	useServer(t, server)
+	defer func(d time.Duration) { progressInterval = d }(progressInterval)
+	progressInterval = time.Hour
+	dir := t.TempDir()
+	rejects := filepath.Join(dir, "rejects.csv")
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	readState := func() (state swarm.PublishState) {
+		b, err := os.ReadFile(checkpoint)
+		if err == nil {
+			err = json.Unmarshal(b, &state)
+		}
+		if err != nil {
+			return swarm.PublishState{}
+		}
+		return state
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in, input := io.Pipe()
+	env, _, stderr := testEnv()
+	env.stdin = in
+	code := make(chan int)
+	go func() {
+		code <- run(ctx, env, []string{"publish", "-name", "orders", "-format", "csv", "-concurrency", "1",
+			"-reject", rejects, "-checkpoint", checkpoint})
+	}()
+
+	// a malformed row is rejected and checkpointed at once, despite the
+	// interval, and does not end the stream
+	_, err := io.WriteString(input, "hello,n\nx\n")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return readState().Rejected == 1 }, 5*time.Second, 10*time.Millisecond)
+	b, err := os.ReadFile(rejects)
+	require.NoError(t, err)
+	require.Equal(t, "x\n", string(b))
+
+	_, err = io.WriteString(input, "world,2\n")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(server.Published()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// canceling, as Ctrl-C does, stops the run while it waits for input and
+	// saves the checkpoint
+	cancel()
+	require.Equal(t, 1, <-code)
+	require.Equal(t, "rejected line 2: 1 fields, the header has 2\nswarmctl publish: interrupted after publishing 1 messages and rejecting 1\n", stderr.String())
+	require.Equal(t, swarm.PublishState{Done: 2, Published: 1, Rejected: 1}, readState())
+	input.Close()
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+func pipelinesCommand() *command {
+	return groupCommand("pipelines", "list, get, create, update and delete pipelines",
+		&command{name: "list", summary: "list pipelines", run: runPipelinesList},
+		&command{name: "get", summary: "show a pipeline", run: runPipelinesGet},
+		&command{name: "create", summary: "create a pipeline from a file", run: runPipelinesCreate},
+		&command{name: "update", summary: "replace a pipeline with the one in a file", run: runPipelinesUpdate},
+		&command{name: "delete", summary: "delete pipelines that nothing refers to", run: runPipelinesDelete},
+		&command{name: "delete-all", summary: "delete every pipeline", run: runPipelinesDeleteAll},
+	)
+}
+
+func webhookActionsCommand() *command {
+	return groupCommand("webhookactions", "list, get, create, update and delete webhook actions",
+		&command{name: "list", summary: "list webhook actions", run: runWebhookActionsList},
+		&command{name: "get", summary: "show a webhook action", run: runWebhookActionsGet},
+		&command{name: "create", summary: "create a webhook action from a file", run: runWebhookActionsCreate},
+		&command{name: "update", summary: "replace a webhook action with the one in a file", run: runWebhookActionsUpdate},
+		&command{name: "delete", summary: "delete webhook actions that no pipeline outputs to", run: runWebhookActionsDelete},
+		&command{name: "delete-all", summary: "delete every webhook action", run: runWebhookActionsDeleteAll},
+	)
+}
+
+func apiTokensCommand() *command {
+	return groupCommand("apitokens", "list, create and delete API tokens",
+		&command{name: "list", summary: "list API tokens, masked", run: runAPITokensList},
+		&command{name: "create", summary: "create an API token and print it", run: runAPITokensCreate},
+		&command{name: "delete", summary: "delete API tokens", run: runAPITokensDelete},
+		&command{name: "delete-all", summary: "delete every API token", run: runAPITokensDeleteAll},
+	)
+}
+
+func pipelineTable(pipelines ...*swarm.Pipeline) func() *table {
+	return func() *table {
+		t := &table{header: []string{"ID", "NAME", "STEPS", "OUTPUTS", "STITCHES"}}
+		for _, p := range pipelines {
+			t.add(p.ID, p.Name, strconv.Itoa(len(p.Steps)), strconv.Itoa(len(p.Outputs)), strconv.Itoa(len(p.StitchConfigs)))
+		}
+		return t
+	}
+}
+
+func webhookActionTable(actions ...*swarm.WebhookAction) func() *table {
+	return func() *table {
+		t := &table{header: []string{"ID", "NAME", "METHOD", "URL"}}
+		for _, a := range actions {
+			t.add(a.ID, a.Name, a.Method, a.URL)
+		}
+		return t
+	}
+}
+
+func runPipelinesList(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "pipelines list", "[flags]")
+	format := outputFlag(fs)
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	pipelines, _, err := client.Pipelines.List(ctx)
+	if err != nil {
+		return err
+	}
+	if pipelines == nil {
+		pipelines = []*swarm.Pipeline{}
+	}
+	return writeOutput(env.stdout, *format, pipelines, pipelineTable(pipelines...))
+}
+
+func runPipelinesGet(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "pipelines get", "[flags] id")
+	format := outputFlag(fs)
+	byName := fs.Bool("name", false, "look the pipeline up by name instead of ID")
+	if err := parseOneArg(fs, args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	var p *swarm.Pipeline
+	if *byName {
+		p, _, err = client.Pipelines.GetByName(ctx, fs.Arg(0))
+	} else {
+		p, _, err = client.Pipelines.Get(ctx, fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(env.stdout, *format, p, pipelineTable(p))
+}
+
+func runPipelinesCreate(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "pipelines create", "[flags] -f pipeline.yaml")
+	format := outputFlag(fs)
+	file := fs.String("f", "", "JSON or YAML file with the pipeline, - for stdin")
+	if err := parseFileFlag(fs, args, file); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	p := &swarm.Pipeline{}
+	if err := readResource(env, *file, p); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	created, _, err := client.Pipelines.Create(ctx, p)
+	if err != nil {
+		return err
+	}
+	return writeOutput(env.stdout, *format, created, pipelineTable(created))
+}
+
+func runPipelinesUpdate(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "pipelines update", "[flags] -f pipeline.yaml [id]")
+	format := outputFlag(fs)
+	file := fs.String("f", "", "JSON or YAML file with the pipeline, - for stdin")
+	if err := parseFileFlag(fs, args, file); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	p := &swarm.Pipeline{}
+	if err := readResource(env, *file, p); err != nil {
+		return err
+	}
+	id, err := updateID(fs.Args(), p.ID)
+	if err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	updated, _, err := client.Pipelines.UpdateByID(ctx, id, p)
+	if err != nil {
+		return err
+	}
+	return writeOutput(env.stdout, *format, updated, pipelineTable(updated))
+}
+
+func runPipelinesDelete(ctx context.Context, env *environment, args []string) error {
+	return runDelete(ctx, env, args, "pipelines", "pipeline", func(client *swarm.Client) deleteFuncs {
+		return deleteFuncs{client.Pipelines.Delete, client.Pipelines.SafeDelete}
+	})
+}
+
+func runPipelinesDeleteAll(ctx context.Context, env *environment, args []string) error {
+	return runDeleteAll(ctx, env, args, "pipelines", func(client *swarm.Client) deleteAllFunc {
+		return client.Pipelines.DeleteAll
+	})
+}
+
+func runWebhookActionsList(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "webhookactions list", "[flags]")
+	format := outputFlag(fs)
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	actions, _, err := client.WebhookActions.List(ctx)
+	if err != nil {
+		return err
+	}
+	if actions == nil {
+		actions = []*swarm.WebhookAction{}
+	}
+	return writeOutput(env.stdout, *format, actions, webhookActionTable(actions...))
+}
+
+func runWebhookActionsGet(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "webhookactions get", "[flags] id")
+	format := outputFlag(fs)
+	byName := fs.Bool("name", false, "look the webhook action up by name instead of ID")
+	if err := parseOneArg(fs, args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	var a *swarm.WebhookAction
+	if *byName {
+		a, _, err = client.WebhookActions.GetByName(ctx, fs.Arg(0))
+	} else {
+		a, _, err = client.WebhookActions.Get(ctx, fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(env.stdout, *format, a, webhookActionTable(a))
+}
+
+func runWebhookActionsCreate(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "webhookactions create", "[flags] -f action.yaml")
+	format := outputFlag(fs)
+	file := fs.String("f", "", "JSON or YAML file with the webhook action, - for stdin")
+	if err := parseFileFlag(fs, args, file); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	a := &swarm.WebhookAction{}
+	if err := readResource(env, *file, a); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	created, _, err := client.WebhookActions.Create(ctx, a)
+	if err != nil {
+		return err
+	}
+	return writeOutput(env.stdout, *format, created, webhookActionTable(created))
+}
+
+func runWebhookActionsUpdate(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "webhookactions update", "[flags] -f action.yaml [id]")
+	format := outputFlag(fs)
+	file := fs.String("f", "", "JSON or YAML file with the webhook action, - for stdin")
+	if err := parseFileFlag(fs, args, file); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	a := &swarm.WebhookAction{}
+	if err := readResource(env, *file, a); err != nil {
+		return err
+	}
+	id, err := updateID(fs.Args(), a.ID)
+	if err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	a.ID = id
+	updated, _, err := client.WebhookActions.UpdateByID(ctx, id, a)
+	if err != nil {
+		return err
+	}
+	return writeOutput(env.stdout, *format, updated, webhookActionTable(updated))
+}
+
+func runWebhookActionsDelete(ctx context.Context, env *environment, args []string) error {
+	return runDelete(ctx, env, args, "webhookactions", "webhook action", func(client *swarm.Client) deleteFuncs {
+		return deleteFuncs{client.WebhookActions.Delete, client.WebhookActions.SafeDelete}
+	})
+}
+
+func runWebhookActionsDeleteAll(ctx context.Context, env *environment, args []string) error {
+	return runDeleteAll(ctx, env, args, "webhookactions", func(client *swarm.Client) deleteAllFunc {
+		return client.WebhookActions.DeleteAll
+	})
+}
+
+// tokenOutput is how API tokens are printed
+type tokenOutput struct {
+	Token       string     `json:"token"`
+	Fingerprint string     `json:"fingerprint"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+func runAPITokensList(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "apitokens list", "[flags]")
+	format := outputFlag(fs)
+	reveal := fs.Bool("reveal", false, "print the tokens instead of masking them")
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	infos, _, err := client.APITokens.ListInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := []tokenOutput{}
+	for _, info := range infos {
+		token := info.Token.String()
+		if *reveal {
+			token = info.Token.Reveal()
+		}
+		out = append(out, tokenOutput{Token: token, Fingerprint: info.Token.Fingerprint(), CreatedAt: info.CreatedAt, LastUsedAt: info.LastUsedAt})
+	}
+	return writeOutput(env.stdout, *format, out, func() *table {
+		t := &table{header: []string{"TOKEN", "FINGERPRINT", "CREATED", "LAST USED"}}
+		for _, o := range out {
+			t.add(o.Token, o.Fingerprint[:12], formatTime(o.CreatedAt), formatTime(o.LastUsedAt))
+		}
+		return t
+	})
+}
+
+func runAPITokensCreate(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "apitokens create", "[flags]")
+	format := outputFlag(fs)
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*format); err != nil {
+		return err
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	token, _, err := client.APITokens.Create(ctx)
+	if err != nil {
+		return err
+	}
+	// the new token is printed in full, since this is the only way to get it
+	out := tokenOutput{Token: token.Reveal(), Fingerprint: token.Fingerprint()}
+	return writeOutput(env.stdout, *format, out, func() *table {
+		t := &table{header: []string{"TOKEN", "FINGERPRINT"}}
+		t.add(out.Token, out.Fingerprint[:12])
+		return t
+	})
+}
+
+func runAPITokensDelete(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "apitokens delete", "token...")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	for _, arg := range fs.Args() {
+		token := swarm.APIToken(arg)
+		if _, err := client.APITokens.Delete(ctx, token); err != nil {
+			return fmt.Errorf("deleting API token %s: %w", token, err)
+		}
+		fmt.Fprintf(env.stdout, "Deleted API token %s.\n", token)
+	}
+	return nil
+}
+
+func runAPITokensDeleteAll(ctx context.Context, env *environment, args []string) error {
+	return runDeleteAll(ctx, env, args, "apitokens", func(client *swarm.Client) deleteAllFunc {
+		return client.APITokens.DeleteAll
+	})
+}
+
+// deleteFuncs are the Delete and SafeDelete methods of a service
+type deleteFuncs struct {
+	delete     func(ctx context.Context, id string) (*http.Response, error)
+	safeDelete func(ctx context.Context, id string, opts *swarm.DeleteOptions) (*http.Response, error)
+}
+
+// runDelete deletes the resources with the IDs in args, refusing those that
+// pipelines refer to unless told to cascade or forced
+func runDelete(ctx context.Context, env *environment, args []string, group, kind string, funcs func(*swarm.Client) deleteFuncs) error {
+	fs := newFlagSet(env, group+" delete", "[flags] id...")
+	cascade := fs.String("cascade", "none", "what to do with pipelines referring to the resource: none refuses, detach removes the references, delete deletes them")
+	force := fs.Bool("force", false, "delete without checking for references")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+	opts := &swarm.DeleteOptions{}
+	switch *cascade {
+	case "none":
+	case "detach":
+		opts.Cascade = swarm.CascadeDetach
+	case "delete":
+		opts.Cascade = swarm.CascadeDelete
+	default:
+		return &exitError{code: 2, err: fmt.Errorf("unknown cascade %q", *cascade)}
+	}
+
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	f := funcs(client)
+	for _, id := range fs.Args() {
+		if *force {
+			_, err = f.delete(ctx, id)
+		} else {
+			_, err = f.safeDelete(ctx, id, opts)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.stdout, "Deleted %s %s.\n", kind, id)
+	}
+	return nil
+}
+
+type deleteAllFunc func(ctx context.Context, opts ...swarm.DeleteAllOption) (*http.Response, error)
+
+// runDeleteAll deletes every resource of a kind, guarded like a client in
+// safe mode: the customer ID must be given with -confirm
+func runDeleteAll(ctx context.Context, env *environment, args []string, group string, deleteAll func(*swarm.Client) deleteAllFunc) error {
+	fs := newFlagSet(env, group+" delete-all", "[flags]")
+	confirm := fs.String("confirm", "", "customer ID of the account, required to delete anything")
+	dryRun := fs.Bool("dry-run", false, "list what would be deleted without deleting it")
+	backup := fs.String("backup", "", "write a bundle of the account's webhook actions and pipelines to this file first")
+	if err := parseNoArgs(fs, args); err != nil {
+		return err
+	}
+
+	client, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	swarm.SafeMode()(client)
+	var opts []swarm.DeleteAllOption
+	if *confirm != "" {
+		opts = append(opts, swarm.ConfirmDeleteAll(*confirm))
+	}
+	if *dryRun {
+		opts = append(opts, swarm.DryRun(env.stdout))
+	}
+	if *backup != "" && !*dryRun {
+		f, err := os.Create(*backup)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		opts = append(opts, swarm.Backup(f))
+	}
+	if _, err := deleteAll(client)(ctx, opts...); err != nil {
+		return err
+	}
+	if !*dryRun {
+		fmt.Fprintf(env.stdout, "Deleted all %s.\n", group)
+	}
+	return nil
+}
+
+// updateID returns the ID to update: the argument if given, which must match
+// the ID in the file if that has one, otherwise the ID in the file
+func updateID(args []string, fileID string) (string, error) {
+	switch {
+	case len(args) > 1:
+		return "", &exitError{code: 2, err: fmt.Errorf("expected at most one ID, got %d", len(args))}
+	case len(args) == 1 && fileID != "" && fileID != args[0]:
+		return "", fmt.Errorf("the file is for ID %s, not %s", fileID, args[0])
+	case len(args) == 1:
+		return args[0], nil
+	case fileID == "":
+		return "", &exitError{code: 2, err: fmt.Errorf("no ID given and the file has none")}
+	default:
+		return fileID, nil
+	}
+}
+
+func parseNoArgs(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+	return nil
+}
+
+func parseOneArg(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+	return nil
+}
+
+func parseFileFlag(fs *flag.FlagSet, args []string, file *string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelinesCommand(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	hook := server.AddWebhookAction(&swarm.WebhookAction{Name: "hook", URL: "https://example.com", Method: "POST"})
+	ctx := context.Background()
+	newEnv := func() (*environment, *bytes.Buffer) {
+		env, stdout, _ := testEnv()
+		useServer(t, server)
+		return env, stdout
+	}
+
+	path := writeFile(t, "orders.yaml", `name: orders
+steps:
+  - type: filter
+    function: return true;
+outputs: [`+hook.ID+`]
+`)
+	env, stdout := newEnv()
+	require.Equal(t, 0, run(ctx, env, []string{"pipelines", "create", "-f", path, "-o", "json"}))
+	require.Contains(t, stdout.String(), `"name": "orders"`)
+	orders := server.Pipelines()[0]
+
+	env, stdout = newEnv()
+	require.Equal(t, 0, run(ctx, env, []string{"pipelines", "list"}))
+	require.Equal(t, "ID                          NAME    STEPS  OUTPUTS  STITCHES\n"+
+		orders.ID+"  orders  1      1        0\n", stdout.String())
+
+	env, stdout = newEnv()
+	require.Equal(t, 0, run(ctx, env, []string{"pipelines", "get", "-name", "-o", "yaml", "orders"}))
+	require.Contains(t, stdout.String(), "id: "+orders.ID+"\n")
+
+	// the ID comes from the file when no argument is given
+	path = writeFile(t, "update.yaml", "id: "+orders.ID+"\nname: orders\nmaxRetries: 3\n")
+	env, _ = newEnv()
+	require.Equal(t, 0, run(ctx, env, []string{"pipelines", "update", "-f", path}))
+	require.Equal(t, 3, server.Pipelines()[0].MaxRetries)
+
+	env, stdout = newEnv()
+	require.Equal(t, 0, run(ctx, env, []string{"pipelines", "delete", orders.ID}))
+	require.Equal(t, "Deleted pipeline "+orders.ID+".\n", stdout.String())
+	require.Empty(t, server.Pipelines())
+}
+
+func TestPipelinesCommand_UnknownField(t *testing.T) {
+	path := writeFile(t, "orders.yaml", "name: orders\nmaxRetry: 3\n")
+	env, _, stderr := testEnv()
+	env.newClient = func() (*swarm.Client, error) { t.Fatal("no client should be needed"); return nil, nil }
+	require.Equal(t, 1, run(context.Background(), env, []string{"pipelines", "create", "-f", path}))
+	require.Contains(t, stderr.String(), "orders.yaml: unknown fields maxRetry\n")
+
+	env, _, stderr = testEnv()
+	require.Equal(t, 2, run(context.Background(), env, []string{"pipelines", "frobnicate"}))
+	require.Equal(t, "swarmctl pipelines: unknown command \"frobnicate\"\n", stderr.String())
+}
+
+func TestWebhookActionsCommand(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	hook := server.AddWebhookAction(&swarm.WebhookAction{Name: "hook", URL: "https://example.com", Method: "POST"})
+	server.AddPipeline(&swarm.Pipeline{Name: "orders", Outputs: []string{hook.ID}})
+
+	env, stdout, _ := testEnv()
+	useServer(t, server)
+	require.Equal(t, 0, run(ctx, env, []string{"webhookactions", "list"}))
+	require.Equal(t, "ID                          NAME  METHOD  URL\n"+
+		hook.ID+"  hook  POST    https://example.com\n", stdout.String())
+
+	env.stdin = strings.NewReader(`{"name": "hook", "url": "https://example.org", "method": "PUT"}`)
+	stdout.Reset()
+	require.Equal(t, 0, run(ctx, env, []string{"webhookactions", "update", "-f", "-", "-o", "json", hook.ID}))
+	require.Equal(t, "PUT", server.WebhookActions()[0].Method)
+
+	// the pipeline refers to the action, so it is only deleted with -cascade
+	env, _, stderr := testEnv()
+	useServer(t, server)
+	require.Equal(t, 1, run(ctx, env, []string{"webhookactions", "delete", hook.ID}))
+	require.Contains(t, stderr.String(), "swarmctl webhookactions: ")
+	require.Len(t, server.WebhookActions(), 1)
+	require.Equal(t, 0, run(ctx, env, []string{"webhookactions", "delete", "-cascade", "detach", hook.ID}))
+	require.Empty(t, server.WebhookActions())
+	require.Empty(t, server.Pipelines()[0].Outputs)
+}
+
+func TestDeleteAllCommand(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	server.AddPipeline(&swarm.Pipeline{Name: "orders"})
+
+	env, stdout, stderr := testEnv()
+	useServer(t, server)
+	require.Equal(t, 1, run(ctx, env, []string{"pipelines", "delete-all"}))
+	require.Contains(t, stderr.String(), "delete all is not confirmed")
+
+	require.Equal(t, 0, run(ctx, env, []string{"pipelines", "delete-all", "-dry-run"}))
+	require.Contains(t, stdout.String(), `would delete pipeline `)
+	require.Len(t, server.Pipelines(), 1)
+
+	backup := filepath.Join(t.TempDir(), "backup.json")
+	stdout.Reset()
+	require.Equal(t, 0, run(ctx, env, []string{"pipelines", "delete-all", "-confirm", "swarmtest", "-backup", backup}))
+	require.Equal(t, "Deleted all pipelines.\n", stdout.String())
+	require.Empty(t, server.Pipelines())
+	b, err := os.ReadFile(backup)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"orders"`)
+}
+
+func TestAPITokensCommand(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	server.AddAPIToken("0123456789abcdef")
+
+	env, stdout, _ := testEnv()
+	useServer(t, server)
+	require.Equal(t, 0, run(ctx, env, []string{"apitokens", "list"}))
+	require.Contains(t, stdout.String(), "****cdef")
+	require.NotContains(t, stdout.String(), "0123456789abcdef")
+
+	stdout.Reset()
+	require.Equal(t, 0, run(ctx, env, []string{"apitokens", "list", "-reveal", "-o", "json"}))
+	require.Contains(t, stdout.String(), `"token": "0123456789abcdef"`)
+
+	stdout.Reset()
+	require.Equal(t, 0, run(ctx, env, []string{"apitokens", "create", "-o", "json"}))
+	created := server.APITokens()[1]
+	require.Contains(t, stdout.String(), `"token": "`+created.Reveal()+`"`)
+
+	stdout.Reset()
+	require.Equal(t, 0, run(ctx, env, []string{"apitokens", "delete", "0123456789abcdef"}))
+	require.Equal(t, "Deleted API token ****cdef.\n", stdout.String())
+	require.Equal(t, []swarm.APIToken{created}, server.APITokens())
+}
+
+func TestProfileFlag(t *testing.T) {
+	env, _, _ := testEnv()
+	var profile string
+	env.newClient = func() (*swarm.Client, error) {
+		profile = env.profile
+		return nil, os.ErrNotExist
+	}
+	require.Equal(t, 1, run(context.Background(), env, []string{"-profile", "staging", "pipelines", "list"}))
+	require.Equal(t, "staging", profile)
+}
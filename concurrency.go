@@ -0,0 +1,211 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// ErrConflict is returned when a resource was modified by someone else while
+// it was being updated
+var ErrConflict = errors.New("resource was modified concurrently")
+
+// patchAttempts is how many times Patch starts over after detecting a
+// concurrent modification before giving up with ErrConflict
+const patchAttempts = 5
+
+// Patch changes part of a pipeline without overwriting concurrent edits to
+// the rest of it. It gets the pipeline, applies mutate to a copy and sends it
+// with CompareAndUpdate. On ErrConflict it starts over with the newer
+// version, so mutate may be called more than once and must only depend on
+// the pipeline it is given. When the pipeline keeps changing, ErrConflict is
+// returned. If mutate leaves the pipeline unchanged no update is sent and the
+// current pipeline is returned.
+func (s *PipelinesService) Patch(ctx context.Context, id string, mutate func(*Pipeline)) (*Pipeline, *http.Response, error) {
+	var resp *http.Response
+	for attempt := 0; attempt < patchAttempts; attempt++ {
+		current, getResp, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, getResp, err
+		}
+		desired, err := clonePipeline(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		mutate(desired)
+		if equalJSON(current, desired) {
+			return current, getResp, nil
+		}
+
+		var p *Pipeline
+		p, resp, err = s.compareAndUpdate(ctx, current, desired, getResp.Header.Get("ETag"))
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		return p, resp, err
+	}
+
+	return nil, resp, ErrConflict
+}
+
+// CompareAndUpdate replaces the pipeline with desired only if it still
+// matches expected, which is usually a pipeline returned by Get. The pipeline
+// is fetched again and compared with expected first. When the API sends an
+// ETag with it, the update carries it in an If-Match header so the server
+// also rejects changes made in between; otherwise a small window for a race
+// remains. ErrConflict is returned, possibly wrapped, when the pipeline
+// changed.
+func (s *PipelinesService) CompareAndUpdate(ctx context.Context, expected *Pipeline, desired *Pipeline) (*Pipeline, *http.Response, error) {
+	return s.compareAndUpdate(ctx, expected, desired, "")
+}
+
+// compareAndUpdate is CompareAndUpdate for an expected pipeline that came
+// with the given ETag, which is trusted instead of fetching the pipeline
+// again. An empty etag fetches it.
+func (s *PipelinesService) compareAndUpdate(ctx context.Context, expected *Pipeline, desired *Pipeline, etag string) (*Pipeline, *http.Response, error) {
+	if expected == nil {
+		return nil, nil, errors.New("expected pipeline is nil")
+	}
+	if err := s.client.validate(ctx, desired); err != nil {
+		return nil, nil, err
+	}
+
+	if etag == "" {
+		current, resp, err := s.Get(ctx, expected.ID)
+		if err != nil {
+			return nil, resp, err
+		}
+		if !equalJSON(expected, current) {
+			return nil, resp, ErrConflict
+		}
+		etag = resp.Header.Get("ETag")
+	}
+
+	path := fmt.Sprintf("%s/%s", pipelinesPath, expected.ID)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "PUT", path, desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	setIfMatch(req, etag)
+
+	p := new(Pipeline)
+	resp, err := s.client.DoRequest(ctx, req, p)
+	if err != nil {
+		return nil, resp, conflictError(resp, err)
+	}
+
+	return p, resp, nil
+}
+
+// CompareAndUpdate replaces the webhook action with desired only if it still
+// matches expected, see PipelinesService.CompareAndUpdate
+func (s *WebhookActionsService) CompareAndUpdate(ctx context.Context, expected *WebhookAction, desired *WebhookAction) (*WebhookAction, *http.Response, error) {
+	if expected == nil {
+		return nil, nil, errors.New("expected webhook action is nil")
+	}
+	if err := s.client.validate(ctx, desired); err != nil {
+		return nil, nil, err
+	}
+
+	current, resp, err := s.Get(ctx, expected.ID)
+	if err != nil {
+		return nil, resp, err
+	}
+	if !equalJSON(expected, current) {
+		return nil, resp, ErrConflict
+	}
+
+	path := fmt.Sprintf("%s/%s", actionWebhookPath, expected.ID)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "PUT", path, desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	setIfMatch(req, resp.Header.Get("ETag"))
+
+	a := new(WebhookAction)
+	resp, err = s.client.DoRequest(ctx, req, a)
+	if err != nil {
+		return nil, resp, conflictError(resp, err)
+	}
+
+	return a, resp, nil
+}
+
+func setIfMatch(req *http.Request, etag string) {
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+}
+
+// conflictError wraps err in ErrConflict when the server rejected an update
+// because the resource changed
+func conflictError(resp *http.Response, err error) error {
+	if resp != nil && (resp.StatusCode == http.StatusPreconditionFailed || resp.StatusCode == http.StatusConflict) {
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return err
+}
+
+// clonePipeline returns a deep copy of p, including fields this client does
+// not know about
+func clonePipeline(p *Pipeline) (*Pipeline, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Pipeline)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// equalJSON reports whether a and b encode to the same JSON, which is how the
+// API sees them. Unlike reflect.DeepEqual it ignores whitespace and member
+// order in the raw JSON of unknown fields, and treats null and empty lists
+// alike because the API returns empty lists for the nil slices it was sent.
+func equalJSON(a, b interface{}) bool {
+	av, err := normalizedJSON(a)
+	if err != nil {
+		return false
+	}
+	bv, err := normalizedJSON(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// normalizedJSON encodes v and decodes it again with empty lists replaced by
+// null
+func normalizedJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return emptyListsToNull(out), nil
+}
+
+func emptyListsToNull(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		for i := range v {
+			v[i] = emptyListsToNull(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = emptyListsToNull(v[k])
+		}
+	}
+	return v
+}
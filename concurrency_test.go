@@ -0,0 +1,231 @@
+package swarm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pipelineServer serves a single stored pipeline. onGet and onPut, when set,
+// are called with the stored pipeline before every GET and PUT is answered.
+// With etags set it sends ETag headers and honors If-Match.
+type pipelineServer struct {
+	mu     sync.Mutex
+	stored *Pipeline
+	gets   int
+	puts   int
+	onGet  func(p *Pipeline, n int)
+	onPut  func(p *Pipeline)
+	etags  bool
+}
+
+func (s *pipelineServer) etag(t *testing.T) string {
+	b, err := json.Marshal(s.stored)
+	require.NoError(t, err)
+	return fmt.Sprintf(`"%x"`, sha256.Sum256(b))
+}
+
+func newPipelineServer(t *testing.T, mux *http.ServeMux) *pipelineServer {
+	s := &pipelineServer{}
+	require.NoError(t, json.Unmarshal([]byte(testPipelineJSON), &s.stored))
+	mux.HandleFunc("/authenticated/pipelines/", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.Method {
+		case "GET":
+			s.gets++
+			if s.onGet != nil {
+				s.onGet(s.stored, s.gets)
+			}
+		case "PUT":
+			if s.onPut != nil {
+				s.onPut(s.stored)
+			}
+			if match := r.Header.Get("If-Match"); s.etags && match != "" && match != s.etag(t) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			s.puts++
+			s.stored = new(Pipeline)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(s.stored))
+		}
+		if s.etags {
+			w.Header().Set("ETag", s.etag(t))
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(s.stored))
+	})
+	return s
+}
+
+func TestPipelines_Patch(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	server := newPipelineServer(t, mux)
+
+	got, _, err := client.Pipelines.Patch(context.Background(), testPipelineID, func(p *Pipeline) {
+		p.Steps[0].Function = "return message.hello == 'dev';"
+	})
+	require.NoError(t, err)
+	require.Equal(t, "return message.hello == 'dev';", got.Steps[0].Function)
+	require.Equal(t, "return message.hello == 'dev';", server.stored.Steps[0].Function)
+	require.Equal(t, 2, server.gets)
+	require.Equal(t, 1, server.puts)
+}
+
+func TestPipelines_PatchConcurrentEdit(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	server := newPipelineServer(t, mux)
+
+	// a teammate renames the pipeline between our first read and the check
+	server.onGet = func(p *Pipeline, n int) {
+		if n == 2 {
+			p.Name = "renamed"
+		}
+	}
+
+	calls := 0
+	got, _, err := client.Pipelines.Patch(context.Background(), testPipelineID, func(p *Pipeline) {
+		calls++
+		p.MaxRetries = 3
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, "renamed", got.Name)
+	require.Equal(t, 3, got.MaxRetries)
+	require.Equal(t, 1, server.puts)
+}
+
+func TestPipelines_PatchConflict(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	server := newPipelineServer(t, mux)
+	server.onGet = func(p *Pipeline, n int) {
+		p.RetryIntervalSeconds = n
+	}
+
+	_, _, err := client.Pipelines.Patch(context.Background(), testPipelineID, func(p *Pipeline) {
+		p.MaxRetries = 3
+	})
+	require.ErrorIs(t, err, ErrConflict)
+	require.Zero(t, server.puts)
+}
+
+func TestPipelines_PatchUnchanged(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	server := newPipelineServer(t, mux)
+
+	got, _, err := client.Pipelines.Patch(context.Background(), testPipelineID, func(p *Pipeline) {
+		p.Name = "pipeline1"
+	})
+	require.NoError(t, err)
+	require.Equal(t, testPipelineObj, got)
+	require.Zero(t, server.puts)
+}
+
+func TestPipelines_CompareAndUpdateETag(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	server := newPipelineServer(t, mux)
+	server.etags = true
+	ctx := context.Background()
+
+	expected, _, err := client.Pipelines.Get(ctx, testPipelineID)
+	require.NoError(t, err)
+	// the ETag is not kept in the value, so it compares equal to one
+	// decoded without it
+	require.Equal(t, testPipelineObj, expected)
+	desired := *expected
+	desired.MaxRetries = 3
+
+	got, _, err := client.Pipelines.CompareAndUpdate(ctx, expected, &desired)
+	require.NoError(t, err)
+	require.Equal(t, 3, got.MaxRetries)
+	require.Equal(t, 2, server.gets)
+
+	// a change made between the check and the update is rejected by the
+	// server through the ETag of the check
+	server.onPut = func(p *Pipeline) { p.Name = "renamed" }
+	desired.MaxRetries = 4
+	_, resp, err := client.Pipelines.CompareAndUpdate(ctx, got, &desired)
+	require.ErrorIs(t, err, ErrConflict)
+	require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	require.Equal(t, 3, server.stored.MaxRetries)
+	server.onPut = nil
+
+	// Patch trusts the ETag of its own read and does not fetch again
+	server.gets = 0
+	_, _, err = client.Pipelines.Patch(ctx, testPipelineID, func(p *Pipeline) {
+		p.MaxRetries = 5
+	})
+	require.NoError(t, err)
+	require.Equal(t, 5, server.stored.MaxRetries)
+	require.Equal(t, 1, server.gets)
+}
+
+func TestCompareAndUpdate_NilExpected(t *testing.T) {
+	client, _, teardown := setup()
+	defer teardown()
+	ctx := context.Background()
+
+	_, _, err := client.Pipelines.CompareAndUpdate(ctx, nil, testPipelineObj)
+	require.EqualError(t, err, "expected pipeline is nil")
+	_, _, err = client.WebhookActions.CompareAndUpdate(ctx, nil, testWebhookActionObj)
+	require.EqualError(t, err, "expected webhook action is nil")
+}
+
+func TestPipelines_CompareAndUpdateCompare(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	server := newPipelineServer(t, mux)
+	ctx := context.Background()
+
+	expected, _, err := client.Pipelines.Get(ctx, testPipelineID)
+	require.NoError(t, err)
+	desired := *expected
+	desired.MaxRetries = 3
+
+	_, _, err = client.Pipelines.CompareAndUpdate(ctx, expected, &desired)
+	require.NoError(t, err)
+	require.Equal(t, 2, server.gets)
+
+	_, _, err = client.Pipelines.CompareAndUpdate(ctx, expected, &desired)
+	require.ErrorIs(t, err, ErrConflict)
+	require.Equal(t, 1, server.puts)
+}
+
+func TestWebhookActions_CompareAndUpdate(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	stored := testWebhookActionJSON
+	mux.HandleFunc("/authenticated/webhookactions/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			stored = string(b)
+		}
+		fmt.Fprint(w, stored)
+	})
+	ctx := context.Background()
+
+	expected, _, err := client.WebhookActions.Get(ctx, testWebhookActionID)
+	require.NoError(t, err)
+	desired := *expected
+	desired.Name = "renamed"
+
+	got, _, err := client.WebhookActions.CompareAndUpdate(ctx, expected, &desired)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", got.Name)
+
+	_, _, err = client.WebhookActions.CompareAndUpdate(ctx, expected, &desired)
+	require.ErrorIs(t, err, ErrConflict)
+}
@@ -0,0 +1,252 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by NewClientFromEnv and LoadProfile
+const (
+	EnvCustomerID  = "SWARM_CUSTOMER_ID"
+	EnvAPIKey      = "SWARM_API_KEY"
+	EnvBaseURL     = "SWARM_BASE_URL"
+	EnvCustomerURL = "SWARM_CUSTOMER_URL"
+	// EnvProfile names the profile LoadProfile uses when given no name
+	EnvProfile = "SWARM_PROFILE"
+	// EnvConfig overrides the path of the configuration file
+	EnvConfig = "SWARM_CONFIG"
+)
+
+// Config is the configuration file, which holds named profiles such as dev,
+// staging and prod:
+//
+//	defaultProfile: dev
+//	profiles:
+//	  dev:
+//	    customerId: MYCUSTOMERID
+//	    apiKeyEnv: SWARM_DEV_API_KEY
+//	  prod:
+//	    customerId: MYCUSTOMERID
+//	    apiKeyFile: /var/run/secrets/swarm/api-key
+type Config struct {
+	DefaultProfile string              `json:"defaultProfile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles"`
+}
+
+// Profile holds the settings of a client. The API key comes from exactly one
+// of APIKey, APIKeyEnv and APIKeyFile. The URLs override the defaults.
+type Profile struct {
+	// Name is the name of the profile in the configuration file
+	Name        string `json:"-"`
+	CustomerID  string `json:"customerId"`
+	APIKey      string `json:"apiKey,omitempty"`
+	APIKeyEnv   string `json:"apiKeyEnv,omitempty"`
+	APIKeyFile  string `json:"apiKeyFile,omitempty"`
+	BaseURL     string `json:"baseUrl,omitempty"`
+	CustomerURL string `json:"customerUrl,omitempty"`
+}
+
+// NewClientFromEnv creates a client configured by the SWARM_CUSTOMER_ID,
+// SWARM_API_KEY, SWARM_BASE_URL and SWARM_CUSTOMER_URL environment variables.
+// The URLs are optional. The API key is read again on every request.
+func NewClientFromEnv(opts ...ClientOption) (*Client, error) {
+	p := ProfileFromEnv()
+	if p.CustomerID == "" {
+		return nil, fmt.Errorf("%s is not set", EnvCustomerID)
+	}
+	return p.NewClient(opts...)
+}
+
+// ProfileFromEnv returns the profile NewClientFromEnv uses
+func ProfileFromEnv() *Profile {
+	return &Profile{
+		Name:        "environment",
+		CustomerID:  os.Getenv(EnvCustomerID),
+		APIKeyEnv:   EnvAPIKey,
+		BaseURL:     os.Getenv(EnvBaseURL),
+		CustomerURL: os.Getenv(EnvCustomerURL),
+	}
+}
+
+// DefaultConfigPath returns the path of the configuration file: the value of
+// SWARM_CONFIG if set, otherwise swarm/config.yaml in $XDG_CONFIG_HOME or
+// ~/.config.
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "swarm", "config.yaml"), nil
+}
+
+// LoadConfig reads a configuration file in YAML or JSON. Unknown fields are
+// rejected.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c := &Config{}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, p := range c.Profiles {
+		if p == nil {
+			p = &Profile{}
+			c.Profiles[name] = p
+		}
+		p.Name = name
+	}
+	return c, nil
+}
+
+// Profile returns the profile with the given name, or the default profile
+// when name is empty
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return nil, errors.New("no profile given and no default profile configured")
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q: %w, configured profiles are %s", name, ErrNotFound, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// LoadProfile reads the profile with the given name from the configuration
+// file at DefaultConfigPath. An empty name uses SWARM_PROFILE, then the
+// file's default profile.
+func LoadProfile(name string) (*Profile, error) {
+	path, err := DefaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	c, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	return c.Profile(name)
+}
+
+// NewClientFromConfig creates a client from the named profile, see
+// LoadProfile. When no name is given, SWARM_PROFILE is not set and
+// SWARM_CUSTOMER_ID is, the client is configured from the environment like
+// NewClientFromEnv instead, so tools support both ways of configuring them.
+func NewClientFromConfig(name string, opts ...ClientOption) (*Client, error) {
+	if name == "" && os.Getenv(EnvProfile) == "" && os.Getenv(EnvCustomerID) != "" {
+		return NewClientFromEnv(opts...)
+	}
+	p, err := LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.NewClient(opts...)
+}
+
+// Credentials returns the provider of the profile's API key
+func (p *Profile) Credentials() (CredentialsProvider, error) {
+	var sources []CredentialsProvider
+	if p.APIKey != "" {
+		sources = append(sources, StaticCredentials(p.APIKey))
+	}
+	if p.APIKeyEnv != "" {
+		sources = append(sources, EnvCredentials(p.APIKeyEnv))
+	}
+	if p.APIKeyFile != "" {
+		sources = append(sources, NewFileCredentials(p.APIKeyFile))
+	}
+	switch len(sources) {
+	case 0:
+		return nil, fmt.Errorf("profile %q: no API key, set one of apiKey, apiKeyEnv and apiKeyFile", p.Name)
+	case 1:
+		return sources[0], nil
+	default:
+		return nil, fmt.Errorf("profile %q: only one of apiKey, apiKeyEnv and apiKeyFile may be set", p.Name)
+	}
+}
+
+// NewClient creates a client configured by the profile. The options are
+// applied after the profile's settings.
+func (p *Profile) NewClient(opts ...ClientOption) (*Client, error) {
+	if p.CustomerID == "" {
+		return nil, fmt.Errorf("profile %q: no customer ID", p.Name)
+	}
+	creds, err := p.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	// fail early rather than on the first request
+	if _, err := creds.APIKey(context.Background()); err != nil {
+		return nil, err
+	}
+
+	c := NewClient(p.CustomerID, "", WithCredentials(creds))
+	if p.BaseURL != "" {
+		if c.BaseURL, err = parseBaseURL(p.BaseURL); err != nil {
+			return nil, fmt.Errorf("base URL: %w", err)
+		}
+	}
+	if p.CustomerURL != "" {
+		if c.CustomerURL, err = parseBaseURL(p.CustomerURL); err != nil {
+			return nil, fmt.Errorf("customer URL: %w", err)
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// parseBaseURL parses an absolute URL, adding the trailing slash request
+// paths are resolved against
+func parseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute URL", s)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
@@ -0,0 +1,111 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv(EnvConfig, path)
+	return path
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/authenticated/apitokens", r.URL.Path)
+		require.Equal(t, "Bearer ENVKEY", r.Header.Get("Authorization"))
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvCustomerID, "")
+	_, err := NewClientFromEnv()
+	require.EqualError(t, err, "SWARM_CUSTOMER_ID is not set")
+
+	t.Setenv(EnvCustomerID, "ENVCUSTOMER")
+	t.Setenv(EnvAPIKey, "ENVKEY")
+	t.Setenv(EnvBaseURL, server.URL+"/v2")
+	t.Setenv(EnvCustomerURL, "")
+	client, err := NewClientFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "https://ENVCUSTOMER.api.swarmiolabs.com/v1/", client.CustomerURL.String())
+	_, _, err = client.APITokens.List(context.Background())
+	require.NoError(t, err)
+
+	t.Setenv(EnvBaseURL, "not a url")
+	_, err = NewClientFromEnv()
+	require.EqualError(t, err, `base URL: "not a url" is not an absolute URL`)
+}
+
+func TestLoadProfile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("PRODKEY\n"), 0o600))
+	writeConfig(t, `
+defaultProfile: dev
+profiles:
+  dev:
+    customerId: DEVCUSTOMER
+    apiKey: DEVKEY
+    baseUrl: http://localhost:8080
+  prod:
+    customerId: PRODCUSTOMER
+    apiKeyFile: `+keyFile+`
+  broken:
+    customerId: X
+    apiKey: A
+    apiKeyEnv: B
+`)
+	t.Setenv(EnvProfile, "")
+
+	p, err := LoadProfile("")
+	require.NoError(t, err)
+	require.Equal(t, "dev", p.Name)
+	client, err := p.NewClient()
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8080/", client.BaseURL.String())
+
+	t.Setenv(EnvProfile, "prod")
+	client, err = NewClientFromConfig("")
+	require.NoError(t, err)
+	key, err := client.APIKey(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "PRODKEY", key)
+
+	_, err = NewClientFromConfig("broken")
+	require.EqualError(t, err, `profile "broken": only one of apiKey, apiKeyEnv and apiKeyFile may be set`)
+
+	_, err = LoadProfile("qa")
+	require.True(t, errors.Is(err, ErrNotFound))
+	require.EqualError(t, err, `profile "qa": not found, configured profiles are broken, dev, prod`)
+}
+
+func TestLoadConfig_UnknownField(t *testing.T) {
+	path := writeConfig(t, "profiles:\n  dev:\n    customer: X\n")
+	_, err := LoadConfig(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown field "customer"`)
+}
+
+func TestNewClientFromConfig_PrefersEnvironment(t *testing.T) {
+	writeConfig(t, "profiles: {}\n")
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvCustomerID, "ENVCUSTOMER")
+	t.Setenv(EnvAPIKey, "ENVKEY")
+	t.Setenv(EnvBaseURL, "")
+	t.Setenv(EnvCustomerURL, "")
+
+	client, err := NewClientFromConfig("")
+	require.NoError(t, err)
+	require.Equal(t, "https://ENVCUSTOMER.api.swarmiolabs.com/v1/", client.CustomerURL.String())
+}
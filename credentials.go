@@ -0,0 +1,153 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialsProvider supplies the API key for each request, so keys can be
+// rotated without recreating the client
+type CredentialsProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// Refresher is implemented by providers that cache the key. Refresh is called
+// when the API answers 401 Unauthorized, before the request is retried once.
+type Refresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// WithCredentials makes the client authenticate with keys from p instead of
+// the key passed to NewClient
+func WithCredentials(p CredentialsProvider) ClientOption {
+	return func(c *Client) {
+		c.credentials = p
+	}
+}
+
+// StaticCredentials always supplies the same key
+type StaticCredentials string
+
+// APIKey returns the key
+func (s StaticCredentials) APIKey(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+// EnvCredentials reads the key from the environment variable with this name
+// on every request
+type EnvCredentials string
+
+// APIKey returns the value of the variable, which must not be empty
+func (e EnvCredentials) APIKey(ctx context.Context) (string, error) {
+	key := os.Getenv(string(e))
+	if key == "" {
+		return "", fmt.Errorf("environment variable %s is not set", string(e))
+	}
+	return key, nil
+}
+
+// FileCredentials reads the key from a file, such as a mounted Kubernetes
+// secret, and reads it again whenever the file's size or modification time
+// changes. Surrounding whitespace is trimmed.
+type FileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	key     string
+	size    int64
+	modTime time.Time
+}
+
+// NewFileCredentials returns a provider reading the key from path. The file
+// is first read on the first request.
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{path: path}
+}
+
+// APIKey returns the key in the file, reading it again if it changed
+func (f *FileCredentials) APIKey(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+	if f.key == "" || info.Size() != f.size || !info.ModTime().Equal(f.modTime) {
+		if err := f.load(); err != nil {
+			return "", err
+		}
+	}
+	return f.key, nil
+}
+
+// Refresh reads the file again even if it does not look changed
+func (f *FileCredentials) Refresh(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.load()
+}
+
+func (f *FileCredentials) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return fmt.Errorf("%s is empty", f.path)
+	}
+	f.key, f.size, f.modTime = key, info.Size(), info.ModTime()
+	return nil
+}
+
+// ChainCredentials supplies the key of the first provider that has one
+type ChainCredentials []CredentialsProvider
+
+// APIKey tries the providers in order. When none has a key, the errors of all
+// of them are returned.
+func (c ChainCredentials) APIKey(ctx context.Context) (string, error) {
+	var msgs []string
+	for _, p := range c {
+		key, err := p.APIKey(ctx)
+		if err == nil && key != "" {
+			return key, nil
+		}
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) == 0 {
+		return "", errors.New("no credentials")
+	}
+	return "", fmt.Errorf("no credentials: %s", strings.Join(msgs, "; "))
+}
+
+// Refresh refreshes every provider in the chain that can be refreshed. It
+// fails only when all of them fail, since the chain can fall back on the
+// others.
+func (c ChainCredentials) Refresh(ctx context.Context) error {
+	var last error
+	refreshed := false
+	for _, p := range c {
+		if r, ok := p.(Refresher); ok {
+			if err := r.Refresh(ctx); err != nil {
+				last = err
+			} else {
+				refreshed = true
+			}
+		}
+	}
+	if refreshed {
+		return nil
+	}
+	return last
+}
@@ -0,0 +1,138 @@
+package swarm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SWARM_TEST_KEY", "")
+	_, err := EnvCredentials("SWARM_TEST_KEY").APIKey(ctx)
+	require.EqualError(t, err, "environment variable SWARM_TEST_KEY is not set")
+
+	t.Setenv("SWARM_TEST_KEY", "FROMENV")
+	key, err := EnvCredentials("SWARM_TEST_KEY").APIKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "FROMENV", key)
+}
+
+func TestFileCredentials(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "api-key")
+	f := NewFileCredentials(path)
+	_, err := f.APIKey(ctx)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("FIRST\n"), 0o600))
+	key, err := f.APIKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "FIRST", key)
+
+	// a rotated secret is picked up on the next request
+	require.NoError(t, os.WriteFile(path, []byte("SECOND\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	key, err = f.APIKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "SECOND", key)
+}
+
+func TestChainCredentials(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SWARM_TEST_KEY", "")
+	chain := ChainCredentials{EnvCredentials("SWARM_TEST_KEY"), StaticCredentials("STATIC")}
+	key, err := chain.APIKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "STATIC", key)
+
+	_, err = ChainCredentials{EnvCredentials("SWARM_TEST_KEY")}.APIKey(ctx)
+	require.EqualError(t, err, "no credentials: environment variable SWARM_TEST_KEY is not set")
+}
+
+func TestClient_RefreshOn401(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	path := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(path, []byte("OLD"), 0o600))
+	WithCredentials(NewFileCredentials(path))(client)
+
+	var auths, bodies []string
+	mux.HandleFunc("/authenticated/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		auths = append(auths, r.Header.Get("Authorization"))
+		bodies = append(bodies, string(b))
+		if r.Header.Get("Authorization") != "Bearer NEW" {
+			// the secret was rotated without the file looking changed
+			require.NoError(t, os.WriteFile(path, []byte("NEW"), 0o600))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(b)
+	})
+
+	p := &Pipeline{Name: "orders", Steps: []PipelineSteps{FilterStep("return true;")}, Outputs: []string{}}
+	_, _, err := client.Pipelines.Create(context.Background(), p)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Bearer OLD", "Bearer NEW"}, auths)
+	require.Equal(t, bodies[0], bodies[1])
+}
+
+func TestClient_No401RetryForStaticKeys(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/authenticated/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	_, _, err := client.Pipelines.List(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestClient_NewRequestSetsAuthorization(t *testing.T) {
+	client, _, teardown := setup()
+	defer teardown()
+
+	req, err := client.NewRequestWithBaseURL("GET", "authenticated/pipelines", nil)
+	require.NoError(t, err)
+	require.Equal(t, "Bearer TESTAPITOKEN", req.Header.Get("Authorization"))
+
+	WithCredentials(EnvCredentials("SWARM_TEST_UNSET_KEY"))(client)
+	_, err = client.NewRequestWithBaseURL("GET", "authenticated/pipelines", nil)
+	require.EqualError(t, err, "getting API key: environment variable SWARM_TEST_UNSET_KEY is not set")
+}
+
+// blockingCredentials waits for the context to be done before failing
+type blockingCredentials struct{}
+
+func (blockingCredentials) APIKey(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestClient_CredentialsUseRequestContext(t *testing.T) {
+	client, _, teardown := setup()
+	defer teardown()
+	WithCredentials(blockingCredentials{})(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := client.Pipelines.List(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	u, err := client.BaseURL.Parse("authenticated/pipelines")
+	require.NoError(t, err)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.NewRequestWithContext(canceled, "GET", u, nil)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,218 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrReferenced is returned by SafeDelete, wrapped in a *ReferencedError,
+// when pipelines still refer to the resource
+var ErrReferenced = errors.New("still referenced")
+
+// Reference is a place in a pipeline that refers to another resource by ID
+type Reference struct {
+	PipelineID   string
+	PipelineName string
+	// Field is the path of the reference, such as "outputs[0]",
+	// "steps[1].outputs[0]" or "stitchConfigs[0].stitchPipelineId"
+	Field string
+}
+
+func (r Reference) String() string {
+	return fmt.Sprintf("pipeline %q (%s) %s", r.PipelineName, r.PipelineID, r.Field)
+}
+
+// ReferencedError lists the references that kept SafeDelete from deleting a
+// resource. It matches ErrReferenced with errors.Is.
+type ReferencedError struct {
+	Kind       string
+	ID         string
+	References []Reference
+}
+
+func (e *ReferencedError) Error() string {
+	refs := make([]string, len(e.References))
+	for i, r := range e.References {
+		refs[i] = r.String()
+	}
+	return fmt.Sprintf("%s %s: %s by %s", e.Kind, e.ID, ErrReferenced, strings.Join(refs, ", "))
+}
+
+func (e *ReferencedError) Unwrap() error {
+	return ErrReferenced
+}
+
+// Cascade decides what SafeDelete does with pipelines that refer to the
+// resource being deleted
+type Cascade int
+
+const (
+	// CascadeNone refuses to delete a referenced resource
+	CascadeNone Cascade = iota
+	// CascadeDetach removes the references from the referring pipelines,
+	// then deletes the resource
+	CascadeDetach
+	// CascadeDelete deletes the referring pipelines, the pipelines referring
+	// to those and so on, then the resource
+	CascadeDelete
+)
+
+// DeleteOptions configures SafeDelete
+type DeleteOptions struct {
+	Cascade Cascade
+}
+
+// SafeDelete deletes the webhook action only if no pipeline outputs to it.
+// Otherwise it returns a *ReferencedError, unless opts asks to cascade. A nil
+// opts refuses.
+func (s *WebhookActionsService) SafeDelete(ctx context.Context, id string, opts *DeleteOptions) (*http.Response, error) {
+	return s.client.safeDelete(ctx, "webhook action", id, opts, func() (*http.Response, error) {
+		return s.Delete(ctx, id)
+	})
+}
+
+// SafeDelete deletes the pipeline only if no other pipeline outputs to it or
+// stitches with it. Otherwise it returns a *ReferencedError, unless opts asks
+// to cascade. A nil opts refuses.
+func (s *PipelinesService) SafeDelete(ctx context.Context, id string, opts *DeleteOptions) (*http.Response, error) {
+	return s.client.safeDelete(ctx, "pipeline", id, opts, func() (*http.Response, error) {
+		return s.Delete(ctx, id)
+	})
+}
+
+// References lists the places in pipelines that refer to the resource with
+// the given ID, through Outputs, step Outputs or stitch configs. A pipeline
+// referring to itself is not listed.
+func (c *Client) References(ctx context.Context, id string) ([]Reference, error) {
+	pipelines, _, err := c.Pipelines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing pipelines: %w", err)
+	}
+	return references(pipelines, id), nil
+}
+
+func (c *Client) safeDelete(ctx context.Context, kind string, id string, opts *DeleteOptions, del func() (*http.Response, error)) (*http.Response, error) {
+	if opts == nil {
+		opts = &DeleteOptions{}
+	}
+	pipelines, resp, err := c.Pipelines.List(ctx)
+	if err != nil {
+		return resp, fmt.Errorf("listing pipelines: %w", err)
+	}
+	refs := references(pipelines, id)
+	if len(refs) == 0 {
+		return del()
+	}
+
+	switch opts.Cascade {
+	case CascadeDetach:
+		for _, p := range referrers(pipelines, refs) {
+			detached, err := clonePipeline(p)
+			if err != nil {
+				return nil, err
+			}
+			detach(detached, id)
+			if _, resp, err := c.Pipelines.CompareAndUpdate(ctx, p, detached); err != nil {
+				return resp, fmt.Errorf("detaching pipeline %q: %w", p.Name, err)
+			}
+		}
+	case CascadeDelete:
+		// delete everything that refers to the resource, directly or
+		// through other deleted pipelines
+		doomed := map[string]bool{id: true}
+		queue := referrers(pipelines, refs)
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			if doomed[p.ID] {
+				continue
+			}
+			doomed[p.ID] = true
+			if resp, err := c.Pipelines.Delete(ctx, p.ID); err != nil {
+				return resp, fmt.Errorf("deleting pipeline %q: %w", p.Name, err)
+			}
+			queue = append(queue, referrers(pipelines, references(pipelines, p.ID))...)
+		}
+	default:
+		return nil, &ReferencedError{Kind: kind, ID: id, References: refs}
+	}
+	return del()
+}
+
+// references lists the places in pipelines other than id itself that refer
+// to id
+func references(pipelines []*Pipeline, id string) []Reference {
+	var refs []Reference
+	for _, p := range pipelines {
+		if p.ID == id {
+			continue
+		}
+		add := func(field string) {
+			refs = append(refs, Reference{PipelineID: p.ID, PipelineName: p.Name, Field: field})
+		}
+		for i, o := range p.Outputs {
+			if o == id {
+				add(fmt.Sprintf("outputs[%d]", i))
+			}
+		}
+		for i, s := range p.Steps {
+			for j, o := range s.Outputs {
+				if o == id {
+					add(fmt.Sprintf("steps[%d].outputs[%d]", i, j))
+				}
+			}
+		}
+		for i, sc := range p.StitchConfigs {
+			if sc.StitchPipelineID == id {
+				add(fmt.Sprintf("stitchConfigs[%d].stitchPipelineId", i))
+			}
+		}
+	}
+	return refs
+}
+
+// referrers returns the pipelines the references are in, once each
+func referrers(pipelines []*Pipeline, refs []Reference) []*Pipeline {
+	seen := map[string]bool{}
+	var out []*Pipeline
+	for _, r := range refs {
+		if seen[r.PipelineID] {
+			continue
+		}
+		seen[r.PipelineID] = true
+		for _, p := range pipelines {
+			if p.ID == r.PipelineID {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// detach removes every reference to id from p
+func detach(p *Pipeline, id string) {
+	without := func(ids []string) []string {
+		out := []string{}
+		for _, o := range ids {
+			if o != id {
+				out = append(out, o)
+			}
+		}
+		return out
+	}
+	p.Outputs = without(p.Outputs)
+	for i := range p.Steps {
+		p.Steps[i].Outputs = without(p.Steps[i].Outputs)
+	}
+	var stitches []PipelineStitchConfig
+	for _, sc := range p.StitchConfigs {
+		if sc.StitchPipelineID != id {
+			stitches = append(stitches, sc)
+		}
+	}
+	p.StitchConfigs = stitches
+}
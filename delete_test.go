@@ -0,0 +1,91 @@
+package swarm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+// referencedAccount stores a webhook action A, pipeline P1 outputting to it
+// directly and from a step, pipeline P2 stitching with P1 and pipeline P3
+// outputting to P2
+func referencedAccount(server *swarmtest.Server) (action, p1, p2, p3 string) {
+	action = server.AddWebhookAction(&swarm.WebhookAction{Name: "hook"}).ID
+	p1 = server.AddPipeline(&swarm.Pipeline{
+		Name:    "orders",
+		Steps:   []swarm.PipelineSteps{{Function: "return message;", Type: "transform", Outputs: []string{action}}},
+		Outputs: []string{action},
+	}).ID
+	p2 = server.AddPipeline(&swarm.Pipeline{
+		Name:          "refunds",
+		Outputs:       []string{},
+		StitchConfigs: []swarm.PipelineStitchConfig{{StitchPipelineID: p1, Key: "id"}},
+	}).ID
+	p3 = server.AddPipeline(&swarm.Pipeline{Name: "audit", Outputs: []string{p2}}).ID
+	return action, p1, p2, p3
+}
+
+func TestWebhookActions_SafeDelete(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+	action, p1, _, _ := referencedAccount(server)
+
+	_, err := client.WebhookActions.SafeDelete(ctx, action, nil)
+	require.True(t, errors.Is(err, swarm.ErrReferenced))
+	var refErr *swarm.ReferencedError
+	require.True(t, errors.As(err, &refErr))
+	require.Equal(t, []swarm.Reference{
+		{PipelineID: p1, PipelineName: "orders", Field: "outputs[0]"},
+		{PipelineID: p1, PipelineName: "orders", Field: "steps[0].outputs[0]"},
+	}, refErr.References)
+	require.EqualError(t, err, fmt.Sprintf(`webhook action %[1]s: still referenced by pipeline "orders" (%[2]s) outputs[0], pipeline "orders" (%[2]s) steps[0].outputs[0]`, action, p1))
+	require.Zero(t, server.Writes())
+
+	_, err = client.WebhookActions.SafeDelete(ctx, action, &swarm.DeleteOptions{Cascade: swarm.CascadeDetach})
+	require.NoError(t, err)
+	require.Empty(t, server.WebhookActions())
+	pipelines := server.Pipelines()
+	require.Len(t, pipelines, 3)
+	require.Empty(t, pipelines[0].Outputs)
+	require.Empty(t, pipelines[0].Steps[0].Outputs)
+}
+
+func TestPipelines_SafeDelete(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+	_, p1, p2, p3 := referencedAccount(server)
+
+	refs, err := client.References(ctx, p1)
+	require.NoError(t, err)
+	require.Equal(t, []swarm.Reference{{PipelineID: p2, PipelineName: "refunds", Field: "stitchConfigs[0].stitchPipelineId"}}, refs)
+
+	_, err = client.Pipelines.SafeDelete(ctx, p1, nil)
+	require.True(t, errors.Is(err, swarm.ErrReferenced))
+
+	// unreferenced pipelines are deleted without a cascade
+	_, err = client.Pipelines.SafeDelete(ctx, p3, nil)
+	require.NoError(t, err)
+	require.Len(t, server.Pipelines(), 2)
+}
+
+func TestPipelines_SafeDeleteCascade(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+	_, p1, _, _ := referencedAccount(server)
+
+	_, err := client.Pipelines.SafeDelete(ctx, p1, &swarm.DeleteOptions{Cascade: swarm.CascadeDelete})
+	require.NoError(t, err)
+	require.Empty(t, server.Pipelines())
+	require.Len(t, server.WebhookActions(), 1)
+}
@@ -0,0 +1,307 @@
+// Package graph builds the dependency graph of webhook actions and pipelines.
+// Pipelines depend on the webhook actions and pipelines they output to,
+// directly or from a step, and on the pipelines they stitch with. The graph
+// finds dangling references and stitch cycles, orders resources so
+// dependencies come first, and renders Graphviz DOT and Mermaid diagrams.
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/manifest"
+)
+
+// EdgeKind is the kind of reference an edge stands for
+type EdgeKind string
+
+const (
+	// EdgeOutput is an entry of a pipeline's Outputs
+	EdgeOutput EdgeKind = "output"
+	// EdgeStepOutput is an entry of a step's Outputs
+	EdgeStepOutput EdgeKind = "step-output"
+	// EdgeStitch is a stitch config
+	EdgeStitch EdgeKind = "stitch"
+)
+
+// Node is a webhook action or pipeline. Graphs built from List results are
+// keyed by ID, graphs built from manifests by name, in which case ID and
+// Name are the same.
+type Node struct {
+	ID   string
+	Name string
+	Kind manifest.Kind
+}
+
+// Edge is a reference from a pipeline to the node with ID To. Step is the
+// index of the step for EdgeStepOutput edges and -1 otherwise.
+type Edge struct {
+	From string
+	To   string
+	Kind EdgeKind
+	Step int
+}
+
+func (e Edge) String() string {
+	if e.Kind == EdgeStepOutput {
+		return fmt.Sprintf("%s -> %s (%s of step %d)", e.From, e.To, e.Kind, e.Step)
+	}
+	return fmt.Sprintf("%s -> %s (%s)", e.From, e.To, e.Kind)
+}
+
+// Graph is a dependency graph. Nodes keep the order they were added in, and
+// edges the order of the references in each pipeline. The zero value is an
+// empty graph; add nodes with AddNode rather than appending to Nodes.
+type Graph struct {
+	Nodes []*Node
+	Edges []Edge
+
+	byID map[string]*Node
+}
+
+// New returns an empty graph
+func New() *Graph {
+	return &Graph{byID: map[string]*Node{}}
+}
+
+// FromResources builds a graph from List results, keyed by ID
+func FromResources(actions []*swarm.WebhookAction, pipelines []*swarm.Pipeline) *Graph {
+	g := New()
+	for _, a := range actions {
+		g.AddNode(&Node{ID: a.ID, Name: a.Name, Kind: manifest.KindWebhookAction})
+	}
+	for _, p := range pipelines {
+		g.AddNode(&Node{ID: p.ID, Name: p.Name, Kind: manifest.KindPipeline})
+	}
+	for _, p := range pipelines {
+		for _, id := range p.Outputs {
+			g.AddEdge(Edge{From: p.ID, To: id, Kind: EdgeOutput, Step: -1})
+		}
+		for i, s := range p.Steps {
+			for _, id := range s.Outputs {
+				g.AddEdge(Edge{From: p.ID, To: id, Kind: EdgeStepOutput, Step: i})
+			}
+		}
+		for _, c := range p.StitchConfigs {
+			g.AddEdge(Edge{From: p.ID, To: c.StitchPipelineID, Kind: EdgeStitch, Step: -1})
+		}
+	}
+	return g
+}
+
+// FromManifest builds a graph from a manifest, keyed by name
+func FromManifest(m *manifest.Manifest) *Graph {
+	g := New()
+	for _, a := range m.WebhookActions {
+		g.AddNode(&Node{ID: a.Name, Name: a.Name, Kind: manifest.KindWebhookAction})
+	}
+	for _, p := range m.Pipelines {
+		g.AddNode(&Node{ID: p.Name, Name: p.Name, Kind: manifest.KindPipeline})
+	}
+	for _, p := range m.Pipelines {
+		for _, name := range p.Outputs {
+			g.AddEdge(Edge{From: p.Name, To: name, Kind: EdgeOutput, Step: -1})
+		}
+		for i, s := range p.Steps {
+			for _, name := range s.Outputs {
+				g.AddEdge(Edge{From: p.Name, To: name, Kind: EdgeStepOutput, Step: i})
+			}
+		}
+		for _, c := range p.StitchConfigs {
+			g.AddEdge(Edge{From: p.Name, To: c.Pipeline, Kind: EdgeStitch, Step: -1})
+		}
+	}
+	return g
+}
+
+// AddNode adds a node, replacing any node with the same ID
+func (g *Graph) AddNode(n *Node) {
+	if g.byID == nil {
+		g.byID = map[string]*Node{}
+	}
+	if old, ok := g.byID[n.ID]; ok {
+		*old = *n
+		return
+	}
+	g.byID[n.ID] = n
+	g.Nodes = append(g.Nodes, n)
+}
+
+// AddEdge adds an edge unless the same edge was already added
+func (g *Graph) AddEdge(e Edge) {
+	for _, existing := range g.Edges {
+		if existing == e {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, e)
+}
+
+// Node returns the node with the given ID, or nil
+func (g *Graph) Node(id string) *Node {
+	return g.byID[id]
+}
+
+// Dependencies returns the edges leaving the node with the given ID
+func (g *Graph) Dependencies(id string) []Edge {
+	var out []Edge
+	for _, e := range g.Edges {
+		if e.From == id {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Referrers returns the edges pointing at the node with the given ID
+func (g *Graph) Referrers(id string) []Edge {
+	var out []Edge
+	for _, e := range g.Edges {
+		if e.To == id {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Dangling returns the edges pointing at nodes that are not in the graph,
+// such as outputs to deleted webhook actions, and stitches with nodes that
+// are not pipelines
+func (g *Graph) Dangling() []Edge {
+	var out []Edge
+	for _, e := range g.Edges {
+		n := g.byID[e.To]
+		if n == nil || (e.Kind == EdgeStitch && n.Kind != manifest.KindPipeline) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// StitchCycles returns the groups of pipelines that stitch with each other
+// in a cycle, each listed in the order the pipelines were added
+func (g *Graph) StitchCycles() [][]*Node {
+	return g.cycles(func(e Edge) bool { return e.Kind == EdgeStitch })
+}
+
+// CycleError is returned by TopologicalOrder when nodes depend on each other
+type CycleError struct {
+	Nodes []*Node
+}
+
+func (e *CycleError) Error() string {
+	names := make([]string, len(e.Nodes))
+	for i, n := range e.Nodes {
+		names[i] = n.Name
+	}
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(names, ", "))
+}
+
+// TopologicalOrder returns the nodes ordered so every node comes after the
+// nodes it depends on, which is an order they can be created in. Dangling
+// edges are ignored. Ties keep the order nodes were added in. A *CycleError
+// is returned when nodes depend on each other.
+func (g *Graph) TopologicalOrder() ([]*Node, error) {
+	if cycles := g.cycles(func(Edge) bool { return true }); len(cycles) > 0 {
+		return nil, &CycleError{Nodes: cycles[0]}
+	}
+
+	done := map[string]bool{}
+	var order []*Node
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if done[n.ID] {
+			return
+		}
+		done[n.ID] = true
+		for _, e := range g.Dependencies(n.ID) {
+			if dep := g.byID[e.To]; dep != nil {
+				visit(dep)
+			}
+		}
+		order = append(order, n)
+	}
+	for _, n := range g.Nodes {
+		visit(n)
+	}
+	return order, nil
+}
+
+// cycles finds the strongly connected components of the subgraph of edges
+// accepted by follow that contain a cycle, using Tarjan's algorithm
+func (g *Graph) cycles(follow func(Edge) bool) [][]*Node {
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []*Node
+	var components [][]*Node
+	next := 0
+
+	var connect func(n *Node)
+	connect = func(n *Node) {
+		index[n.ID] = next
+		low[n.ID] = next
+		next++
+		stack = append(stack, n)
+		onStack[n.ID] = true
+
+		selfLoop := false
+		for _, e := range g.Dependencies(n.ID) {
+			dep := g.byID[e.To]
+			if dep == nil || !follow(e) {
+				continue
+			}
+			if dep == n {
+				selfLoop = true
+			}
+			if _, seen := index[dep.ID]; !seen {
+				connect(dep)
+				if low[dep.ID] < low[n.ID] {
+					low[n.ID] = low[dep.ID]
+				}
+			} else if onStack[dep.ID] && index[dep.ID] < low[n.ID] {
+				low[n.ID] = index[dep.ID]
+			}
+		}
+
+		if low[n.ID] != index[n.ID] {
+			return
+		}
+		var component []*Node
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top.ID] = false
+			component = append(component, top)
+			if top == n {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			components = append(components, g.sorted(component))
+		}
+	}
+
+	for _, n := range g.Nodes {
+		if _, seen := index[n.ID]; !seen {
+			connect(n)
+		}
+	}
+	return components
+}
+
+// sorted returns nodes in the order they were added to the graph
+func (g *Graph) sorted(nodes []*Node) []*Node {
+	in := map[*Node]bool{}
+	for _, n := range nodes {
+		in[n] = true
+	}
+	var out []*Node
+	for _, n := range g.Nodes {
+		if in[n] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
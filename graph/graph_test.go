@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func testGraph() *Graph {
+	actions := []*swarm.WebhookAction{{ID: "A1", Name: "hook"}}
+	pipelines := []*swarm.Pipeline{
+		{ID: "P1", Name: "orders", Outputs: []string{"P2"}, StitchConfigs: []swarm.PipelineStitchConfig{{StitchPipelineID: "P3"}}},
+		{ID: "P2", Name: "enrich", Steps: []swarm.PipelineSteps{{Outputs: []string{"A1", "A9"}}}, Outputs: []string{"A1"}},
+		{ID: "P3", Name: "refunds", StitchConfigs: []swarm.PipelineStitchConfig{{StitchPipelineID: "P1"}}},
+	}
+	return FromResources(actions, pipelines)
+}
+
+func names(nodes []*Node) []string {
+	var out []string
+	for _, n := range nodes {
+		out = append(out, n.Name)
+	}
+	return out
+}
+
+func TestFromResources(t *testing.T) {
+	g := testGraph()
+	require.Len(t, g.Nodes, 4)
+	require.Equal(t, []Edge{
+		{From: "P1", To: "P2", Kind: EdgeOutput, Step: -1},
+		{From: "P1", To: "P3", Kind: EdgeStitch, Step: -1},
+		{From: "P2", To: "A1", Kind: EdgeOutput, Step: -1},
+		{From: "P2", To: "A1", Kind: EdgeStepOutput, Step: 0},
+		{From: "P2", To: "A9", Kind: EdgeStepOutput, Step: 0},
+		{From: "P3", To: "P1", Kind: EdgeStitch, Step: -1},
+	}, g.Edges)
+	require.Equal(t, "P2 -> A9 (step-output of step 0)", g.Edges[4].String())
+	require.Len(t, g.Referrers("A1"), 2)
+	require.Equal(t, &Node{ID: "A1", Name: "hook", Kind: manifest.KindWebhookAction}, g.Node("A1"))
+}
+
+func TestDangling(t *testing.T) {
+	g := testGraph()
+	g.AddEdge(Edge{From: "P3", To: "A1", Kind: EdgeStitch, Step: -1})
+	require.Equal(t, []Edge{
+		{From: "P2", To: "A9", Kind: EdgeStepOutput, Step: 0},
+		{From: "P3", To: "A1", Kind: EdgeStitch, Step: -1},
+	}, g.Dangling())
+}
+
+func TestZeroGraph(t *testing.T) {
+	var g Graph
+	require.Nil(t, g.Node("P1"))
+	g.AddNode(&Node{ID: "P1", Name: "orders", Kind: manifest.KindPipeline})
+	g.AddEdge(Edge{From: "P1", To: "A1", Kind: EdgeOutput, Step: -1})
+	require.Equal(t, "orders", g.Node("P1").Name)
+	require.Equal(t, []Edge{{From: "P1", To: "A1", Kind: EdgeOutput, Step: -1}}, g.Dangling())
+}
+
+func TestStitchCycles(t *testing.T) {
+	g := testGraph()
+	cycles := g.StitchCycles()
+	require.Len(t, cycles, 1)
+	require.Equal(t, []string{"orders", "refunds"}, names(cycles[0]))
+
+	g.AddEdge(Edge{From: "P2", To: "P2", Kind: EdgeStitch, Step: -1})
+	require.Len(t, g.StitchCycles(), 2)
+}
+
+func TestTopologicalOrder(t *testing.T) {
+	_, err := testGraph().TopologicalOrder()
+	var cycle *CycleError
+	require.True(t, errors.As(err, &cycle))
+	require.EqualError(t, err, "dependency cycle: orders, refunds")
+
+	m, err := manifest.Parse([]byte(`
+pipelines:
+  - name: orders
+    outputs: [enrich]
+    stitchConfigs: [{pipeline: refunds, key: id}]
+  - name: enrich
+    steps: [{type: transform, function: return message;, outputs: [hook]}]
+  - name: refunds
+webhookActions:
+  - name: hook
+`))
+	require.NoError(t, err)
+	order, err := FromManifest(m).TopologicalOrder()
+	require.NoError(t, err)
+	require.Equal(t, []string{"hook", "enrich", "refunds", "orders"}, names(order))
+}
+
+func TestWriteDOT(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, testGraph().WriteDOT(buf))
+	require.Equal(t, `digraph swarm {
+  rankdir=LR;
+  "A1" [label="hook", shape=ellipse];
+  "P1" [label="orders", shape=box];
+  "P2" [label="enrich", shape=box];
+  "P3" [label="refunds", shape=box];
+  "A9" [label="A9 (missing)", shape=ellipse, style=dashed, color=red];
+  "P1" -> "P2";
+  "P1" -> "P3" [style=dashed, label="stitch"];
+  "P2" -> "A1";
+  "P2" -> "A1" [label="step 0"];
+  "P2" -> "A9" [label="step 0"];
+  "P3" -> "P1" [style=dashed, label="stitch"];
+}
+`, buf.String())
+}
+
+func TestWriteMermaid(t *testing.T) {
+	g := testGraph()
+	g.Node("P3").Name = `refunds "v2"`
+	buf := &bytes.Buffer{}
+	require.NoError(t, g.WriteMermaid(buf))
+	require.Equal(t, `flowchart LR
+  n0(["hook"])
+  n1["orders"]
+  n2["enrich"]
+  n3["refunds #quot;v2#quot;"]
+  n4{{"A9 (missing)"}}
+  n1 --> n2
+  n1 -. stitch .-> n3
+  n2 --> n0
+  n2 -- step 0 --> n0
+  n2 -- step 0 --> n4
+  n3 -. stitch .-> n1
+`, buf.String())
+}
@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/catalystsquad/swarm-client-go/manifest"
+)
+
+// WriteDOT renders the graph in the Graphviz DOT language. Pipelines are
+// boxes, webhook actions ellipses, stitches dashed edges and the targets of
+// dangling references red dashed nodes.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph swarm {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	for _, n := range g.Nodes {
+		shape := "ellipse"
+		if n.Kind == manifest.KindPipeline {
+			shape = "box"
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, shape=%s];\n", strconv.Quote(n.ID), strconv.Quote(n.Name), shape)
+	}
+	for _, id := range g.missing() {
+		fmt.Fprintf(bw, "  %s [label=%s, shape=ellipse, style=dashed, color=red];\n", strconv.Quote(id), strconv.Quote(id+" (missing)"))
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		switch e.Kind {
+		case EdgeStitch:
+			attrs = append(attrs, "style=dashed", `label="stitch"`)
+		case EdgeStepOutput:
+			attrs = append(attrs, fmt.Sprintf(`label="step %d"`, e.Step))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, "  %s -> %s [%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(bw, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteMermaid renders the graph as a Mermaid flowchart. Pipelines are
+// rectangles, webhook actions stadiums, stitches dotted links and the
+// targets of dangling references marked missing.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	// Mermaid IDs are restricted, so nodes get generated ones
+	ids := map[string]string{}
+	nodeID := func(id string) string {
+		if _, ok := ids[id]; !ok {
+			ids[id] = fmt.Sprintf("n%d", len(ids))
+		}
+		return ids[id]
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart LR")
+	for _, n := range g.Nodes {
+		if n.Kind == manifest.KindPipeline {
+			fmt.Fprintf(bw, "  %s[%s]\n", nodeID(n.ID), mermaidLabel(n.Name))
+		} else {
+			fmt.Fprintf(bw, "  %s([%s])\n", nodeID(n.ID), mermaidLabel(n.Name))
+		}
+	}
+	for _, id := range g.missing() {
+		fmt.Fprintf(bw, "  %s{{%s}}\n", nodeID(id), mermaidLabel(id+" (missing)"))
+	}
+	for _, e := range g.Edges {
+		switch e.Kind {
+		case EdgeStitch:
+			fmt.Fprintf(bw, "  %s -. stitch .-> %s\n", nodeID(e.From), nodeID(e.To))
+		case EdgeStepOutput:
+			fmt.Fprintf(bw, "  %s -- step %d --> %s\n", nodeID(e.From), e.Step, nodeID(e.To))
+		default:
+			fmt.Fprintf(bw, "  %s --> %s\n", nodeID(e.From), nodeID(e.To))
+		}
+	}
+	return bw.Flush()
+}
+
+// missing returns the IDs edges point at that are not nodes, in the order
+// they are first referenced
+func (g *Graph) missing() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, e := range g.Edges {
+		if g.byID[e.To] == nil && !seen[e.To] {
+			seen[e.To] = true
+			out = append(out, e.To)
+		}
+	}
+	return out
+}
+
+// mermaidLabel quotes a label, escaping the characters Mermaid would
+// otherwise interpret
+func mermaidLabel(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
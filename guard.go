@@ -0,0 +1,99 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUnconfirmed is returned by DeleteAll, possibly wrapped, on clients using
+// SafeMode when the call is not confirmed with ConfirmDeleteAll, and on any
+// client when it is confirmed with the wrong customer ID
+var ErrUnconfirmed = errors.New("delete all is not confirmed")
+
+// SafeMode makes DeleteAll on every service refuse to delete anything unless
+// it is confirmed with ConfirmDeleteAll
+func SafeMode() ClientOption {
+	return func(c *Client) {
+		c.safeMode = true
+	}
+}
+
+// DeleteAllOption configures DeleteAll
+type DeleteAllOption func(*deleteAllOptions)
+
+type deleteAllOptions struct {
+	confirm string
+	dryRun  io.Writer
+	backup  io.Writer
+}
+
+// ConfirmDeleteAll confirms DeleteAll with the customer ID of the account
+// being wiped, which must be the one the client was created with. It is
+// required on clients using SafeMode and checked, but optional, on others.
+func ConfirmDeleteAll(customerID string) DeleteAllOption {
+	return func(o *deleteAllOptions) {
+		o.confirm = customerID
+	}
+}
+
+// DryRun makes DeleteAll write what it would delete to w, one resource per
+// line, instead of deleting it. A dry run needs no confirmation.
+func DryRun(w io.Writer) DeleteAllOption {
+	return func(o *deleteAllOptions) {
+		o.dryRun = w
+	}
+}
+
+// Backup makes DeleteAll write an export of the account's webhook actions and
+// pipelines to w as JSON before deleting, see Client.Export. It is not
+// supported for API tokens, which cannot be restored.
+func Backup(w io.Writer) DeleteAllOption {
+	return func(o *deleteAllOptions) {
+		o.backup = w
+	}
+}
+
+// guardDeleteAll applies the DeleteAll options. It reports whether to go on
+// with the delete; a dry run writes the descriptions returned by list and
+// stops.
+func (c *Client) guardDeleteAll(ctx context.Context, kind string, opts []DeleteAllOption, list func() ([]string, error)) (bool, error) {
+	o := &deleteAllOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.confirm != "" && o.confirm != c.customerID {
+		return false, fmt.Errorf("%w: %q is not the customer ID of this client", ErrUnconfirmed, o.confirm)
+	}
+	if o.backup != nil && kind == "API token" {
+		return false, errors.New("API tokens cannot be backed up")
+	}
+
+	if o.dryRun != nil {
+		resources, err := list()
+		if err != nil {
+			return false, err
+		}
+		for _, r := range resources {
+			if _, err := fmt.Fprintf(o.dryRun, "would delete %s %s\n", kind, r); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	if c.safeMode && o.confirm == "" {
+		return false, fmt.Errorf("%w: deleting every %s requires ConfirmDeleteAll", ErrUnconfirmed, kind)
+	}
+	if o.backup != nil {
+		b, err := c.Export(ctx)
+		if err != nil {
+			return false, fmt.Errorf("backing up: %w", err)
+		}
+		if err := b.WriteJSON(o.backup); err != nil {
+			return false, fmt.Errorf("backing up: %w", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,96 @@
+package swarm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteAll_SafeMode(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client(swarm.SafeMode())
+	ctx := context.Background()
+	server.AddPipeline(&swarm.Pipeline{Name: "orders"})
+	server.AddWebhookAction(&swarm.WebhookAction{Name: "hook"})
+
+	_, err := client.Pipelines.DeleteAll(ctx)
+	require.True(t, errors.Is(err, swarm.ErrUnconfirmed))
+	_, err = client.WebhookActions.DeleteAll(ctx, swarm.ConfirmDeleteAll("OTHERCUSTOMER"))
+	require.True(t, errors.Is(err, swarm.ErrUnconfirmed))
+	require.EqualError(t, err, `delete all is not confirmed: "OTHERCUSTOMER" is not the customer ID of this client`)
+	_, err = client.APITokens.DeleteAll(ctx)
+	require.True(t, errors.Is(err, swarm.ErrUnconfirmed))
+	require.Zero(t, server.Writes())
+
+	_, err = client.Pipelines.DeleteAll(ctx, swarm.ConfirmDeleteAll("swarmtest"))
+	require.NoError(t, err)
+	require.Empty(t, server.Pipelines())
+}
+
+func TestDeleteAll_DryRun(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client(swarm.SafeMode())
+	ctx := context.Background()
+	p := server.AddPipeline(&swarm.Pipeline{Name: "orders"})
+	a := server.AddWebhookAction(&swarm.WebhookAction{Name: "hook"})
+	token, _, err := client.APITokens.Create(ctx)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	_, err = client.Pipelines.DeleteAll(ctx, swarm.DryRun(buf))
+	require.NoError(t, err)
+	_, err = client.WebhookActions.DeleteAll(ctx, swarm.DryRun(buf))
+	require.NoError(t, err)
+	_, err = client.APITokens.DeleteAll(ctx, swarm.DryRun(buf))
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf(`would delete pipeline %s "orders"
+would delete webhook action %s "hook"
+would delete API token ****0003
+`, p.ID, a.ID), buf.String())
+	require.NotContains(t, buf.String(), token.Reveal())
+	require.Equal(t, 1, server.Writes())
+}
+
+func TestDeleteAll_WithoutSafeMode(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+	server.AddPipeline(&swarm.Pipeline{Name: "orders"})
+
+	// a wrong confirmation is rejected, but none is needed
+	_, err := client.Pipelines.DeleteAll(ctx, swarm.ConfirmDeleteAll("OTHERCUSTOMER"))
+	require.True(t, errors.Is(err, swarm.ErrUnconfirmed))
+	_, err = client.Pipelines.DeleteAll(ctx)
+	require.NoError(t, err)
+	require.Empty(t, server.Pipelines())
+}
+
+func TestDeleteAll_Backup(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+	server.AddPipeline(&swarm.Pipeline{Name: "orders", Outputs: []string{}})
+
+	buf := &bytes.Buffer{}
+	_, err := client.Pipelines.DeleteAll(ctx, swarm.Backup(buf))
+	require.NoError(t, err)
+	require.Empty(t, server.Pipelines())
+
+	b, err := swarm.ReadBundle(buf)
+	require.NoError(t, err)
+	require.Len(t, b.Pipelines, 1)
+	require.Equal(t, "orders", b.Pipelines[0].Name)
+
+	_, err = client.APITokens.DeleteAll(ctx, swarm.Backup(buf))
+	require.EqualError(t, err, "API tokens cannot be backed up")
+}
@@ -0,0 +1,284 @@
+package swarm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownFieldsError is reported by clients created with StrictDecoding when
+// a response contains fields this client does not know about. Fields holds
+// their JSON paths, such as "steps[0].newField".
+type UnknownFieldsError struct {
+	// Method and Path identify the request whose response had the fields
+	Method string
+	Path   string
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("response to %s %s contains unknown fields: %s", e.Method, e.Path, strings.Join(e.Fields, ", "))
+}
+
+// knownFieldCache maps a struct type to the JSON names of its fields
+var knownFieldCache sync.Map
+
+func knownFields(t reflect.Type) []string {
+	if names, ok := knownFieldCache.Load(t); ok {
+		return names.([]string)
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	knownFieldCache.Store(t, names)
+	return names
+}
+
+// jsonName returns the name a struct field is encoded under, if any
+func jsonName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// isKnownField reports whether name matches one of the known field names,
+// case insensitively like encoding/json
+func isKnownField(known []string, name string) bool {
+	for _, k := range known {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalWithExtra decodes data into v, which must be a pointer to a struct
+// without its own UnmarshalJSON method, and returns the object members that
+// did not match any of its fields. Matching is case insensitive like
+// encoding/json. The result is nil when there are no extra members.
+func unmarshalWithExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+
+	known := knownFields(reflect.TypeOf(v).Elem())
+	var extra map[string]json.RawMessage
+	for k, raw := range members {
+		if isKnownField(known, k) {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[k] = raw
+	}
+	return extra, nil
+}
+
+// marshalWithExtra encodes v, which must be a struct without its own
+// MarshalJSON method, and appends the extra members in key order. Extra
+// members named like one of v's fields, compared case insensitively as when
+// decoding, are skipped so the output has no duplicate keys and the field
+// wins.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	b := bytes.TrimRight(buf.Bytes(), "\n")
+	if len(extra) == 0 {
+		return b, nil
+	}
+
+	known := knownFields(reflect.TypeOf(v))
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		if !isKnownField(known, k) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return b, nil
+	}
+	sort.Strings(keys)
+
+	out := &bytes.Buffer{}
+	out.Write(b[:len(b)-1])
+	for i, k := range keys {
+		if i > 0 || len(b) > 2 {
+			out.WriteByte(',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(kb)
+		out.WriteByte(':')
+		out.Write(extra[k])
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// UnknownFields returns the JSON paths of every field decoded into v that
+// this client does not know about, in a stable order. It accepts the values
+// returned by the services, including slices of them.
+func UnknownFields(v interface{}) []string {
+	var out []string
+	collectUnknown(reflect.ValueOf(v), "", &out)
+	return out
+}
+
+var extraType = reflect.TypeOf(map[string]json.RawMessage{})
+
+func collectUnknown(v reflect.Value, path string, out *[]string) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectUnknown(v.Elem(), path, out)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectUnknown(v.Index(i), fmt.Sprintf("%s[%d]", path, i), out)
+		}
+	case reflect.Struct:
+		prefix := path
+		if prefix != "" {
+			prefix += "."
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Name == "Extra" && f.Type == extraType {
+				keys := make([]string, 0, v.Field(i).Len())
+				for _, k := range v.Field(i).MapKeys() {
+					keys = append(keys, k.String())
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					*out = append(*out, prefix+k)
+				}
+				continue
+			}
+			if name, ok := jsonName(f); ok {
+				collectUnknown(v.Field(i), prefix+name, out)
+			}
+		}
+	}
+}
+
+// The types below keep unknown members in their Extra field when decoded and
+// write them back when encoded, so a Get, modify, Update cycle does not drop
+// fields added to the API after this client was written.
+
+// UnmarshalJSON decodes a pipeline, keeping unknown members in Extra
+func (p *Pipeline) UnmarshalJSON(data []byte) error {
+	type alias Pipeline
+	a := (*alias)(p)
+	extra, err := unmarshalWithExtra(data, a)
+	if err != nil {
+		return err
+	}
+	p.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a pipeline, including the members in Extra
+func (p Pipeline) MarshalJSON() ([]byte, error) {
+	type alias Pipeline
+	return marshalWithExtra(alias(p), p.Extra)
+}
+
+// UnmarshalJSON decodes a step, keeping unknown members in Extra
+func (s *PipelineSteps) UnmarshalJSON(data []byte) error {
+	type alias PipelineSteps
+	a := (*alias)(s)
+	extra, err := unmarshalWithExtra(data, a)
+	if err != nil {
+		return err
+	}
+	s.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a step, including the members in Extra
+func (s PipelineSteps) MarshalJSON() ([]byte, error) {
+	type alias PipelineSteps
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+// UnmarshalJSON decodes a stitch config, keeping unknown members in Extra
+func (c *PipelineStitchConfig) UnmarshalJSON(data []byte) error {
+	type alias PipelineStitchConfig
+	a := (*alias)(c)
+	extra, err := unmarshalWithExtra(data, a)
+	if err != nil {
+		return err
+	}
+	c.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a stitch config, including the members in Extra
+func (c PipelineStitchConfig) MarshalJSON() ([]byte, error) {
+	type alias PipelineStitchConfig
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+// UnmarshalJSON decodes a webhook action, keeping unknown members in Extra
+func (a *WebhookAction) UnmarshalJSON(data []byte) error {
+	type alias WebhookAction
+	al := (*alias)(a)
+	extra, err := unmarshalWithExtra(data, al)
+	if err != nil {
+		return err
+	}
+	a.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a webhook action, including the members in Extra
+func (a WebhookAction) MarshalJSON() ([]byte, error) {
+	type alias WebhookAction
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+// UnmarshalJSON decodes a header, keeping unknown members in Extra
+func (h *WebhookActionsHeader) UnmarshalJSON(data []byte) error {
+	type alias WebhookActionsHeader
+	a := (*alias)(h)
+	extra, err := unmarshalWithExtra(data, a)
+	if err != nil {
+		return err
+	}
+	h.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a header, including the members in Extra
+func (h WebhookActionsHeader) MarshalJSON() ([]byte, error) {
+	type alias WebhookActionsHeader
+	return marshalWithExtra(alias(h), h.Extra)
+}
@@ -0,0 +1,128 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPipelineExtraJSON = `{
+  "id": "01G6XSSVAAT8CNRGXPSBZSA1PY",
+  "name": "pipeline1",
+  "steps": [
+    {
+      "function": "return message.a < 1 && message.b > 2;",
+      "outputs": [],
+      "required": true,
+      "type": "filter",
+      "timeoutMs": 500
+    }
+  ],
+  "outputs": [],
+  "persistOutput": false,
+  "stitchConfigs": null,
+  "retryIntervalSeconds": 60,
+  "maxRetries": -1,
+  "labels": {"team": "orders"},
+  "paused": true
+}`
+
+func TestPipeline_PreservesUnknownFields(t *testing.T) {
+	p := new(Pipeline)
+	require.NoError(t, json.Unmarshal([]byte(testPipelineExtraJSON), p))
+	require.Equal(t, map[string]json.RawMessage{
+		"labels": json.RawMessage(`{"team": "orders"}`),
+		"paused": json.RawMessage(`true`),
+	}, p.Extra)
+	require.Equal(t, map[string]json.RawMessage{"timeoutMs": json.RawMessage(`500`)}, p.Steps[0].Extra)
+	require.Equal(t, []string{"steps[0].timeoutMs", "labels", "paused"}, UnknownFields(p))
+
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, testPipelineExtraJSON, string(b))
+}
+
+func TestPipeline_NoUnknownFields(t *testing.T) {
+	p := new(Pipeline)
+	require.NoError(t, json.Unmarshal([]byte(testPipelineJSON), p))
+	require.Nil(t, p.Extra)
+	require.Empty(t, UnknownFields(p))
+
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, testPipelineJSON, string(b))
+
+	// keys match declared fields case insensitively, like encoding/json
+	a := new(WebhookAction)
+	require.NoError(t, json.Unmarshal([]byte(`{"ID": "abc", "Name": "hook"}`), a))
+	require.Equal(t, "abc", a.ID)
+	require.Nil(t, a.Extra)
+}
+
+func TestWebhookActions_UpdateRoundTripsUnknownFields(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	const withExtra = `{"id": "01G7816ZYJT8CNRGXPSBZSA1PY", "name": "webhook1", "url": "https://example.com/mywebhook/123",
+		"method": "POST", "headers": [{"name": "X-Key", "value": "1", "secret": true}], "successCodes": [200],
+		"retryIntervalSeconds": 60, "maxRetries": -1, "timeoutSeconds": 30}`
+
+	var sent map[string]interface{}
+	mux.HandleFunc("/authenticated/webhookactions/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(b, &sent))
+		}
+		fmt.Fprint(w, withExtra)
+	})
+
+	ctx := context.Background()
+	a, _, err := client.WebhookActions.Get(ctx, testWebhookActionID)
+	require.NoError(t, err)
+	a.Name = "renamed"
+	_, _, err = client.WebhookActions.Update(ctx, a.ID, a)
+	require.NoError(t, err)
+
+	require.Equal(t, "renamed", sent["name"])
+	require.Equal(t, 30.0, sent["timeoutSeconds"])
+	require.Equal(t, true, sent["headers"].([]interface{})[0].(map[string]interface{})["secret"])
+}
+
+func TestStrictDecoding(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	var reports []*UnknownFieldsError
+	StrictDecoding(func(err *UnknownFieldsError) { reports = append(reports, err) })(client)
+
+	mux.HandleFunc("/authenticated/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[`+testPipelineJSON+`,`+testPipelineExtraJSON+`]`)
+	})
+
+	// the request succeeds and the unknown fields are reported on the side
+	pipelines, _, err := client.Pipelines.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pipelines, 2)
+	require.Len(t, reports, 1)
+	require.Equal(t, []string{"[1].steps[0].timeoutMs", "[1].labels", "[1].paused"}, reports[0].Fields)
+	require.Equal(t, "response to GET /authenticated/pipelines contains unknown fields: [1].steps[0].timeoutMs, [1].labels, [1].paused", reports[0].Error())
+}
+
+func TestMarshalJSON_ExtraCollidesWithField(t *testing.T) {
+	p := Pipeline{Name: "orders", Extra: map[string]json.RawMessage{
+		"name":    json.RawMessage(`"stale"`),
+		"Outputs": json.RawMessage(`["stale"]`),
+		"paused":  json.RawMessage(`true`),
+	}}
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(b), `"name"`))
+	require.NotContains(t, string(b), "stale")
+	require.Contains(t, string(b), `"paused":true`)
+}
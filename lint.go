@@ -0,0 +1,240 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/catalystsquad/swarm-client-go/stepjs"
+)
+
+// LintSeverity is the severity of a LintDiagnostic
+type LintSeverity string
+
+const (
+	// LintError marks a problem that will break the pipeline
+	LintError LintSeverity = "error"
+	// LintWarning marks a likely mistake that will not stop the pipeline
+	// from being created
+	LintWarning LintSeverity = "warning"
+)
+
+// Codes identifying the kind of problem a LintDiagnostic reports
+const (
+	LintSyntax             = "syntax"
+	LintMissingReturn      = "missing-return"
+	LintUndeclaredVariable = "undeclared-variable"
+	LintUnknownStepType    = "unknown-step-type"
+	LintUnknownOutput      = "unknown-output"
+	LintUnknownField       = "unknown-field"
+)
+
+// LintDiagnostic is a single problem found in a pipeline. Step is the index
+// into Pipeline.Steps, or -1 for problems with the pipeline itself. Line and
+// Column point into the step's Function when the problem is in its source.
+type LintDiagnostic struct {
+	Step     int          `json:"step"`
+	Field    string       `json:"field"`
+	Line     int          `json:"line,omitempty"`
+	Column   int          `json:"column,omitempty"`
+	Severity LintSeverity `json:"severity"`
+	Code     string       `json:"code"`
+	Message  string       `json:"message"`
+}
+
+func (d LintDiagnostic) String() string {
+	var b strings.Builder
+	if d.Step >= 0 {
+		fmt.Fprintf(&b, "steps[%d].%s", d.Step, d.Field)
+	} else {
+		b.WriteString(d.Field)
+	}
+	if d.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", d.Line, d.Column)
+	}
+	fmt.Fprintf(&b, ": %s: %s (%s)", d.Severity, d.Message, d.Code)
+	return b.String()
+}
+
+// LintDiagnostics is the result of linting a pipeline
+type LintDiagnostics []LintDiagnostic
+
+// HasErrors reports whether any diagnostic has error severity
+func (d LintDiagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// ByStep groups diagnostics by step index. Pipeline level diagnostics are
+// stored under -1.
+func (d LintDiagnostics) ByStep() map[int]LintDiagnostics {
+	out := map[int]LintDiagnostics{}
+	for _, diag := range d {
+		out[diag.Step] = append(out[diag.Step], diag)
+	}
+	return out
+}
+
+// LintOptions controls the checks Lint performs
+type LintOptions struct {
+	// KnownIDs are the pipeline and webhook action IDs outputs may refer
+	// to. When nil, output references are not checked.
+	KnownIDs []string
+	// SampleMessage, when set, is used to check that fields read by step
+	// functions exist. It may be any value that encodes to a JSON object.
+	SampleMessage interface{}
+}
+
+// Lint statically checks a pipeline before it is created or updated. It
+// parses each step's Function and reports syntax errors, filters without a
+// return, undeclared variables, unknown step types and outputs referring to
+// IDs that do not exist. Lint never contacts the API; see PipelinesService.Lint
+// to check outputs against the live account. A nil pipeline has no
+// diagnostics.
+func Lint(p *Pipeline, opts *LintOptions) LintDiagnostics {
+	if p == nil {
+		return nil
+	}
+	if opts == nil {
+		opts = &LintOptions{}
+	}
+	var sample interface{}
+	if opts.SampleMessage != nil {
+		b, err := json.Marshal(opts.SampleMessage)
+		if err == nil {
+			_ = json.Unmarshal(b, &sample)
+		}
+	}
+
+	var known map[string]bool
+	if opts.KnownIDs != nil {
+		known = map[string]bool{}
+		for _, id := range opts.KnownIDs {
+			known[id] = true
+		}
+	}
+
+	var diags LintDiagnostics
+	for i, id := range p.Outputs {
+		if known != nil && !known[id] {
+			diags = append(diags, LintDiagnostic{
+				Step:     -1,
+				Field:    fmt.Sprintf("outputs[%d]", i),
+				Severity: LintError,
+				Code:     LintUnknownOutput,
+				Message:  fmt.Sprintf("output %q does not exist", id),
+			})
+		}
+	}
+	for i, s := range p.Steps {
+		diags = append(diags, lintStep(i, s, known, sample)...)
+	}
+	return diags
+}
+
+func lintStep(i int, s PipelineSteps, known map[string]bool, sample interface{}) LintDiagnostics {
+	var diags LintDiagnostics
+	add := func(field string, pos stepjs.Pos, sev LintSeverity, code, msg string) {
+		diags = append(diags, LintDiagnostic{
+			Step:     i,
+			Field:    field,
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Severity: sev,
+			Code:     code,
+			Message:  msg,
+		})
+	}
+
+	if !s.Type.IsKnown() {
+		add("type", stepjs.Pos{}, LintError, LintUnknownStepType, fmt.Sprintf("unknown step type %q", s.Type))
+	}
+
+	for j, id := range s.Outputs {
+		if known != nil && !known[id] {
+			add(fmt.Sprintf("outputs[%d]", j), stepjs.Pos{}, LintError, LintUnknownOutput, fmt.Sprintf("output %q does not exist", id))
+		}
+	}
+
+	prog, err := stepjs.Parse(s.Function)
+	if err != nil {
+		var pos stepjs.Pos
+		msg := err.Error()
+		if jsErr, ok := err.(*stepjs.Error); ok {
+			pos, msg = jsErr.Pos, jsErr.Msg
+		}
+		add("function", pos, LintError, LintSyntax, msg)
+		return diags
+	}
+
+	a := stepjs.Analyze(prog)
+	if s.Type == StepTypeFilter && !a.Returns {
+		add("function", stepjs.Pos{}, LintError, LintMissingReturn, "filter function never returns a value")
+	}
+	for _, id := range a.Undeclared {
+		add("function", id.Pos, LintError, LintUndeclaredVariable, fmt.Sprintf("%s is not declared", id.Name))
+	}
+	if sample != nil {
+		for _, f := range a.Fields {
+			if missing, ok := missingField(sample, f); ok {
+				add("function", f.Pos, LintWarning, LintUnknownField, fmt.Sprintf("message.%s is not present in the sample message", missing))
+			}
+		}
+	}
+	return diags
+}
+
+// missingField walks a field path through the sample message and returns the
+// first prefix that does not exist. Walking stops at the first value that is
+// not an object, since the remaining segments are then methods or indexes.
+func missingField(sample interface{}, f stepjs.FieldRef) (string, bool) {
+	path := f.Path
+	if f.Assigned {
+		path = path[:len(path)-1]
+	}
+	cur := sample
+	for i, seg := range path {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		v, ok := obj[seg]
+		if !ok {
+			return strings.Join(path[:i+1], "."), true
+		}
+		cur = v
+	}
+	return "", false
+}
+
+// Lint checks a pipeline like the package level Lint, additionally verifying
+// that its outputs refer to webhook actions or pipelines that exist in the
+// account. Any KnownIDs in opts are replaced by the IDs found in the account.
+func (s *PipelinesService) Lint(ctx context.Context, p *Pipeline, opts *LintOptions) (LintDiagnostics, error) {
+	actions, _, err := s.client.WebhookActions.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipelines, _, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o := LintOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.KnownIDs = []string{}
+	for _, a := range actions {
+		o.KnownIDs = append(o.KnownIDs, a.ID)
+	}
+	for _, pl := range pipelines {
+		o.KnownIDs = append(o.KnownIDs, pl.ID)
+	}
+	return Lint(p, &o), nil
+}
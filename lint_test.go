@@ -0,0 +1,83 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	p := &Pipeline{
+		Name: "pipeline1",
+		Steps: []PipelineSteps{
+			{Function: "return message.hello == 'prod';", Type: "filter", Outputs: []string{"missing"}},
+			{Function: "message.hello == 'prod'", Type: "filter"},
+			{Function: "return message.hello ==", Type: "filter"},
+			{Function: "const x = 1\nreturn y > x", Type: "filter"},
+			{Function: "message.total = message.qty * message.cost\nreturn message", Type: "transform"},
+			{Function: "return true", Type: "mystery"},
+		},
+		Outputs: []string{"01G2YYM9RRT8CNRGXPSBZSA1PY", "nope"},
+	}
+
+	diags := Lint(p, &LintOptions{
+		KnownIDs:      []string{"01G2YYM9RRT8CNRGXPSBZSA1PY"},
+		SampleMessage: map[string]interface{}{"hello": "prod", "qty": 1},
+	})
+	require.True(t, diags.HasErrors())
+
+	byStep := diags.ByStep()
+	require.Equal(t, LintDiagnostics{{Step: -1, Field: "outputs[1]", Severity: LintError, Code: LintUnknownOutput, Message: `output "nope" does not exist`}}, byStep[-1])
+	require.Equal(t, LintUnknownOutput, byStep[0][0].Code)
+	require.Len(t, byStep[0], 1)
+	require.Equal(t, LintMissingReturn, byStep[1][0].Code)
+	require.Equal(t, LintSyntax, byStep[2][0].Code)
+	require.Equal(t, 1, byStep[2][0].Line)
+	require.Equal(t, LintDiagnostic{Step: 3, Field: "function", Line: 2, Column: 8, Severity: LintError, Code: LintUndeclaredVariable, Message: "y is not declared"}, byStep[3][0])
+	require.Len(t, byStep[4], 1)
+	require.Equal(t, LintUnknownField, byStep[4][0].Code)
+	require.Equal(t, LintWarning, byStep[4][0].Severity)
+	require.Contains(t, byStep[4][0].Message, "message.cost")
+	require.Equal(t, LintUnknownStepType, byStep[5][0].Code)
+}
+
+func TestLint_Clean(t *testing.T) {
+	diags := Lint(testPipelineObj, nil)
+	require.Empty(t, diags)
+	require.False(t, diags.HasErrors())
+}
+
+func TestLint_NilPipeline(t *testing.T) {
+	diags := Lint(nil, nil)
+	require.Empty(t, diags)
+	require.False(t, diags.HasErrors())
+}
+
+func TestPipelines_Lint(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/authenticated/webhookactions", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		fmt.Fprint(w, `[{"id": "01G2YYM9RRT8CNRGXPSBZSA1PY"}]`)
+	})
+	mux.HandleFunc("/authenticated/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		fmt.Fprint(w, `[]`)
+	})
+
+	ctx := context.Background()
+	diags, err := client.Pipelines.Lint(ctx, testPipelineObj, nil)
+	require.NoError(t, err)
+	require.Empty(t, diags)
+
+	p := *testPipelineObj
+	p.Outputs = []string{"gone"}
+	diags, err = client.Pipelines.Lint(ctx, &p, nil)
+	require.NoError(t, err)
+	require.Len(t, diags, 1)
+	require.Equal(t, LintUnknownOutput, diags[0].Code)
+}
@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+// Apply makes the changes in the plan, in order, stopping at the first
+// error. Each change made is marked Applied, and the ID of each created
+// resource is recorded in its Change. Updates use CompareAndUpdate, so a
+// resource modified since the plan was computed fails with swarm.ErrConflict
+// instead of being overwritten. Applying a partially applied plan again is
+// not supported; compute a new plan instead.
+func (p *Plan) Apply(ctx context.Context, client *swarm.Client) error {
+	for _, c := range p.Changes {
+		if err := p.apply(ctx, client, c); err != nil {
+			return fmt.Errorf("%s %s %q: %w", c.Action, c.Kind, c.Name, err)
+		}
+		c.Applied = true
+	}
+	return nil
+}
+
+func (p *Plan) apply(ctx context.Context, client *swarm.Client, c *Change) error {
+	switch c.Kind {
+	case KindWebhookAction:
+		switch c.Action {
+		case ActionCreate:
+			a, _, err := client.WebhookActions.Create(ctx, c.webhookAction.toSwarm(nil))
+			if err != nil {
+				return err
+			}
+			c.ID = a.ID
+			p.ids[c.Name] = a.ID
+		case ActionUpdate:
+			_, _, err := client.WebhookActions.CompareAndUpdate(ctx, c.liveAction, c.webhookAction.toSwarm(c.liveAction))
+			return err
+		case ActionDelete:
+			_, err := client.WebhookActions.Delete(ctx, c.ID)
+			return err
+		}
+
+	case KindPipeline:
+		switch c.Action {
+		case ActionCreate:
+			desired, err := c.pipeline.toSwarm(nil, p.resolve)
+			if err != nil {
+				return err
+			}
+			created, _, err := client.Pipelines.Create(ctx, desired)
+			if err != nil {
+				return err
+			}
+			c.ID = created.ID
+			p.ids[c.Name] = created.ID
+		case ActionUpdate:
+			desired, err := c.pipeline.toSwarm(c.livePipeline, p.resolve)
+			if err != nil {
+				return err
+			}
+			_, _, err = client.Pipelines.CompareAndUpdate(ctx, c.livePipeline, desired)
+			return err
+		case ActionDelete:
+			_, err := client.Pipelines.Delete(ctx, c.ID)
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the ID of the resource with the given name: a declared
+// resource once it exists, or else the only resource in the account with
+// that name
+func (p *Plan) resolve(name string) (string, error) {
+	if id, ok := p.ids[name]; ok {
+		return id, nil
+	}
+	if ids := p.liveIDs[name]; len(ids) == 1 {
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("%q does not name exactly one existing resource", name)
+}
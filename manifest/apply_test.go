@@ -0,0 +1,50 @@
+package manifest
+
+import (
+	"context"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	stale := server.AddPipeline(&swarm.Pipeline{Name: "stale", Steps: []swarm.PipelineSteps{swarm.FilterStep("return true;")}})
+
+	m := mustParse(t, testManifestYAML)
+	plan, err := NewPlan(ctx, client, m, &PlanOptions{Prune: true})
+	require.NoError(t, err)
+	creates, updates, deletes := plan.Counts()
+	require.Equal(t, []int{3, 0, 1}, []int{creates, updates, deletes})
+	require.NoError(t, plan.Apply(ctx, client))
+
+	actions := server.WebhookActions()
+	require.Len(t, actions, 1)
+	pipelines := server.Pipelines()
+	require.Len(t, pipelines, 2)
+	enrich, orders := pipelines[0], pipelines[1]
+	require.Equal(t, "enrich", enrich.Name)
+	require.Equal(t, []string{actions[0].ID}, enrich.Outputs)
+	require.Equal(t, []string{enrich.ID}, orders.Outputs)
+	require.NotEqual(t, stale.ID, enrich.ID)
+
+	// applying again changes nothing
+	plan, err = NewPlan(ctx, client, m, &PlanOptions{Prune: true})
+	require.NoError(t, err)
+	require.True(t, plan.Empty())
+
+	// updates keep the ID and fields this package does not manage
+	m.Pipelines[0].PersistOutput = true
+	plan, err = NewPlan(ctx, client, m, nil)
+	require.NoError(t, err)
+	require.Equal(t, []FieldDiff{{Path: "persistOutput", Old: false, New: true}}, plan.Changes[0].Diffs)
+	require.NoError(t, plan.Apply(ctx, client))
+	require.True(t, server.Pipelines()[1].PersistOutput)
+	require.Equal(t, orders.ID, server.Pipelines()[1].ID)
+}
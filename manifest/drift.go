@@ -0,0 +1,239 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+// DriftStatus says how a resource in the account differs from the manifest
+type DriftStatus string
+
+const (
+	// DriftNone means the resource matches its declaration
+	DriftNone DriftStatus = "in-sync"
+	// DriftMissing means a declared resource does not exist
+	DriftMissing DriftStatus = "missing"
+	// DriftExtra means a resource exists that the manifest does not declare
+	DriftExtra DriftStatus = "extra"
+	// DriftModified means a resource differs from its declaration
+	DriftModified DriftStatus = "modified"
+)
+
+// ResourceDrift is the drift status of a single resource. Diffs lists the
+// fields of a modified resource, old values being those in the account.
+type ResourceDrift struct {
+	Status DriftStatus `json:"status"`
+	Kind   Kind        `json:"kind"`
+	Name   string      `json:"name"`
+	ID     string      `json:"id,omitempty"`
+	Diffs  []FieldDiff `json:"diffs,omitempty"`
+}
+
+func (d ResourceDrift) String() string {
+	return fmt.Sprintf("%s %s %q", d.Status, d.Kind, d.Name)
+}
+
+// DriftReport compares every declared resource, and every resource in the
+// account, with the manifest
+type DriftReport struct {
+	Resources []ResourceDrift `json:"resources"`
+}
+
+// CheckDrift lists the webhook actions and pipelines in the account and
+// reports how they differ from m. It makes no changes.
+func CheckDrift(ctx context.Context, client *swarm.Client, m *Manifest) (*DriftReport, error) {
+	actions, _, err := client.WebhookActions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook actions: %w", err)
+	}
+	pipelines, _, err := client.Pipelines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing pipelines: %w", err)
+	}
+	return DetectDrift(m, actions, pipelines)
+}
+
+// DetectDrift reports how the given live resources, as returned by the List
+// methods, differ from m. Declared resources come first in manifest order,
+// followed by extra resources in list order. When several resources in the
+// account share a declared name, the first is compared and the others are
+// reported as extra.
+func DetectDrift(m *Manifest, actions []*swarm.WebhookAction, pipelines []*swarm.Pipeline) (*DriftReport, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for _, a := range actions {
+		names[a.ID] = a.Name
+	}
+	for _, p := range pipelines {
+		names[p.ID] = p.Name
+	}
+	liveName := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		return id
+	}
+
+	report := &DriftReport{Resources: []ResourceDrift{}}
+	matched := map[string]bool{}
+	for _, a := range m.WebhookActions {
+		d := ResourceDrift{Status: DriftMissing, Kind: KindWebhookAction, Name: a.Name}
+		for _, live := range actions {
+			if live.Name == a.Name {
+				matched[live.ID] = true
+				d.ID = live.ID
+				diffs, err := diff(webhookActionFromSwarm(live), a)
+				if err != nil {
+					return nil, fmt.Errorf("webhook action %q: %w", a.Name, err)
+				}
+				d.Diffs = diffs
+				d.Status = statusOf(d.Diffs)
+				break
+			}
+		}
+		report.Resources = append(report.Resources, d)
+	}
+	for _, p := range m.Pipelines {
+		d := ResourceDrift{Status: DriftMissing, Kind: KindPipeline, Name: p.Name}
+		for _, live := range pipelines {
+			if live.Name == p.Name {
+				matched[live.ID] = true
+				d.ID = live.ID
+				diffs, err := diff(pipelineFromSwarm(live, liveName), p)
+				if err != nil {
+					return nil, fmt.Errorf("pipeline %q: %w", p.Name, err)
+				}
+				d.Diffs = diffs
+				d.Status = statusOf(d.Diffs)
+				break
+			}
+		}
+		report.Resources = append(report.Resources, d)
+	}
+
+	for _, a := range actions {
+		if !matched[a.ID] {
+			report.Resources = append(report.Resources, ResourceDrift{Status: DriftExtra, Kind: KindWebhookAction, Name: a.Name, ID: a.ID})
+		}
+	}
+	for _, p := range pipelines {
+		if !matched[p.ID] {
+			report.Resources = append(report.Resources, ResourceDrift{Status: DriftExtra, Kind: KindPipeline, Name: p.Name, ID: p.ID})
+		}
+	}
+	return report, nil
+}
+
+func statusOf(diffs []FieldDiff) DriftStatus {
+	if len(diffs) > 0 {
+		return DriftModified
+	}
+	return DriftNone
+}
+
+// HasDrift reports whether any resource is missing, extra or modified
+func (r *DriftReport) HasDrift() bool {
+	for _, d := range r.Resources {
+		if d.Status != DriftNone {
+			return true
+		}
+	}
+	return false
+}
+
+// Counts returns the number of resources with each status
+func (r *DriftReport) Counts() map[DriftStatus]int {
+	counts := map[DriftStatus]int{}
+	for _, d := range r.Resources {
+		counts[d.Status]++
+	}
+	return counts
+}
+
+// WriteText writes the drifted resources, one per line followed by the
+// fields that differ, and a summary
+func (r *DriftReport) WriteText(w io.Writer) error {
+	for _, d := range r.Resources {
+		if d.Status == DriftNone {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+		for _, fd := range d.Diffs {
+			if _, err := fmt.Fprintf(w, "    %s\n", fd); err != nil {
+				return err
+			}
+		}
+	}
+	c := r.Counts()
+	_, err := fmt.Fprintf(w, "Drift: %d missing, %d extra, %d modified, %d in sync.\n",
+		c[DriftMissing], c[DriftExtra], c[DriftModified], c[DriftNone])
+	return err
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *DriftReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as a JUnit XML test suite with a test case
+// per resource, failing for drifted resources, so CI systems can display it
+func (r *DriftReport) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{Name: "swarm drift", Tests: len(r.Resources)}
+	for _, d := range r.Resources {
+		tc := junitTestCase{Name: d.Name, ClassName: string(d.Kind)}
+		if d.Status != DriftNone {
+			suite.Failures++
+			var lines []string
+			for _, fd := range d.Diffs {
+				lines = append(lines, fd.String())
+			}
+			tc.Failure = &junitFailure{Message: d.String(), Type: string(d.Status), Text: strings.Join(lines, "\n")}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,75 @@
+package manifest
+
+import (
+	"bytes"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/stretchr/testify/require"
+)
+
+func testDriftReport(t *testing.T) *DriftReport {
+	live := []*swarm.WebhookAction{{
+		ID: "A1", Name: "orders-hook", URL: "https://example.com/orders", Method: "POST",
+		Headers:      []swarm.WebhookActionsHeader{{Name: "X-Key", Value: "secret"}},
+		SuccessCodes: []int{200}, RetryIntervalSeconds: 60, MaxRetries: -1,
+	}}
+	pipelines := []*swarm.Pipeline{
+		{ID: "P2", Name: "enrich", Steps: []swarm.PipelineSteps{swarm.TransformStep("return message;")}, Outputs: []string{"A1"}},
+		{ID: "P3", Name: "unmanaged", Steps: []swarm.PipelineSteps{swarm.FilterStep("return true;")}, Outputs: []string{}},
+	}
+	report, err := DetectDrift(mustParse(t, testManifestYAML), live, pipelines)
+	require.NoError(t, err)
+	return report
+}
+
+func TestDetectDrift(t *testing.T) {
+	report := testDriftReport(t)
+	require.True(t, report.HasDrift())
+	require.Equal(t, []ResourceDrift{
+		{Status: DriftNone, Kind: KindWebhookAction, Name: "orders-hook", ID: "A1"},
+		{Status: DriftMissing, Kind: KindPipeline, Name: "orders"},
+		{Status: DriftModified, Kind: KindPipeline, Name: "enrich", ID: "P2", Diffs: []FieldDiff{
+			{Path: "steps[0].function", Old: "return message;", New: "return { id: message.id };"},
+		}},
+		{Status: DriftExtra, Kind: KindPipeline, Name: "unmanaged", ID: "P3"},
+	}, report.Resources)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.WriteText(buf))
+	require.Equal(t, `missing pipeline "orders"
+modified pipeline "enrich"
+    steps[0].function: "return message;" => "return { id: message.id };"
+extra pipeline "unmanaged"
+Drift: 1 missing, 1 extra, 1 modified, 1 in sync.
+`, buf.String())
+}
+
+func TestDriftReport_JUnit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, testDriftReport(t).WriteJUnit(buf))
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="swarm drift" tests="4" failures="3">
+  <testcase name="orders-hook" classname="webhookAction"></testcase>
+  <testcase name="orders" classname="pipeline">
+    <failure message="missing pipeline &#34;orders&#34;" type="missing"></failure>
+  </testcase>
+  <testcase name="enrich" classname="pipeline">
+    <failure message="modified pipeline &#34;enrich&#34;" type="modified">steps[0].function: &#34;return message;&#34; =&gt; &#34;return { id: message.id };&#34;</failure>
+  </testcase>
+  <testcase name="unmanaged" classname="pipeline">
+    <failure message="extra pipeline &#34;unmanaged&#34;" type="extra"></failure>
+  </testcase>
+</testsuite>
+`, buf.String())
+}
+
+func TestDriftReport_NoDrift(t *testing.T) {
+	report, err := DetectDrift(&Manifest{}, nil, nil)
+	require.NoError(t, err)
+	require.False(t, report.HasDrift())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.WriteJSON(buf))
+	require.JSONEq(t, `{"resources": []}`, buf.String())
+}
@@ -0,0 +1,341 @@
+// Package manifest manages webhook actions and pipelines declaratively. A
+// Manifest describes the desired resources, referring to each other by name
+// rather than ID, and a Plan computes and applies the changes that make an
+// account match it.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares webhook actions and pipelines. Names must be unique
+// across both kinds so that outputs can refer to either by name.
+type Manifest struct {
+	WebhookActions []*WebhookAction `json:"webhookActions,omitempty"`
+	Pipelines      []*Pipeline      `json:"pipelines,omitempty"`
+}
+
+// WebhookAction declares a webhook action, see swarm.WebhookAction
+type WebhookAction struct {
+	Name                  string   `json:"name"`
+	URL                   string   `json:"url"`
+	Method                string   `json:"method"`
+	Headers               []Header `json:"headers,omitempty"`
+	MaxConcurrentRequests int      `json:"maxConcurrentRequests"`
+	VerifyTLSCertificate  bool     `json:"verifyTlsCertificate"`
+	SuccessCodes          []int    `json:"successCodes,omitempty"`
+	RetryIntervalSeconds  int      `json:"retryIntervalSeconds"`
+	MaxRetries            int      `json:"maxRetries"`
+}
+
+// Header is a header sent by a webhook action
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Pipeline declares a pipeline, see swarm.Pipeline. Outputs name webhook
+// actions or pipelines.
+type Pipeline struct {
+	Name                 string         `json:"name"`
+	Steps                []Step         `json:"steps,omitempty"`
+	Outputs              []string       `json:"outputs,omitempty"`
+	PersistOutput        bool           `json:"persistOutput"`
+	StitchConfigs        []StitchConfig `json:"stitchConfigs,omitempty"`
+	RetryIntervalSeconds int            `json:"retryIntervalSeconds"`
+	MaxRetries           int            `json:"maxRetries"`
+}
+
+// Step declares a pipeline step. Outputs name webhook actions or pipelines.
+type Step struct {
+	Function string         `json:"function"`
+	Outputs  []string       `json:"outputs,omitempty"`
+	Required bool           `json:"required"`
+	Type     swarm.StepType `json:"type"`
+}
+
+// StitchConfig declares a stitch with the pipeline of the given name
+type StitchConfig struct {
+	Pipeline string `json:"pipeline"`
+	Key      string `json:"key"`
+	TTL      int    `json:"ttl"`
+}
+
+// Parse reads a manifest in YAML or JSON. YAML input may hold several
+// documents, which are merged. Unknown fields are rejected so typos do not
+// go unnoticed.
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+
+		// go through JSON so the json tags are the single source of field
+		// names for both formats
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		part := &Manifest{}
+		jdec := json.NewDecoder(bytes.NewReader(b))
+		jdec.DisallowUnknownFields()
+		if err := jdec.Decode(part); err != nil {
+			return nil, err
+		}
+		m.Merge(part)
+	}
+	return m, nil
+}
+
+// Load reads and merges the manifests in the given files
+func Load(paths ...string) (*Manifest, error) {
+	m := &Manifest{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		part, err := Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		m.Merge(part)
+	}
+	return m, nil
+}
+
+// Merge appends the resources of other to m
+func (m *Manifest) Merge(other *Manifest) {
+	m.WebhookActions = append(m.WebhookActions, other.WebhookActions...)
+	m.Pipelines = append(m.Pipelines, other.Pipelines...)
+}
+
+// Validate checks that names are present and unique and that every resource
+// would pass the swarm package's validation. References to names that are
+// not declared are allowed, since they may exist in the account; a Plan
+// checks them. Errors are returned as a *swarm.ValidationError.
+func (m *Manifest) Validate() error {
+	var errs []*swarm.FieldError
+	add := func(field string, format string, args ...interface{}) {
+		errs = append(errs, &swarm.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	nest := func(field string, err error) {
+		var verr *swarm.ValidationError
+		if errors.As(err, &verr) {
+			for _, fe := range verr.Errors {
+				add(field+"."+fe.Field, "%s", fe.Message)
+			}
+		} else if err != nil {
+			add(field, "%s", err)
+		}
+	}
+
+	seen := map[string]string{}
+	checkName := func(field string, name string) {
+		if name == "" {
+			return // reported by the resource's own validation
+		}
+		if other, ok := seen[name]; ok {
+			add(field+".name", "%q is already used by %s", name, other)
+			return
+		}
+		seen[name] = field
+	}
+
+	// references are validated as placeholder IDs, since only their
+	// presence matters here
+	for i, a := range m.WebhookActions {
+		field := fmt.Sprintf("webhookActions[%d]", i)
+		checkName(field, a.Name)
+		nest(field, a.toSwarm(nil).Validate())
+	}
+	for i, p := range m.Pipelines {
+		field := fmt.Sprintf("pipelines[%d]", i)
+		checkName(field, p.Name)
+		placeholder := func(name string) (string, error) { return name, nil }
+		sp, _ := p.toSwarm(nil, placeholder)
+		nest(field, sp.Validate())
+	}
+
+	if len(errs) > 0 {
+		return &swarm.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// FromResources builds a manifest declaring the given resources, as returned
+// by the List methods, with references turned into names. References to IDs
+// that are not among the resources are kept as IDs.
+func FromResources(actions []*swarm.WebhookAction, pipelines []*swarm.Pipeline) *Manifest {
+	names := map[string]string{}
+	for _, a := range actions {
+		names[a.ID] = a.Name
+	}
+	for _, p := range pipelines {
+		names[p.ID] = p.Name
+	}
+	name := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		return id
+	}
+
+	m := &Manifest{}
+	for _, a := range actions {
+		m.WebhookActions = append(m.WebhookActions, webhookActionFromSwarm(a))
+	}
+	for _, p := range pipelines {
+		m.Pipelines = append(m.Pipelines, pipelineFromSwarm(p, name))
+	}
+	return m
+}
+
+// toSwarm converts the declaration into an API resource. Fields of base this
+// package does not manage, such as the ID and unknown fields of the action
+// and its headers, are kept. Headers are matched by position.
+func (a *WebhookAction) toSwarm(base *swarm.WebhookAction) *swarm.WebhookAction {
+	out := &swarm.WebhookAction{}
+	if base != nil {
+		out.ID = base.ID
+		out.Extra = base.Extra
+	}
+	out.Name = a.Name
+	out.URL = a.URL
+	out.Method = a.Method
+	out.Headers = []swarm.WebhookActionsHeader{}
+	for i, h := range a.Headers {
+		header := swarm.WebhookActionsHeader{Name: h.Name, Value: h.Value}
+		if base != nil && i < len(base.Headers) {
+			header.Extra = base.Headers[i].Extra
+		}
+		out.Headers = append(out.Headers, header)
+	}
+	out.MaxConcurrentRequests = a.MaxConcurrentRequests
+	out.VerifyTLSCertificate = a.VerifyTLSCertificate
+	out.SuccessCodes = append([]int{}, a.SuccessCodes...)
+	out.RetryIntervalSeconds = a.RetryIntervalSeconds
+	out.MaxRetries = a.MaxRetries
+	return out
+}
+
+// toSwarm converts the declaration into an API resource, using resolve to
+// turn referenced names into IDs. Fields of base this package does not
+// manage, such as the ID and unknown fields of the pipeline, its steps and its
+// stitch configs, are kept. Steps and stitch configs are matched by position.
+func (p *Pipeline) toSwarm(base *swarm.Pipeline, resolve func(name string) (string, error)) (*swarm.Pipeline, error) {
+	out := &swarm.Pipeline{}
+	if base != nil {
+		out.ID = base.ID
+		out.Extra = base.Extra
+	}
+	out.Name = p.Name
+	out.PersistOutput = p.PersistOutput
+	out.RetryIntervalSeconds = p.RetryIntervalSeconds
+	out.MaxRetries = p.MaxRetries
+
+	resolveAll := func(names []string) ([]string, error) {
+		ids := []string{}
+		for _, name := range names {
+			id, err := resolve(name)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	var err error
+	out.Steps = []swarm.PipelineSteps{}
+	for i, s := range p.Steps {
+		step := swarm.PipelineSteps{Function: s.Function, Required: s.Required, Type: s.Type}
+		if base != nil && i < len(base.Steps) {
+			step.Extra = base.Steps[i].Extra
+		}
+		if step.Outputs, err = resolveAll(s.Outputs); err != nil {
+			return nil, err
+		}
+		out.Steps = append(out.Steps, step)
+	}
+	if out.Outputs, err = resolveAll(p.Outputs); err != nil {
+		return nil, err
+	}
+	for i, c := range p.StitchConfigs {
+		id, err := resolve(c.Pipeline)
+		if err != nil {
+			return nil, err
+		}
+		stitch := swarm.PipelineStitchConfig{StitchPipelineID: id, Key: c.Key, TTL: c.TTL}
+		if base != nil && i < len(base.StitchConfigs) {
+			stitch.Extra = base.StitchConfigs[i].Extra
+		}
+		out.StitchConfigs = append(out.StitchConfigs, stitch)
+	}
+	return out, nil
+}
+
+// webhookActionFromSwarm converts an API resource into a declaration
+func webhookActionFromSwarm(a *swarm.WebhookAction) *WebhookAction {
+	out := &WebhookAction{
+		Name:                  a.Name,
+		URL:                   a.URL,
+		Method:                a.Method,
+		MaxConcurrentRequests: a.MaxConcurrentRequests,
+		VerifyTLSCertificate:  a.VerifyTLSCertificate,
+		RetryIntervalSeconds:  a.RetryIntervalSeconds,
+		MaxRetries:            a.MaxRetries,
+	}
+	for _, h := range a.Headers {
+		out.Headers = append(out.Headers, Header{Name: h.Name, Value: h.Value})
+	}
+	if len(a.SuccessCodes) > 0 {
+		out.SuccessCodes = append([]int{}, a.SuccessCodes...)
+	}
+	return out
+}
+
+// pipelineFromSwarm converts an API resource into a declaration, using name
+// to turn referenced IDs into names
+func pipelineFromSwarm(p *swarm.Pipeline, name func(id string) string) *Pipeline {
+	names := func(ids []string) []string {
+		var out []string
+		for _, id := range ids {
+			out = append(out, name(id))
+		}
+		return out
+	}
+
+	out := &Pipeline{
+		Name:                 p.Name,
+		Outputs:              names(p.Outputs),
+		PersistOutput:        p.PersistOutput,
+		RetryIntervalSeconds: p.RetryIntervalSeconds,
+		MaxRetries:           p.MaxRetries,
+	}
+	for _, s := range p.Steps {
+		out.Steps = append(out.Steps, Step{Function: s.Function, Outputs: names(s.Outputs), Required: s.Required, Type: s.Type})
+	}
+	for _, c := range p.StitchConfigs {
+		out.StitchConfigs = append(out.StitchConfigs, StitchConfig{Pipeline: name(c.StitchPipelineID), Key: c.Key, TTL: c.TTL})
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package manifest
+
+import (
+	"encoding/json"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifestYAML = `
+webhookActions:
+  - name: orders-hook
+    url: https://example.com/orders
+    method: POST
+    headers:
+      - name: X-Key
+        value: secret
+    successCodes: [200]
+    retryIntervalSeconds: 60
+    maxRetries: -1
+pipelines:
+  - name: orders
+    steps:
+      - type: filter
+        required: true
+        function: return message.type == 'order';
+    outputs: [enrich]
+  - name: enrich
+    steps:
+      - type: transform
+        required: true
+        function: "return { id: message.id };"
+    outputs: [orders-hook]
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(testManifestYAML))
+	require.NoError(t, err)
+	require.Len(t, m.WebhookActions, 1)
+	require.Equal(t, []Header{{Name: "X-Key", Value: "secret"}}, m.WebhookActions[0].Headers)
+	require.Equal(t, -1, m.WebhookActions[0].MaxRetries)
+	require.Len(t, m.Pipelines, 2)
+	require.Equal(t, swarm.StepTypeFilter, m.Pipelines[0].Steps[0].Type)
+	require.Equal(t, []string{"orders-hook"}, m.Pipelines[1].Outputs)
+	require.NoError(t, m.Validate())
+
+	// JSON and multiple YAML documents are accepted too
+	m, err = Parse([]byte(`{"pipelines": [{"name": "a", "steps": [{"type": "filter", "function": "return true;"}]}]}
+---
+pipelines:
+  - name: b
+    steps: [{type: filter, function: return true;}]
+`))
+	require.NoError(t, err)
+	require.Len(t, m.Pipelines, 2)
+	require.Equal(t, "b", m.Pipelines[1].Name)
+}
+
+func TestParse_UnknownField(t *testing.T) {
+	_, err := Parse([]byte("pipelines:\n  - name: a\n    output: [b]\n"))
+	require.EqualError(t, err, `json: unknown field "output"`)
+}
+
+func TestValidate(t *testing.T) {
+	m, err := Parse([]byte(`
+webhookActions:
+  - name: dup
+    url: example.com
+    method: POST
+pipelines:
+  - name: dup
+    steps: [{type: filter, function: return true;}]
+  - name: ""
+`))
+	require.NoError(t, err)
+
+	var verr *swarm.ValidationError
+	require.ErrorAs(t, m.Validate(), &verr)
+	var got []string
+	for _, fe := range verr.Errors {
+		got = append(got, fe.Error())
+	}
+	require.Equal(t, []string{
+		"webhookActions[0].url: must be an absolute http or https URL",
+		`pipelines[0].name: "dup" is already used by webhookActions[0]`,
+		"pipelines[1].name: is required",
+	}, got)
+}
+
+func TestFromResources(t *testing.T) {
+	m := FromResources(
+		[]*swarm.WebhookAction{{ID: "A1", Name: "hook", URL: "https://example.com", Method: "POST", Headers: []swarm.WebhookActionsHeader{}}},
+		[]*swarm.Pipeline{{
+			ID:            "P1",
+			Name:          "orders",
+			Steps:         []swarm.PipelineSteps{swarm.FilterStep("return true;", "A1")},
+			Outputs:       []string{"A1", "GONE"},
+			StitchConfigs: []swarm.PipelineStitchConfig{{StitchPipelineID: "P1", Key: "id", TTL: 60}},
+		}},
+	)
+	require.Equal(t, &Manifest{
+		WebhookActions: []*WebhookAction{{Name: "hook", URL: "https://example.com", Method: "POST"}},
+		Pipelines: []*Pipeline{{
+			Name:          "orders",
+			Steps:         []Step{{Function: "return true;", Outputs: []string{"hook"}, Required: true, Type: swarm.StepTypeFilter}},
+			Outputs:       []string{"hook", "GONE"},
+			StitchConfigs: []StitchConfig{{Pipeline: "orders", Key: "id", TTL: 60}},
+		}},
+	}, m)
+}
+
+func TestToSwarm_KeepsUnknownFields(t *testing.T) {
+	m := mustParse(t, testManifestYAML)
+	extra := map[string]json.RawMessage{"new": json.RawMessage(`1`)}
+
+	action := m.WebhookActions[0].toSwarm(&swarm.WebhookAction{
+		ID:      "A1",
+		Headers: []swarm.WebhookActionsHeader{{Name: "X-Key", Extra: extra}},
+		Extra:   extra,
+	})
+	require.Equal(t, []string{"headers[0].new", "new"}, swarm.UnknownFields(action))
+
+	m.Pipelines[0].StitchConfigs = []StitchConfig{{Pipeline: "enrich", Key: "id"}}
+	p, err := m.Pipelines[0].toSwarm(&swarm.Pipeline{
+		ID:            "P1",
+		Steps:         []swarm.PipelineSteps{{Extra: extra}},
+		StitchConfigs: []swarm.PipelineStitchConfig{{Extra: extra}},
+		Extra:         extra,
+	}, func(name string) (string, error) { return name, nil })
+	require.NoError(t, err)
+	require.Equal(t, []string{"steps[0].new", "stitchConfigs[0].new", "new"}, swarm.UnknownFields(p))
+	require.Equal(t, "P1", p.ID)
+}
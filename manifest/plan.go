@@ -0,0 +1,464 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+// Kind is the kind of resource a Change applies to
+type Kind string
+
+const (
+	KindWebhookAction Kind = "webhookAction"
+	KindPipeline      Kind = "pipeline"
+)
+
+// Action is what a Change does
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// FieldDiff is a single field that differs between the account and the
+// manifest. Path is a JSON path such as "steps[0].function". Old or New is
+// nil when the field is only present on one side.
+type FieldDiff struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: %s => %s", d.Path, formatValue(d.Old), formatValue(d.New))
+}
+
+// Change is a single create, update or delete in a Plan
+type Change struct {
+	Action Action `json:"action"`
+	Kind   Kind   `json:"kind"`
+	Name   string `json:"name"`
+	// ID is the ID of the resource in the account. It is empty for creates
+	// until the plan is applied.
+	ID string `json:"id,omitempty"`
+	// Diffs lists the fields an update changes
+	Diffs []FieldDiff `json:"diffs,omitempty"`
+	// Applied is set once Apply has made the change
+	Applied bool `json:"applied,omitempty"`
+
+	webhookAction *WebhookAction
+	pipeline      *Pipeline
+	liveAction    *swarm.WebhookAction
+	livePipeline  *swarm.Pipeline
+}
+
+func (c *Change) String() string {
+	symbol := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[c.Action]
+	return fmt.Sprintf("%s %s %s", symbol, c.Kind, c.Name)
+}
+
+// PlanOptions configures NewPlan
+type PlanOptions struct {
+	// Prune deletes webhook actions and pipelines in the account that the
+	// manifest does not declare
+	Prune bool
+}
+
+// Plan is the list of changes that make an account match a manifest, in the
+// order they are applied: webhook actions first, then pipelines ordered so
+// the pipelines they output to or stitch with exist before them, then
+// deletes.
+type Plan struct {
+	Changes []*Change `json:"changes"`
+
+	// names of resources in the account by ID, and IDs of resources in the
+	// account by name, for resolving references
+	liveNames map[string]string
+	liveIDs   map[string][]string
+	// IDs of declared resources that exist, by name
+	ids map[string]string
+}
+
+// NewPlan lists the webhook actions and pipelines in the account and
+// computes the changes that make it match m. A nil opts uses the defaults.
+func NewPlan(ctx context.Context, client *swarm.Client, m *Manifest, opts *PlanOptions) (*Plan, error) {
+	actions, _, err := client.WebhookActions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook actions: %w", err)
+	}
+	pipelines, _, err := client.Pipelines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing pipelines: %w", err)
+	}
+	return Compare(m, actions, pipelines, opts)
+}
+
+// Compare computes the plan for m against the given live resources, as
+// returned by the List methods. It makes no requests.
+func Compare(m *Manifest, actions []*swarm.WebhookAction, pipelines []*swarm.Pipeline, opts *PlanOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &PlanOptions{}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{liveNames: map[string]string{}, liveIDs: map[string][]string{}, ids: map[string]string{}}
+	liveActions := map[string][]*swarm.WebhookAction{}
+	for _, a := range actions {
+		liveActions[a.Name] = append(liveActions[a.Name], a)
+		plan.liveNames[a.ID] = a.Name
+		plan.liveIDs[a.Name] = append(plan.liveIDs[a.Name], a.ID)
+	}
+	livePipelines := map[string][]*swarm.Pipeline{}
+	for _, p := range pipelines {
+		livePipelines[p.Name] = append(livePipelines[p.Name], p)
+		plan.liveNames[p.ID] = p.Name
+		plan.liveIDs[p.Name] = append(plan.liveIDs[p.Name], p.ID)
+	}
+	declared := map[string]Kind{}
+	for _, a := range m.WebhookActions {
+		declared[a.Name] = KindWebhookAction
+	}
+	for _, p := range m.Pipelines {
+		declared[p.Name] = KindPipeline
+	}
+
+	if err := plan.checkReferences(m, declared, livePipelines, opts.Prune); err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.WebhookActions {
+		live := liveActions[a.Name]
+		if len(live) > 1 {
+			return nil, fmt.Errorf("webhook action %q: %d webhook actions in the account have this name", a.Name, len(live))
+		}
+		c := &Change{Kind: KindWebhookAction, Name: a.Name, webhookAction: a}
+		if len(live) == 0 {
+			c.Action = ActionCreate
+		} else {
+			c.ID = live[0].ID
+			plan.ids[a.Name] = c.ID
+			c.liveAction = live[0]
+			diffs, err := diff(webhookActionFromSwarm(live[0]), a)
+			if err != nil {
+				return nil, fmt.Errorf("webhook action %q: %w", a.Name, err)
+			}
+			c.Diffs = diffs
+			if len(c.Diffs) == 0 {
+				continue
+			}
+			c.Action = ActionUpdate
+		}
+		plan.Changes = append(plan.Changes, c)
+	}
+
+	ordered, err := orderPipelines(m.Pipelines, livePipelines)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ordered {
+		live := livePipelines[p.Name]
+		if len(live) > 1 {
+			return nil, fmt.Errorf("pipeline %q: %d pipelines in the account have this name", p.Name, len(live))
+		}
+		c := &Change{Kind: KindPipeline, Name: p.Name, pipeline: p}
+		if len(live) == 0 {
+			c.Action = ActionCreate
+		} else {
+			c.ID = live[0].ID
+			plan.ids[p.Name] = c.ID
+			c.livePipeline = live[0]
+			diffs, err := diff(pipelineFromSwarm(live[0], plan.liveName), p)
+			if err != nil {
+				return nil, fmt.Errorf("pipeline %q: %w", p.Name, err)
+			}
+			c.Diffs = diffs
+			if len(c.Diffs) == 0 {
+				continue
+			}
+			c.Action = ActionUpdate
+		}
+		plan.Changes = append(plan.Changes, c)
+	}
+
+	if opts.Prune {
+		for _, p := range pipelines {
+			if declared[p.Name] != KindPipeline {
+				plan.Changes = append(plan.Changes, &Change{Action: ActionDelete, Kind: KindPipeline, Name: p.Name, ID: p.ID})
+			}
+		}
+		for _, a := range actions {
+			if declared[a.Name] != KindWebhookAction {
+				plan.Changes = append(plan.Changes, &Change{Action: ActionDelete, Kind: KindWebhookAction, Name: a.Name, ID: a.ID})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// liveName returns the name of the resource in the account with the given
+// ID, or the ID itself if there is none
+func (p *Plan) liveName(id string) string {
+	if name, ok := p.liveNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+// checkReferences makes sure every output and stitch config names exactly
+// one resource, declared or in the account, and that stitches name
+// pipelines. When pruning, undeclared resources would be deleted, so
+// references to them are errors too.
+func (p *Plan) checkReferences(m *Manifest, declared map[string]Kind, livePipelines map[string][]*swarm.Pipeline, prune bool) error {
+	var errs []*swarm.FieldError
+	check := func(field string, name string, pipelineOnly bool) {
+		if kind, ok := declared[name]; ok {
+			if pipelineOnly && kind != KindPipeline {
+				errs = append(errs, &swarm.FieldError{Field: field, Message: fmt.Sprintf("%q is not a pipeline", name)})
+			}
+			return
+		}
+		ids := p.liveIDs[name]
+		if pipelineOnly {
+			ids = nil
+			for _, live := range livePipelines[name] {
+				ids = append(ids, live.ID)
+			}
+		}
+		switch len(ids) {
+		case 0:
+			errs = append(errs, &swarm.FieldError{Field: field, Message: fmt.Sprintf("%q is not declared and does not exist", name)})
+		case 1:
+			if prune {
+				errs = append(errs, &swarm.FieldError{Field: field, Message: fmt.Sprintf("%q is not declared and would be pruned", name)})
+			}
+		default:
+			errs = append(errs, &swarm.FieldError{Field: field, Message: fmt.Sprintf("%q is ambiguous, it is used by %s", name, strings.Join(ids, ", "))})
+		}
+	}
+
+	for i, pl := range m.Pipelines {
+		for j, s := range pl.Steps {
+			for k, name := range s.Outputs {
+				check(fmt.Sprintf("pipelines[%d].steps[%d].outputs[%d]", i, j, k), name, false)
+			}
+		}
+		for j, name := range pl.Outputs {
+			check(fmt.Sprintf("pipelines[%d].outputs[%d]", i, j), name, false)
+		}
+		for j, c := range pl.StitchConfigs {
+			check(fmt.Sprintf("pipelines[%d].stitchConfigs[%d].pipeline", i, j), c.Pipeline, true)
+		}
+	}
+	if len(errs) > 0 {
+		return &swarm.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// references returns the names a pipeline outputs to or stitches with
+func (p *Pipeline) references() []string {
+	refs := append([]string{}, p.Outputs...)
+	for _, s := range p.Steps {
+		refs = append(refs, s.Outputs...)
+	}
+	for _, c := range p.StitchConfigs {
+		refs = append(refs, c.Pipeline)
+	}
+	return refs
+}
+
+// orderPipelines sorts pipelines so that every pipeline that has to be
+// created comes before the pipelines referring to it. Otherwise the manifest
+// order is kept. A cycle of pipelines that all have to be created cannot be
+// applied and is an error.
+func orderPipelines(pipelines []*Pipeline, live map[string][]*swarm.Pipeline) ([]*Pipeline, error) {
+	byName := map[string]*Pipeline{}
+	for _, p := range pipelines {
+		byName[p.Name] = p
+	}
+	deps := func(p *Pipeline) []*Pipeline {
+		var out []*Pipeline
+		for _, name := range p.references() {
+			if dep, ok := byName[name]; ok && len(live[name]) == 0 && dep != p {
+				out = append(out, dep)
+			}
+		}
+		return out
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[*Pipeline]int{}
+	var ordered []*Pipeline
+	var visit func(p *Pipeline, path []string) error
+	visit = func(p *Pipeline, path []string) error {
+		switch state[p] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("pipelines that do not exist yet refer to each other: %s", strings.Join(append(path, p.Name), " -> "))
+		}
+		state[p] = visiting
+		for _, dep := range deps(p) {
+			if err := visit(dep, append(path, p.Name)); err != nil {
+				return err
+			}
+		}
+		state[p] = done
+		ordered = append(ordered, p)
+		return nil
+	}
+	for _, p := range pipelines {
+		if err := visit(p, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Empty reports whether the plan has no changes
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Counts returns the number of creates, updates and deletes in the plan
+func (p *Plan) Counts() (creates, updates, deletes int) {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			creates++
+		case ActionUpdate:
+			updates++
+		case ActionDelete:
+			deletes++
+		}
+	}
+	return creates, updates, deletes
+}
+
+// WriteText writes a human readable description of the plan, one change per
+// line followed by the fields an update changes, and a summary
+func (p *Plan) WriteText(w io.Writer) error {
+	if p.Empty() {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+	for _, c := range p.Changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+		for _, d := range c.Diffs {
+			if _, err := fmt.Fprintf(w, "    %s\n", d); err != nil {
+				return err
+			}
+		}
+	}
+	creates, updates, deletes := p.Counts()
+	_, err := fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete.\n", creates, updates, deletes)
+	return err
+}
+
+// diff compares the JSON encodings of two values field by field
+func diff(old, new interface{}) ([]FieldDiff, error) {
+	o, err := generic(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := generic(new)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []FieldDiff
+	diffValues("", o, n, &diffs)
+	return diffs, nil
+}
+
+// generic converts v into the maps, slices and scalars it encodes to
+func generic(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func diffValues(path string, old, new interface{}, diffs *[]FieldDiff) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			keys := map[string]bool{}
+			for k := range o {
+				keys[k] = true
+			}
+			for k := range n {
+				keys[k] = true
+			}
+			sorted := make([]string, 0, len(keys))
+			for k := range keys {
+				sorted = append(sorted, k)
+			}
+			sort.Strings(sorted)
+			for _, k := range sorted {
+				p := k
+				if path != "" {
+					p = path + "." + k
+				}
+				diffValues(p, o[k], n[k], diffs)
+			}
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			for i := 0; i < len(o) || i < len(n); i++ {
+				var ov, nv interface{}
+				if i < len(o) {
+					ov = o[i]
+				}
+				if i < len(n) {
+					nv = n[i]
+				}
+				diffValues(fmt.Sprintf("%s[%d]", path, i), ov, nv, diffs)
+			}
+			return
+		}
+	}
+	if !equalGeneric(old, new) {
+		*diffs = append(*diffs, FieldDiff{Path: path, Old: old, New: new})
+	}
+}
+
+func equalGeneric(a, b interface{}) bool {
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	return string(ab) == string(bb)
+}
+
+// formatValue formats a diffed value as JSON, or "(none)" when absent
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "(none)"
+	}
+	buf := &strings.Builder{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"bytes"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, s string) *Manifest {
+	t.Helper()
+	m, err := Parse([]byte(s))
+	require.NoError(t, err)
+	return m
+}
+
+func TestCompare_CreateAll(t *testing.T) {
+	plan, err := Compare(mustParse(t, testManifestYAML), nil, nil, nil)
+	require.NoError(t, err)
+
+	// enrich is created before orders, which outputs to it
+	buf := &bytes.Buffer{}
+	require.NoError(t, plan.WriteText(buf))
+	require.Equal(t, `+ webhookAction orders-hook
++ pipeline enrich
++ pipeline orders
+Plan: 3 to create, 0 to update, 0 to delete.
+`, buf.String())
+}
+
+func TestCompare_Update(t *testing.T) {
+	live := []*swarm.WebhookAction{{
+		ID: "A1", Name: "orders-hook", URL: "https://example.com/orders", Method: "POST",
+		Headers:      []swarm.WebhookActionsHeader{{Name: "X-Key", Value: "secret"}},
+		SuccessCodes: []int{200}, RetryIntervalSeconds: 60, MaxRetries: -1,
+	}}
+	pipelines := []*swarm.Pipeline{
+		{ID: "P1", Name: "orders", Steps: []swarm.PipelineSteps{swarm.FilterStep("return message.type == 'order';")}, Outputs: []string{"P2"}},
+		{ID: "P2", Name: "enrich", Steps: []swarm.PipelineSteps{swarm.TransformStep("return message;")}, Outputs: []string{"A1"}},
+		{ID: "P3", Name: "unmanaged", Steps: []swarm.PipelineSteps{swarm.FilterStep("return true;")}, Outputs: []string{}},
+	}
+
+	plan, err := Compare(mustParse(t, testManifestYAML), live, pipelines, nil)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	require.NoError(t, plan.WriteText(buf))
+	require.Equal(t, `~ pipeline enrich
+    steps[0].function: "return message;" => "return { id: message.id };"
+Plan: 0 to create, 1 to update, 0 to delete.
+`, buf.String())
+	require.Equal(t, "P2", plan.Changes[0].ID)
+
+	plan, err = Compare(mustParse(t, testManifestYAML), live, pipelines, &PlanOptions{Prune: true})
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 2)
+	require.Equal(t, "- pipeline unmanaged", plan.Changes[1].String())
+}
+
+func TestCompare_References(t *testing.T) {
+	m := mustParse(t, `
+pipelines:
+  - name: a
+    steps: [{type: filter, function: return true;, outputs: [missing]}]
+    stitchConfigs: [{pipeline: hook, key: id, ttl: 60}]
+  - name: b
+    steps: [{type: filter, function: return true;}]
+    outputs: [shared, existing]
+`)
+	live := []*swarm.WebhookAction{{ID: "A1", Name: "hook"}, {ID: "A2", Name: "shared"}, {ID: "A3", Name: "existing"}}
+	pipelines := []*swarm.Pipeline{{ID: "P1", Name: "shared"}}
+
+	_, err := Compare(m, live, pipelines, nil)
+	require.EqualError(t, err, `validation failed: `+
+		`pipelines[0].steps[0].outputs[0]: "missing" is not declared and does not exist; `+
+		`pipelines[0].stitchConfigs[0].pipeline: "hook" is not declared and does not exist; `+
+		`pipelines[1].outputs[0]: "shared" is ambiguous, it is used by A2, P1`)
+
+	m.Pipelines = m.Pipelines[1:]
+	m.Pipelines[0].Outputs = []string{"existing"}
+	_, err = Compare(m, live, pipelines, &PlanOptions{Prune: true})
+	require.EqualError(t, err, `validation failed: pipelines[0].outputs[0]: "existing" is not declared and would be pruned`)
+}
+
+func TestCompare_Cycle(t *testing.T) {
+	m := mustParse(t, `
+pipelines:
+  - name: a
+    steps: [{type: filter, function: return true;}]
+    outputs: [b]
+  - name: b
+    steps: [{type: filter, function: return true;}]
+    stitchConfigs: [{pipeline: a, key: id, ttl: 60}]
+`)
+	_, err := Compare(m, nil, nil, nil)
+	require.EqualError(t, err, "pipelines that do not exist yet refer to each other: a -> b -> a")
+
+	// once one of them exists the other can be created first
+	plan, err := Compare(m, nil, []*swarm.Pipeline{{ID: "P1", Name: "a", Outputs: []string{}}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "+ pipeline b", plan.Changes[0].String())
+	require.Equal(t, "~ pipeline a", plan.Changes[1].String())
+}
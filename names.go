@@ -0,0 +1,104 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when no resource has the requested name
+	ErrNotFound = errors.New("not found")
+	// ErrAmbiguousName is returned when more than one resource has the
+	// requested name
+	ErrAmbiguousName = errors.New("name is ambiguous")
+	// ErrDuplicateName is returned by Create on clients using UniqueNames
+	// when a resource with the same name already exists
+	ErrDuplicateName = errors.New("name already exists")
+)
+
+// UniqueNames makes Create on pipelines and webhook actions fail with
+// ErrDuplicateName when a resource of the same kind already has the name.
+// The check lists the existing resources first, so two clients creating the
+// same name at once can still both succeed.
+func UniqueNames() ClientOption {
+	return func(c *Client) {
+		c.uniqueNames = true
+	}
+}
+
+// GetByName returns the only pipeline with the given name. It returns
+// ErrNotFound when there is none and ErrAmbiguousName when there are several,
+// both possibly wrapped.
+func (s *PipelinesService) GetByName(ctx context.Context, name string) (*Pipeline, *http.Response, error) {
+	pipelines, resp, err := s.List(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var found []*Pipeline
+	for _, p := range pipelines {
+		if p.Name == name {
+			found = append(found, p)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, resp, fmt.Errorf("pipeline %q: %w", name, ErrNotFound)
+	case 1:
+		return found[0], resp, nil
+	}
+
+	ids := make([]string, len(found))
+	for i, p := range found {
+		ids[i] = p.ID
+	}
+	return nil, resp, ambiguousNameError("pipeline", name, ids)
+}
+
+// GetByName returns the only webhook action with the given name. It returns
+// ErrNotFound when there is none and ErrAmbiguousName when there are several,
+// both possibly wrapped.
+func (s *WebhookActionsService) GetByName(ctx context.Context, name string) (*WebhookAction, *http.Response, error) {
+	actions, resp, err := s.List(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var found []*WebhookAction
+	for _, a := range actions {
+		if a.Name == name {
+			found = append(found, a)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, resp, fmt.Errorf("webhook action %q: %w", name, ErrNotFound)
+	case 1:
+		return found[0], resp, nil
+	}
+
+	ids := make([]string, len(found))
+	for i, a := range found {
+		ids[i] = a.ID
+	}
+	return nil, resp, ambiguousNameError("webhook action", name, ids)
+}
+
+func ambiguousNameError(kind string, name string, ids []string) error {
+	return fmt.Errorf("%s %q: %w: used by %s", kind, name, ErrAmbiguousName, strings.Join(ids, ", "))
+}
+
+// duplicateNameError interprets the result of a GetByName call made before
+// Create on clients using UniqueNames. It returns nil when the name is free.
+func duplicateNameError(kind string, name string, err error) error {
+	switch {
+	case err == nil, errors.Is(err, ErrAmbiguousName):
+		return fmt.Errorf("%s %q: %w", kind, name, ErrDuplicateName)
+	case errors.Is(err, ErrNotFound):
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelines_GetByName(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	other := `{"id": "01G6XSSVAAT8CNRGXPSBZSA1PZ", "name": "pipeline2", "steps": [], "outputs": []}`
+	dup := `{"id": "01G6XSSVAAT8CNRGXPSBZSA1PX", "name": "pipeline2", "steps": [], "outputs": []}`
+	mux.HandleFunc("/authenticated/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		fmt.Fprint(w, `[`+testPipelineJSON+`,`+other+`,`+dup+`]`)
+	})
+	ctx := context.Background()
+
+	got, _, err := client.Pipelines.GetByName(ctx, "pipeline1")
+	require.NoError(t, err)
+	require.Equal(t, testPipelineObj, got)
+
+	_, _, err = client.Pipelines.GetByName(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, _, err = client.Pipelines.GetByName(ctx, "pipeline2")
+	require.ErrorIs(t, err, ErrAmbiguousName)
+	require.EqualError(t, err, `pipeline "pipeline2": name is ambiguous: used by 01G6XSSVAAT8CNRGXPSBZSA1PZ, 01G6XSSVAAT8CNRGXPSBZSA1PX`)
+}
+
+func TestWebhookActions_GetByName(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/authenticated/webhookactions", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[`+testWebhookActionJSON+`]`)
+	})
+	ctx := context.Background()
+
+	got, _, err := client.WebhookActions.GetByName(ctx, testWebhookActionObj.Name)
+	require.NoError(t, err)
+	require.Equal(t, testWebhookActionObj, got)
+
+	_, _, err = client.WebhookActions.GetByName(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.EqualError(t, err, `webhook action "missing": not found`)
+}
+
+func TestUniqueNames(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	UniqueNames()(client)
+
+	created := 0
+	mux.HandleFunc("/authenticated/pipelines", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fmt.Fprint(w, `[`+testPipelineJSON+`]`)
+		case "POST":
+			created++
+			fmt.Fprint(w, testPipelineJSON)
+		}
+	})
+	ctx := context.Background()
+
+	_, _, err := client.Pipelines.Create(ctx, testPipelineObj)
+	require.ErrorIs(t, err, ErrDuplicateName)
+	require.Zero(t, created)
+
+	p := *testPipelineObj
+	p.Name = "pipeline2"
+	_, _, err = client.Pipelines.Create(ctx, &p)
+	require.NoError(t, err)
+	require.Equal(t, 1, created)
+}
@@ -2,6 +2,7 @@ package swarm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -11,7 +12,9 @@ type PipelinesService service
 
 const pipelinesPath = "authenticated/pipelines"
 
-// Pipeline is the generic type used for creating and returning Pipelines
+// Pipeline is the generic type used for creating and returning Pipelines.
+// MaxRetries is MaxRetriesInfinite to retry forever, see RetryPolicy for a
+// typed view of the retry fields.
 type Pipeline struct {
 	ID                   string                 `json:"id"`
 	Name                 string                 `json:"name"`
@@ -21,6 +24,10 @@ type Pipeline struct {
 	StitchConfigs        []PipelineStitchConfig `json:"stitchConfigs"`
 	RetryIntervalSeconds int                    `json:"retryIntervalSeconds"`
 	MaxRetries           int                    `json:"maxRetries"`
+
+	// Extra holds fields returned by the API that this client does not
+	// know about. They are sent back unchanged on Create and Update.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // PipelineSteps is a nested type for the Pipeline struct
@@ -28,14 +35,90 @@ type PipelineSteps struct {
 	Function string   `json:"function"`
 	Outputs  []string `json:"outputs"`
 	Required bool     `json:"required"`
-	Type     string   `json:"type"`
+	Type     StepType `json:"type"`
+
+	// Extra holds fields returned by the API that this client does not
+	// know about. They are sent back unchanged on Create and Update.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// StepType identifies what a pipeline step does with a message. The
+// constants cover the types this client knows how to validate and run
+// locally. Types it does not know about are kept as is when decoded, so
+// pipelines using other step types still round trip, and NewStep builds
+// steps of any type.
+//
+// PipelineSteps.Type used to be a plain string. Assigning a string variable
+// to it now needs a conversion, StepType(s); untyped string constants such as
+// "filter" still work.
+type StepType string
+
+const (
+	// StepTypeFilter steps drop the message unless the function returns a
+	// truthy value
+	StepTypeFilter StepType = "filter"
+	// StepTypeTransform steps replace the message with the value the
+	// function returns
+	StepTypeTransform StepType = "transform"
+)
+
+// KnownStepTypes returns every step type this client knows about
+func KnownStepTypes() []StepType {
+	return []StepType{StepTypeFilter, StepTypeTransform}
+}
+
+// IsKnown reports whether the step type is one of KnownStepTypes
+func (t StepType) IsKnown() bool {
+	for _, known := range KnownStepTypes() {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterStep returns a required filter step running the given function.
+// Outputs are optional IDs the step sends messages to.
+func FilterStep(function string, outputs ...string) PipelineSteps {
+	return newStep(StepTypeFilter, function, outputs)
 }
 
-// PipelineStitchConfig is a nested type for the Pipeline struct
+// TransformStep returns a required transform step running the given
+// function. Outputs are optional IDs the step sends messages to.
+func TransformStep(function string, outputs ...string) PipelineSteps {
+	return newStep(StepTypeTransform, function, outputs)
+}
+
+// NewStep returns a required step of any type running the given function,
+// for step types without a constructor of their own. Outputs are optional IDs
+// the step sends messages to.
+func NewStep(t StepType, function string, outputs ...string) PipelineSteps {
+	return newStep(t, function, outputs)
+}
+
+func newStep(t StepType, function string, outputs []string) PipelineSteps {
+	// outputs are never nil so they encode as an empty array like the API
+	// returns them
+	o := make([]string, len(outputs))
+	copy(o, outputs)
+	return PipelineSteps{
+		Function: function,
+		Outputs:  o,
+		Required: true,
+		Type:     t,
+	}
+}
+
+// PipelineStitchConfig is a nested type for the Pipeline struct. TTL is in
+// seconds, see TTLDuration and SetTTL.
 type PipelineStitchConfig struct {
 	StitchPipelineID string `json:"stitchPipelineId"`
 	Key              string `json:"key"`
 	TTL              int    `json:"ttl"`
+
+	// Extra holds fields returned by the API that this client does not
+	// know about. They are sent back unchanged on Create and Update.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // List all pipelines
@@ -45,7 +128,7 @@ func (s *PipelinesService) List(ctx context.Context) ([]*Pipeline, *http.Respons
 		return nil, nil, err
 	}
 
-	req, err := s.client.NewRequest("GET", u, nil)
+	req, err := s.client.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -62,7 +145,7 @@ func (s *PipelinesService) List(ctx context.Context) ([]*Pipeline, *http.Respons
 // Get a pipeline by ID
 func (s *PipelinesService) Get(ctx context.Context, id string) (*Pipeline, *http.Response, error) {
 	path := fmt.Sprintf("%s/%s", pipelinesPath, id)
-	req, err := s.client.NewRequestWithBaseURL("GET", path, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "GET", path, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -78,7 +161,17 @@ func (s *PipelinesService) Get(ctx context.Context, id string) (*Pipeline, *http
 
 // Create a pipeline
 func (s *PipelinesService) Create(ctx context.Context, i *Pipeline) (*Pipeline, *http.Response, error) {
-	req, err := s.client.NewRequestWithBaseURL("POST", pipelinesPath, i)
+	if err := s.client.validate(ctx, i); err != nil {
+		return nil, nil, err
+	}
+	if s.client.uniqueNames && i != nil {
+		_, resp, err := s.GetByName(ctx, i.Name)
+		if err := duplicateNameError("pipeline", i.Name, err); err != nil {
+			return nil, resp, err
+		}
+	}
+
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "POST", pipelinesPath, i)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -92,9 +185,35 @@ func (s *PipelinesService) Create(ctx context.Context, i *Pipeline) (*Pipeline,
 	return p, resp, nil
 }
 
-// Update a pipeline
+// Update a pipeline. The pipeline is identified by its ID field, see
+// UpdateByID to pass the ID separately like WebhookActionsService.Update.
 func (s *PipelinesService) Update(ctx context.Context, i *Pipeline) (*Pipeline, *http.Response, error) {
-	req, err := s.client.NewRequestWithBaseURL("PUT", pipelinesPath, i)
+	if err := s.client.validate(ctx, i); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "PUT", pipelinesPath, i)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := new(Pipeline)
+	resp, err := s.client.DoRequest(ctx, req, p)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return p, resp, nil
+}
+
+// UpdateByID updates the pipeline with the given ID
+func (s *PipelinesService) UpdateByID(ctx context.Context, id string, i *Pipeline) (*Pipeline, *http.Response, error) {
+	if err := s.client.validate(ctx, i); err != nil {
+		return nil, nil, err
+	}
+
+	path := fmt.Sprintf("%s/%s", pipelinesPath, id)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "PUT", path, i)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -111,7 +230,7 @@ func (s *PipelinesService) Update(ctx context.Context, i *Pipeline) (*Pipeline,
 // Delete a pipeline by ID
 func (s *PipelinesService) Delete(ctx context.Context, id string) (*http.Response, error) {
 	path := fmt.Sprintf("%s/%s", pipelinesPath, id)
-	req, err := s.client.NewRequestWithBaseURL("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "DELETE", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -120,10 +239,25 @@ func (s *PipelinesService) Delete(ctx context.Context, id string) (*http.Respons
 	return resp, err
 }
 
-// DeleteAll pipelines
-func (s *PipelinesService) DeleteAll(ctx context.Context) (*http.Response, error) {
+// DeleteAll pipelines. The options confirm the call, ask for a dry run or back
+// the account up first. Only clients using SafeMode require the confirmation;
+// on other clients DeleteAll without options deletes everything, as it always
+// has.
+func (s *PipelinesService) DeleteAll(ctx context.Context, opts ...DeleteAllOption) (*http.Response, error) {
+	ok, err := s.client.guardDeleteAll(ctx, "pipeline", opts, func() ([]string, error) {
+		pipelines, _, err := s.List(ctx)
+		var out []string
+		for _, p := range pipelines {
+			out = append(out, fmt.Sprintf("%s %q", p.ID, p.Name))
+		}
+		return out, err
+	})
+	if !ok {
+		return nil, err
+	}
+
 	path := fmt.Sprintf("%s/all", pipelinesPath)
-	req, err := s.client.NewRequestWithBaseURL("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "DELETE", path, nil)
 	if err != nil {
 		return nil, err
 	}
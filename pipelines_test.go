@@ -2,6 +2,7 @@ package swarm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
@@ -121,6 +122,24 @@ func TestPipelines_Update(t *testing.T) {
 	require.Equal(t, testPipelineObj, got)
 }
 
+func TestPipelines_UpdateByID(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/authenticated/pipelines/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/authenticated/pipelines/"+testPipelineID, r.URL.Path)
+		require.Contains(t, r.Header, "Authorization")
+		fmt.Fprint(w, testPipelineJSON)
+	})
+
+	ctx := context.Background()
+	got, _, err := client.Pipelines.UpdateByID(ctx, testPipelineID, testPipelineObj)
+
+	require.NoError(t, err)
+	require.Equal(t, testPipelineObj, got)
+}
+
 func TestPipelines_Delete(t *testing.T) {
 	client, mux, teardown := setup()
 	defer teardown()
@@ -150,3 +169,34 @@ func TestPipelines_DeleteAll(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestStepBuilders(t *testing.T) {
+	got := FilterStep("return message.hello == 'prod';")
+	require.Equal(t, testPipelineObj.Steps[0], got)
+	require.Equal(t, StepTypeFilter, got.Type)
+
+	got = TransformStep("return message", "01G2YYM9RRT8CNRGXPSBZSA1PY")
+	require.Equal(t, PipelineSteps{
+		Function: "return message",
+		Outputs:  []string{"01G2YYM9RRT8CNRGXPSBZSA1PY"},
+		Required: true,
+		Type:     StepTypeTransform,
+	}, got)
+
+	got = NewStep("enrich", "return message")
+	require.Equal(t, StepType("enrich"), got.Type)
+	require.Equal(t, []string{}, got.Outputs)
+	require.True(t, got.Required)
+}
+
+func TestStepType_Unknown(t *testing.T) {
+	var s PipelineSteps
+	require.NoError(t, json.Unmarshal([]byte(`{"function": "return 1", "type": "enrich"}`), &s))
+	require.Equal(t, StepType("enrich"), s.Type)
+	require.False(t, s.Type.IsKnown())
+	require.True(t, StepTypeTransform.IsKnown())
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"type":"enrich"`)
+}
@@ -0,0 +1,148 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CredentialsLookup returns the credentials to use for a customer
+type CredentialsLookup func(ctx context.Context, customerID string) (CredentialsProvider, error)
+
+// PoolOptions configures NewClientPool
+type PoolOptions struct {
+	// Credentials looks up the credentials of a customer when its client is
+	// created. It is required.
+	Credentials CredentialsLookup
+	// HTTPClient sends the requests of every client in the pool. Metrics and
+	// middleware go in its Transport. The default has a one minute timeout.
+	HTTPClient *http.Client
+	// ClientOptions are applied to every client, after the credentials and
+	// HTTP client
+	ClientOptions []ClientOption
+	// IdleTimeout is how long a client may go unused before it is evicted.
+	// Zero keeps clients until they are evicted explicitly.
+	IdleTimeout time.Duration
+}
+
+// ClientPool hands out clients for many customers. Clients are created on
+// first use and share one HTTP client, so connections and middleware are not
+// duplicated per customer. It is safe for concurrent use.
+type ClientPool struct {
+	opts PoolOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+}
+
+// NewClientPool returns an empty pool
+func NewClientPool(opts *PoolOptions) (*ClientPool, error) {
+	if opts == nil || opts.Credentials == nil {
+		return nil, errors.New("a credentials lookup is required")
+	}
+	p := &ClientPool{opts: *opts, now: time.Now, clients: map[string]*pooledClient{}}
+	if p.opts.HTTPClient == nil {
+		p.opts.HTTPClient = &http.Client{Timeout: time.Minute}
+	}
+	return p, nil
+}
+
+// Get returns the client for the customer, creating it when the pool has
+// none. Clients idle for longer than the idle timeout are evicted first. The
+// customer ID becomes part of a host name, so it may only contain letters,
+// digits and hyphens.
+func (p *ClientPool) Get(ctx context.Context, customerID string) (*Client, error) {
+	if err := checkCustomerID(customerID); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.evictIdle()
+	if pc, ok := p.clients[customerID]; ok {
+		pc.lastUsed = p.now()
+		p.mu.Unlock()
+		return pc.client, nil
+	}
+	p.mu.Unlock()
+
+	// the lookup may be slow, so it runs without holding the lock
+	creds, err := p.opts.Credentials(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("looking up credentials of customer %s: %w", customerID, err)
+	}
+	if creds == nil {
+		return nil, fmt.Errorf("looking up credentials of customer %s: no credentials returned", customerID)
+	}
+	opts := append([]ClientOption{WithCredentials(creds), WithHTTPClient(p.opts.HTTPClient)}, p.opts.ClientOptions...)
+	client := NewClient(customerID, "", opts...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc, ok := p.clients[customerID]; ok {
+		// another caller created it meanwhile
+		pc.lastUsed = p.now()
+		return pc.client, nil
+	}
+	p.clients[customerID] = &pooledClient{client: client, lastUsed: p.now()}
+	return client, nil
+}
+
+// checkCustomerID returns an error for IDs that cannot be the host label of
+// the customer URL, where NewClient would panic or address another host
+func checkCustomerID(customerID string) error {
+	if customerID == "" {
+		return errors.New("customer ID is empty")
+	}
+	for _, r := range customerID {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-') {
+			return fmt.Errorf("customer ID %q may only contain letters, digits and hyphens", customerID)
+		}
+	}
+	return nil
+}
+
+// Evict removes the customer's client, so the next Get looks up its
+// credentials again
+func (p *ClientPool) Evict(customerID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, customerID)
+}
+
+// EvictIdle removes the clients that have been idle for longer than the idle
+// timeout and returns how many it removed
+func (p *ClientPool) EvictIdle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.evictIdle()
+}
+
+func (p *ClientPool) evictIdle() int {
+	if p.opts.IdleTimeout <= 0 {
+		return 0
+	}
+	evicted := 0
+	cutoff := p.now().Add(-p.opts.IdleTimeout)
+	for id, pc := range p.clients {
+		if pc.lastUsed.Before(cutoff) {
+			delete(p.clients, id)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Len returns the number of clients in the pool
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.clients)
+}
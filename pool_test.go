@@ -0,0 +1,137 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingTransport counts requests, standing in for metrics middleware
+type countingTransport struct {
+	requests int64
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&c.requests, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestClientPool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+	base, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+
+	var lookups int64
+	transport := &countingTransport{}
+	pool, err := NewClientPool(&PoolOptions{
+		Credentials: func(ctx context.Context, customerID string) (CredentialsProvider, error) {
+			atomic.AddInt64(&lookups, 1)
+			switch customerID {
+			case "UNKNOWN":
+				return nil, errors.New("no such customer")
+			case "NOCREDS":
+				return nil, nil
+			}
+			return StaticCredentials("KEY-" + customerID), nil
+		},
+		HTTPClient:    &http.Client{Transport: transport},
+		ClientOptions: []ClientOption{func(c *Client) { c.BaseURL = base }},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := pool.Get(ctx, fmt.Sprintf("CUSTOMER%d", i%2))
+			if err == nil {
+				_, _, err = client.Pipelines.List(ctx)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, pool.Len())
+	require.EqualValues(t, 20, atomic.LoadInt64(&transport.requests))
+
+	a, err := pool.Get(ctx, "CUSTOMER0")
+	require.NoError(t, err)
+	b, err := pool.Get(ctx, "CUSTOMER0")
+	require.NoError(t, err)
+	require.Same(t, a, b)
+	key, err := a.APIKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "KEY-CUSTOMER0", key)
+	require.Equal(t, "https://CUSTOMER0.api.swarmiolabs.com/v1/", a.CustomerURL.String())
+
+	_, err = pool.Get(ctx, "UNKNOWN")
+	require.EqualError(t, err, "looking up credentials of customer UNKNOWN: no such customer")
+	_, err = pool.Get(ctx, "NOCREDS")
+	require.EqualError(t, err, "looking up credentials of customer NOCREDS: no credentials returned")
+	require.Equal(t, 2, pool.Len())
+
+	// IDs that are not host labels are rejected before any lookup
+	lookupsBefore := atomic.LoadInt64(&lookups)
+	for _, id := range []string{"", "acme corp", "evil.example.com/x", "acme%"} {
+		_, err = pool.Get(ctx, id)
+		require.Error(t, err, id)
+	}
+	_, err = pool.Get(ctx, "acme corp")
+	require.EqualError(t, err, `customer ID "acme corp" may only contain letters, digits and hyphens`)
+	require.Equal(t, lookupsBefore, atomic.LoadInt64(&lookups))
+	require.Equal(t, 2, pool.Len())
+
+	pool.Evict("CUSTOMER0")
+	require.Equal(t, 1, pool.Len())
+}
+
+func TestClientPool_EvictIdle(t *testing.T) {
+	pool, err := NewClientPool(&PoolOptions{
+		Credentials: func(ctx context.Context, customerID string) (CredentialsProvider, error) {
+			return StaticCredentials("KEY"), nil
+		},
+		IdleTimeout: time.Minute,
+	})
+	require.NoError(t, err)
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err = pool.Get(ctx, "A")
+	require.NoError(t, err)
+	now = now.Add(30 * time.Second)
+	_, err = pool.Get(ctx, "B")
+	require.NoError(t, err)
+
+	now = now.Add(45 * time.Second)
+	require.Equal(t, 1, pool.EvictIdle())
+	require.Equal(t, 1, pool.Len())
+
+	// Get evicts idle clients too
+	now = now.Add(time.Hour)
+	_, err = pool.Get(ctx, "C")
+	require.NoError(t, err)
+	require.Equal(t, 1, pool.Len())
+
+	_, err = NewClientPool(&PoolOptions{})
+	require.EqualError(t, err, "a credentials lookup is required")
+}
@@ -0,0 +1,306 @@
+// Package promote copies pipelines, and the webhook actions they output to,
+// from one Swarm account to another, such as from staging to production.
+// Resources are matched by name, environment specific webhook URLs and
+// headers are substituted, and the changes are shown for confirmation before
+// they are applied.
+package promote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/manifest"
+)
+
+// ErrNotConfirmed is returned by Apply when the promotion was not confirmed
+var ErrNotConfirmed = errors.New("promotion was not confirmed")
+
+// Substitutions adapts webhook actions to the target environment. URL
+// replacements apply first, then header values, then per action overrides.
+type Substitutions struct {
+	// URLs replaces text in every promoted webhook action URL, in order
+	URLs []Replacement `json:"urls,omitempty"`
+	// Headers sets the value of headers with these names in every promoted
+	// webhook action that has them. Names match case insensitively, so two
+	// names differing only in case are rejected.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Actions overrides individual webhook actions by name
+	Actions map[string]ActionOverride `json:"actions,omitempty"`
+}
+
+// Replacement replaces every occurrence of Old with New
+type Replacement struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// ActionOverride replaces the URL of a webhook action when URL is set, and
+// sets the given headers, adding those it does not have. Header names match
+// case insensitively like in Substitutions.
+type ActionOverride struct {
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Options configures Prepare
+type Options struct {
+	// Pipelines names the pipelines to promote. The webhook actions they
+	// output to are promoted with them. Pipelines they output to or stitch
+	// with that are not named must already exist in the target.
+	Pipelines []string
+	// Substitutions adapts webhook actions to the target
+	Substitutions Substitutions
+	// Source and Target label the accounts in the record, such as "staging"
+	// and "prod"
+	Source string
+	Target string
+}
+
+// Promotion is a prepared promotion. Its plan lists the changes to the
+// target account; nothing is changed until Apply.
+type Promotion struct {
+	// Manifest declares the promoted resources as they will be in the
+	// target, after substitutions
+	Manifest *manifest.Manifest
+	// Plan holds the changes that make the target match
+	Plan *manifest.Plan
+
+	target *swarm.Client
+	opts   Options
+}
+
+// Record describes an applied promotion, for an audit log
+type Record struct {
+	PromotedAt time.Time          `json:"promotedAt"`
+	Source     string             `json:"source,omitempty"`
+	Target     string             `json:"target,omitempty"`
+	Pipelines  []string           `json:"pipelines"`
+	Changes    []*manifest.Change `json:"changes"`
+}
+
+// Prepare reads the named pipelines and the webhook actions they output to
+// from the source account and plans the changes that make the target match,
+// without making any.
+func Prepare(ctx context.Context, source, target *swarm.Client, opts *Options) (*Promotion, error) {
+	if opts == nil || len(opts.Pipelines) == 0 {
+		return nil, errors.New("no pipelines to promote")
+	}
+	actions, _, err := source.WebhookActions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing source webhook actions: %w", err)
+	}
+	pipelines, _, err := source.Pipelines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing source pipelines: %w", err)
+	}
+
+	m, err := selectResources(manifest.FromResources(actions, pipelines), opts.Pipelines)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Substitutions.apply(m); err != nil {
+		return nil, err
+	}
+
+	plan, err := manifest.NewPlan(ctx, target, m, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Promotion{Manifest: m, Plan: plan, target: target, opts: *opts}, nil
+}
+
+// selectResources keeps the named pipelines of the source manifest and the
+// webhook actions they output to
+func selectResources(src *manifest.Manifest, names []string) (*manifest.Manifest, error) {
+	pipelines := map[string][]*manifest.Pipeline{}
+	for _, p := range src.Pipelines {
+		pipelines[p.Name] = append(pipelines[p.Name], p)
+	}
+	actions := map[string][]*manifest.WebhookAction{}
+	for _, a := range src.WebhookActions {
+		actions[a.Name] = append(actions[a.Name], a)
+	}
+
+	m := &manifest.Manifest{}
+	selected := map[string]bool{}
+	for _, name := range names {
+		switch found := pipelines[name]; {
+		case len(found) == 0:
+			return nil, fmt.Errorf("pipeline %q: %w in the source", name, swarm.ErrNotFound)
+		case len(found) > 1:
+			return nil, fmt.Errorf("pipeline %q: %w in the source", name, swarm.ErrAmbiguousName)
+		case !selected[name]:
+			selected[name] = true
+			m.Pipelines = append(m.Pipelines, found[0])
+		}
+	}
+
+	for _, p := range m.Pipelines {
+		refs := append([]string{}, p.Outputs...)
+		for _, s := range p.Steps {
+			refs = append(refs, s.Outputs...)
+		}
+		for _, name := range refs {
+			found := actions[name]
+			if len(found) == 0 || selected[name] {
+				continue
+			}
+			if len(found) > 1 {
+				return nil, fmt.Errorf("webhook action %q: %w in the source", name, swarm.ErrAmbiguousName)
+			}
+			selected[name] = true
+			m.WebhookActions = append(m.WebhookActions, found[0])
+		}
+	}
+	return m, nil
+}
+
+// apply substitutes the webhook actions of m in place. Actions are copied
+// first so the source manifest is not modified.
+func (s *Substitutions) apply(m *manifest.Manifest) error {
+	if err := checkHeaderNames("header substitutions", s.Headers); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(s.Actions))
+	for name := range s.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checkHeaderNames(fmt.Sprintf("substitution for webhook action %q", name), s.Actions[name].Headers); err != nil {
+			return err
+		}
+	}
+	for name := range s.Actions {
+		found := false
+		for _, a := range m.WebhookActions {
+			found = found || a.Name == name
+		}
+		if !found {
+			return fmt.Errorf("substitution for webhook action %q, which is not promoted", name)
+		}
+	}
+
+	for i, orig := range m.WebhookActions {
+		a := *orig
+		a.Headers = append([]manifest.Header{}, orig.Headers...)
+		for _, r := range s.URLs {
+			a.URL = strings.ReplaceAll(a.URL, r.Old, r.New)
+		}
+		for j, h := range a.Headers {
+			for name, value := range s.Headers {
+				if strings.EqualFold(h.Name, name) {
+					a.Headers[j].Value = value
+					break
+				}
+			}
+		}
+		if o, ok := s.Actions[a.Name]; ok {
+			if o.URL != "" {
+				a.URL = o.URL
+			}
+			for _, name := range sortedKeys(o.Headers) {
+				set := false
+				for j, h := range a.Headers {
+					if strings.EqualFold(h.Name, name) {
+						a.Headers[j].Value = o.Headers[name]
+						set = true
+					}
+				}
+				if !set {
+					a.Headers = append(a.Headers, manifest.Header{Name: name, Value: o.Headers[name]})
+				}
+			}
+		}
+		m.WebhookActions[i] = &a
+	}
+	return nil
+}
+
+// checkHeaderNames returns an error naming what when two header names differ
+// only in case, since which value applies would be ambiguous
+func checkHeaderNames(what string, headers map[string]string) error {
+	seen := map[string]string{}
+	for _, name := range sortedKeys(headers) {
+		key := strings.ToLower(name)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("%s: headers %q and %q differ only in case", what, other, name)
+		}
+		seen[key] = name
+	}
+	return nil
+}
+
+// WriteText writes a description of the promotion and its plan
+func (p *Promotion) WriteText(w io.Writer) error {
+	from, to := p.opts.Source, p.opts.Target
+	if from == "" {
+		from = "source"
+	}
+	if to == "" {
+		to = "target"
+	}
+	if _, err := fmt.Fprintf(w, "Promoting %s from %s to %s:\n", strings.Join(p.opts.Pipelines, ", "), from, to); err != nil {
+		return err
+	}
+	return p.Plan.WriteText(w)
+}
+
+// Apply asks confirm whether to go ahead, then applies the plan to the
+// target. It returns ErrNotConfirmed when confirm returns false or is nil.
+// Nothing is asked when the plan is empty. The record lists the changes made, also
+// when applying fails part way.
+func (p *Promotion) Apply(ctx context.Context, confirm func(*Promotion) (bool, error)) (*Record, error) {
+	record := &Record{
+		PromotedAt: time.Now().UTC(),
+		Source:     p.opts.Source,
+		Target:     p.opts.Target,
+		Pipelines:  p.opts.Pipelines,
+		Changes:    []*manifest.Change{},
+	}
+	if p.Plan.Empty() {
+		return record, nil
+	}
+	if confirm == nil {
+		return nil, ErrNotConfirmed
+	}
+	ok, err := confirm(p)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotConfirmed
+	}
+
+	err = p.Plan.Apply(ctx, p.target)
+	for _, c := range p.Plan.Changes {
+		if c.Applied {
+			record.Changes = append(record.Changes, c)
+		}
+	}
+	return record, err
+}
+
+// WriteJSON writes the record as a single line of JSON, so records can be
+// appended to a log file
+func (r *Record) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package promote
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/manifest"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func testAccounts(t *testing.T) (source, target *swarmtest.Server) {
+	source = swarmtest.NewServer()
+	t.Cleanup(source.Close)
+	hook := source.AddWebhookAction(&swarm.WebhookAction{
+		Name:    "orders-hook",
+		URL:     "https://staging.example.com/orders",
+		Method:  "POST",
+		Headers: []swarm.WebhookActionsHeader{{Name: "Authorization", Value: "staging-key"}},
+	})
+	source.AddWebhookAction(&swarm.WebhookAction{Name: "unrelated", URL: "https://staging.example.com/x", Method: "POST"})
+	source.AddPipeline(&swarm.Pipeline{
+		Name:    "orders",
+		Steps:   []swarm.PipelineSteps{swarm.FilterStep("return message.type == 'order';")},
+		Outputs: []string{hook.ID},
+	})
+	source.AddPipeline(&swarm.Pipeline{Name: "other", Steps: []swarm.PipelineSteps{swarm.FilterStep("return true;")}})
+
+	target = swarmtest.NewServer()
+	t.Cleanup(target.Close)
+	return source, target
+}
+
+func TestPromote(t *testing.T) {
+	source, target := testAccounts(t)
+	ctx := context.Background()
+
+	p, err := Prepare(ctx, source.Client(), target.Client(), &Options{
+		Pipelines: []string{"orders"},
+		Substitutions: Substitutions{
+			URLs:    []Replacement{{Old: "staging.example.com", New: "example.com"}},
+			Headers: map[string]string{"authorization": "prod-key"},
+		},
+		Source: "staging",
+		Target: "prod",
+	})
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, p.WriteText(buf))
+	require.Equal(t, `Promoting orders from staging to prod:
++ webhookAction orders-hook
++ pipeline orders
+Plan: 2 to create, 0 to update, 0 to delete.
+`, buf.String())
+
+	// nothing happens without confirmation
+	_, err = p.Apply(ctx, func(*Promotion) (bool, error) { return false, nil })
+	require.ErrorIs(t, err, ErrNotConfirmed)
+	_, err = p.Apply(ctx, nil)
+	require.ErrorIs(t, err, ErrNotConfirmed)
+	require.Zero(t, target.Writes())
+
+	record, err := p.Apply(ctx, func(*Promotion) (bool, error) { return true, nil })
+	require.NoError(t, err)
+	require.Equal(t, "prod", record.Target)
+	require.Len(t, record.Changes, 2)
+
+	actions := target.WebhookActions()
+	require.Len(t, actions, 1)
+	require.Equal(t, "https://example.com/orders", actions[0].URL)
+	require.Equal(t, "prod-key", actions[0].Headers[0].Value)
+	require.Equal(t, []string{actions[0].ID}, target.Pipelines()[0].Outputs)
+
+	// a second promotion only shows what changed
+	source.AddPipeline(&swarm.Pipeline{Name: "unused", Steps: []swarm.PipelineSteps{swarm.FilterStep("return true;")}})
+	p, err = Prepare(ctx, source.Client(), target.Client(), &Options{
+		Pipelines: []string{"orders"},
+		Substitutions: Substitutions{
+			Actions: map[string]ActionOverride{"orders-hook": {URL: "https://prod.example.com/orders", Headers: map[string]string{"X-Env": "prod"}}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, p.Plan.Changes, 1)
+	require.Equal(t, manifest.ActionUpdate, p.Plan.Changes[0].Action)
+	require.Equal(t, []manifest.FieldDiff{
+		{Path: "headers[0].value", Old: "prod-key", New: "staging-key"},
+		{Path: "headers[1]", Old: nil, New: map[string]interface{}{"name": "X-Env", "value": "prod"}},
+		{Path: "url", Old: "https://example.com/orders", New: "https://prod.example.com/orders"},
+	}, p.Plan.Changes[0].Diffs)
+}
+
+func TestPrepare_Errors(t *testing.T) {
+	source, target := testAccounts(t)
+	ctx := context.Background()
+
+	_, err := Prepare(ctx, source.Client(), target.Client(), &Options{Pipelines: []string{"missing"}})
+	require.ErrorIs(t, err, swarm.ErrNotFound)
+
+	_, err = Prepare(ctx, source.Client(), target.Client(), &Options{
+		Pipelines:     []string{"orders"},
+		Substitutions: Substitutions{Actions: map[string]ActionOverride{"unrelated": {URL: "https://example.com"}}},
+	})
+	require.EqualError(t, err, `substitution for webhook action "unrelated", which is not promoted`)
+
+	_, err = Prepare(ctx, source.Client(), target.Client(), &Options{
+		Pipelines:     []string{"orders"},
+		Substitutions: Substitutions{Headers: map[string]string{"X-Key": "a", "x-key": "b"}},
+	})
+	require.EqualError(t, err, `header substitutions: headers "X-Key" and "x-key" differ only in case`)
+
+	_, err = Prepare(ctx, source.Client(), target.Client(), nil)
+	require.EqualError(t, err, "no pipelines to promote")
+}
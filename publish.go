@@ -16,7 +16,7 @@ type PublishService service
 // sent as the body of the request.
 func (s *PublishService) Publish(ctx context.Context, pipelineName string, data interface{}) (*http.Response, error) {
 	path := fmt.Sprintf("%s?name=%s", publishPath, url.QueryEscape(pipelineName))
-	req, err := s.client.NewRequestWithCustomerURL("POST", path, data)
+	req, err := s.client.newRequest(ctx, s.client.CustomerURL, "POST", path, data)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +33,7 @@ func (s *PublishService) Publish(ctx context.Context, pipelineName string, data
 // sent as the body of the request.
 func (s *PublishService) PublishByID(ctx context.Context, pipelineID string, data interface{}) (*http.Response, error) {
 	path := fmt.Sprintf("%s?id=%s", publishPath, url.QueryEscape(pipelineID))
-	req, err := s.client.NewRequestWithCustomerURL("POST", path, data)
+	req, err := s.client.newRequest(ctx, s.client.CustomerURL, "POST", path, data)
 	if err != nil {
 		return nil, err
 	}
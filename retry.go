@@ -0,0 +1,154 @@
+package swarm
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxRetriesInfinite is the wire value of MaxRetries meaning messages are
+// retried until they succeed
+const MaxRetriesInfinite = -1
+
+// RetryPolicy describes how failed messages are retried by pipelines and
+// webhook actions. It is a typed view over the RetryIntervalSeconds and
+// MaxRetries fields, see Pipeline.RetryPolicy and WebhookAction.RetryPolicy.
+type RetryPolicy struct {
+	// Interval is the delay between attempts
+	Interval time.Duration
+	// MaxRetries is the number of retries after the first attempt. It is
+	// ignored when Infinite is set.
+	MaxRetries int
+	// Infinite retries until the message succeeds
+	Infinite bool
+}
+
+// InfiniteRetries returns a policy retrying forever at the given interval
+func InfiniteRetries(interval time.Duration) RetryPolicy {
+	return RetryPolicy{Interval: interval, Infinite: true}
+}
+
+// LimitedRetries returns a policy retrying up to maxRetries times at the
+// given interval. A negative maxRetries is rejected when the policy is set.
+func LimitedRetries(interval time.Duration, maxRetries int) RetryPolicy {
+	return RetryPolicy{Interval: interval, MaxRetries: maxRetries}
+}
+
+// WorstCaseDelay is the longest a message can spend being retried before it
+// is given up on. The second return value is false for infinite policies,
+// which have no bound. A negative MaxRetries counts as no retries.
+func (r RetryPolicy) WorstCaseDelay() (time.Duration, bool) {
+	if r.Infinite {
+		return 0, false
+	}
+	if r.MaxRetries <= 0 {
+		return 0, true
+	}
+	return r.Interval * time.Duration(r.MaxRetries), true
+}
+
+func (r RetryPolicy) String() string {
+	if r.Infinite {
+		return fmt.Sprintf("retry every %s forever", r.Interval)
+	}
+	return fmt.Sprintf("retry every %s up to %d times", r.Interval, r.MaxRetries)
+}
+
+// retryPolicyFromWire converts the API's integer fields into a RetryPolicy.
+// Negative values other than MaxRetriesInfinite, which validation rejects,
+// are clamped to no retries.
+func retryPolicyFromWire(intervalSeconds int, maxRetries int) RetryPolicy {
+	r := RetryPolicy{Interval: time.Duration(intervalSeconds) * time.Second}
+	switch {
+	case maxRetries == MaxRetriesInfinite:
+		r.Infinite = true
+	case maxRetries > 0:
+		r.MaxRetries = maxRetries
+	}
+	return r
+}
+
+// wire converts the policy into the API's integer fields. The interval must
+// be a whole number of seconds.
+func (r RetryPolicy) wire() (intervalSeconds int, maxRetries int, err error) {
+	intervalSeconds, err = durationSeconds(r.Interval)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxRetries, err = r.wireMaxRetries()
+	if err != nil {
+		return 0, 0, err
+	}
+	return intervalSeconds, maxRetries, nil
+}
+
+// wireMaxRetries converts the policy into the API's MaxRetries field. A
+// negative count is an error, since the API would take -1 to mean retrying
+// forever.
+func (r RetryPolicy) wireMaxRetries() (int, error) {
+	if r.Infinite {
+		return MaxRetriesInfinite, nil
+	}
+	if r.MaxRetries < 0 {
+		return 0, fmt.Errorf("MaxRetries %d is negative, use InfiniteRetries to retry forever", r.MaxRetries)
+	}
+	return r.MaxRetries, nil
+}
+
+// durationSeconds converts a duration to the whole seconds the API expects.
+// Fractions are an error rather than rounded, so nothing changes silently.
+func durationSeconds(d time.Duration) (int, error) {
+	if d%time.Second != 0 {
+		return 0, fmt.Errorf("%s is not a whole number of seconds", d)
+	}
+	return int(d / time.Second), nil
+}
+
+// RetryPolicy returns the pipeline's retry settings
+func (p *Pipeline) RetryPolicy() RetryPolicy {
+	return retryPolicyFromWire(p.RetryIntervalSeconds, p.MaxRetries)
+}
+
+// SetRetryPolicy sets RetryIntervalSeconds and MaxRetries from a policy. It
+// returns an error, leaving the pipeline unchanged, when the interval is not
+// a whole number of seconds or a limited policy has negative MaxRetries.
+func (p *Pipeline) SetRetryPolicy(r RetryPolicy) error {
+	interval, maxRetries, err := r.wire()
+	if err != nil {
+		return err
+	}
+	p.RetryIntervalSeconds, p.MaxRetries = interval, maxRetries
+	return nil
+}
+
+// RetryPolicy returns the webhook action's retry settings
+func (a *WebhookAction) RetryPolicy() RetryPolicy {
+	return retryPolicyFromWire(a.RetryIntervalSeconds, a.MaxRetries)
+}
+
+// SetRetryPolicy sets RetryIntervalSeconds and MaxRetries from a policy, see
+// Pipeline.SetRetryPolicy
+func (a *WebhookAction) SetRetryPolicy(r RetryPolicy) error {
+	interval, maxRetries, err := r.wire()
+	if err != nil {
+		return err
+	}
+	a.RetryIntervalSeconds, a.MaxRetries = interval, maxRetries
+	return nil
+}
+
+// TTLDuration returns how long a stitch waits for a matching message. The
+// TTL field is in seconds.
+func (c PipelineStitchConfig) TTLDuration() time.Duration {
+	return time.Duration(c.TTL) * time.Second
+}
+
+// SetTTL sets the TTL field from a duration. It returns an error, leaving
+// the TTL unchanged, when the duration is not a whole number of seconds.
+func (c *PipelineStitchConfig) SetTTL(d time.Duration) error {
+	ttl, err := durationSeconds(d)
+	if err != nil {
+		return err
+	}
+	c.TTL = ttl
+	return nil
+}
@@ -0,0 +1,78 @@
+package swarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	r := testPipelineObj.RetryPolicy()
+	require.Equal(t, InfiniteRetries(time.Minute), r)
+	_, bounded := r.WorstCaseDelay()
+	require.False(t, bounded)
+	require.Equal(t, "retry every 1m0s forever", r.String())
+
+	r = LimitedRetries(30*time.Second, 4)
+	d, bounded := r.WorstCaseDelay()
+	require.True(t, bounded)
+	require.Equal(t, 2*time.Minute, d)
+
+	// invalid negative retries count as none instead of a negative delay
+	r = (&Pipeline{RetryIntervalSeconds: 60, MaxRetries: -5}).RetryPolicy()
+	require.Equal(t, LimitedRetries(time.Minute, 0), r)
+	d, _ = r.WorstCaseDelay()
+	require.Zero(t, d)
+	d, _ = LimitedRetries(time.Minute, -2).WorstCaseDelay()
+	require.Zero(t, d)
+}
+
+func TestSetRetryPolicy(t *testing.T) {
+	p := &Pipeline{}
+	require.NoError(t, p.SetRetryPolicy(InfiniteRetries(90*time.Second)))
+	require.Equal(t, 90, p.RetryIntervalSeconds)
+	require.Equal(t, MaxRetriesInfinite, p.MaxRetries)
+
+	// fractional seconds are rejected like the builder does, not rounded
+	a := &WebhookAction{RetryIntervalSeconds: 5}
+	err := a.SetRetryPolicy(LimitedRetries(1500*time.Millisecond, 3))
+	require.EqualError(t, err, "1.5s is not a whole number of seconds")
+	require.Equal(t, 5, a.RetryIntervalSeconds)
+
+	require.NoError(t, a.SetRetryPolicy(LimitedRetries(2*time.Second, 3)))
+	require.Equal(t, 2, a.RetryIntervalSeconds)
+	require.Equal(t, 3, a.MaxRetries)
+	require.Equal(t, LimitedRetries(2*time.Second, 3), a.RetryPolicy())
+
+	// -1 would mean retrying forever to the API
+	err = a.SetRetryPolicy(LimitedRetries(0, -1))
+	require.EqualError(t, err, "MaxRetries -1 is negative, use InfiniteRetries to retry forever")
+	require.Equal(t, 3, a.MaxRetries)
+	require.Error(t, (&Pipeline{}).SetRetryPolicy(LimitedRetries(time.Minute, -2)))
+}
+
+func TestStitchConfigTTL(t *testing.T) {
+	c := PipelineStitchConfig{TTL: 600}
+	require.Equal(t, 10*time.Minute, c.TTLDuration())
+
+	require.NoError(t, c.SetTTL(time.Hour))
+	require.Equal(t, 3600, c.TTL)
+	require.Error(t, c.SetTTL(1500*time.Millisecond))
+	require.Equal(t, 3600, c.TTL)
+}
+
+func TestPipelineBuilder_RetryPolicy(t *testing.T) {
+	p, err := NewPipeline("p").Filter("return true").RetryPolicy(InfiniteRetries(time.Minute)).Build()
+	require.NoError(t, err)
+	require.Equal(t, 60, p.RetryIntervalSeconds)
+	require.Equal(t, MaxRetriesInfinite, p.MaxRetries)
+
+	_, err = NewPipeline("p").Filter("return true").RetryPolicy(LimitedRetries(time.Minute, -1)).Build()
+	require.EqualError(t, err, "validation failed: maxRetries: MaxRetries -1 is negative, use InfiniteRetries to retry forever")
+
+	// a later call replaces the error
+	p, err = NewPipeline("p").Filter("return true").RetryPolicy(LimitedRetries(time.Minute, -1)).Retry(time.Minute, 2).Build()
+	require.NoError(t, err)
+	require.Equal(t, 2, p.MaxRetries)
+}
@@ -0,0 +1,97 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RotateOptions configures Rotate
+type RotateOptions struct {
+	// Persist is called with the new token once the client uses it, to save
+	// it to a secret store. Save token.Reveal(); the token encodes to JSON
+	// masked. An error aborts the rotation: the client goes
+	// back to the old token and the new one is deleted.
+	Persist func(ctx context.Context, token APIToken) error
+	// GracePeriod is how long other processes still using the old token get
+	// to pick up the new one before the old token is deleted
+	GracePeriod time.Duration
+}
+
+// Rotate replaces the token the client is authenticated with. It creates a
+// new token, switches the client to it, calls opts.Persist, waits for the
+// grace period and deletes the old token. When the context ends during the
+// grace period the client keeps the new token, the old one is left in place
+// and the context's error is returned with the new token. A nil opts rotates
+// without persisting or waiting.
+//
+// Afterwards the client uses the new token directly, in place of any
+// credentials provider it had.
+func (s *APITokensService) Rotate(ctx context.Context, opts *RotateOptions) (APIToken, error) {
+	if opts == nil {
+		opts = &RotateOptions{}
+	}
+	previous := s.client.provider()
+	key, err := previous.APIKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	old := APIToken(key)
+	created, _, err := s.Create(ctx)
+	if err != nil {
+		return "", fmt.Errorf("creating token: %w", err)
+	}
+	token := *created
+	s.client.SetAPIKey(string(token))
+
+	if opts.Persist != nil {
+		if err := opts.Persist(ctx, token); err != nil {
+			s.client.setProvider(previous)
+			if _, delErr := s.Delete(ctx, token); delErr != nil {
+				return "", fmt.Errorf("persisting token: %w (deleting the new token also failed: %s)", err, delErr)
+			}
+			return "", fmt.Errorf("persisting token: %w", err)
+		}
+	}
+
+	if opts.GracePeriod > 0 {
+		timer := time.NewTimer(opts.GracePeriod)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return token, fmt.Errorf("old token was not deleted: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	if _, err := s.Delete(ctx, old); err != nil {
+		return token, fmt.Errorf("deleting old token: %w", err)
+	}
+	return token, nil
+}
+
+// RevokeAllExcept deletes every token other than keep and the token the
+// client is authenticated with, unlike DeleteAll, which would lock the client
+// out. It returns the tokens it deleted, also when it fails part way.
+func (s *APITokensService) RevokeAllExcept(ctx context.Context, keep APIToken) ([]APIToken, error) {
+	tokens, _, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.client.APIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := APIToken(key)
+	var revoked []APIToken
+	for _, t := range tokens {
+		if t == nil || *t == keep || *t == current {
+			continue
+		}
+		if _, err := s.Delete(ctx, *t); err != nil {
+			return revoked, fmt.Errorf("deleting token: %w", err)
+		}
+		revoked = append(revoked, *t)
+	}
+	return revoked, nil
+}
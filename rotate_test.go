@@ -0,0 +1,99 @@
+package swarm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+// newRotateServer returns a server storing the given tokens and a client using
+// the first of them
+func newRotateServer(t *testing.T, tokens ...swarm.APIToken) (*swarmtest.Server, *swarm.Client) {
+	server := swarmtest.NewServer()
+	t.Cleanup(server.Close)
+	for _, token := range tokens {
+		server.AddAPIToken(token)
+	}
+	client := server.Client()
+	client.SetAPIKey(string(tokens[0]))
+	return server, client
+}
+
+func TestAPITokens_Rotate(t *testing.T) {
+	server, client := newRotateServer(t, "TESTAPITOKEN", "OTHER")
+	ctx := context.Background()
+
+	var persisted swarm.APIToken
+	token, err := client.APITokens.Rotate(ctx, &swarm.RotateOptions{
+		Persist: func(ctx context.Context, token swarm.APIToken) error {
+			require.Equal(t, string(token), apiKey(t, client))
+			persisted = token
+			return nil
+		},
+		GracePeriod: time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.Equal(t, token, persisted)
+	require.Equal(t, string(token), apiKey(t, client))
+	require.Equal(t, []swarm.APIToken{"OTHER", token}, server.APITokens())
+}
+
+func TestAPITokens_RotatePersistFails(t *testing.T) {
+	server, client := newRotateServer(t, "TESTAPITOKEN")
+	ctx := context.Background()
+
+	failed := errors.New("secret store is down")
+	_, err := client.APITokens.Rotate(ctx, &swarm.RotateOptions{
+		Persist: func(context.Context, swarm.APIToken) error { return failed },
+	})
+	require.True(t, errors.Is(err, failed))
+	require.Equal(t, "TESTAPITOKEN", apiKey(t, client))
+	require.Equal(t, []swarm.APIToken{"TESTAPITOKEN"}, server.APITokens())
+}
+
+func TestAPITokens_RotateCanceled(t *testing.T) {
+	server, client := newRotateServer(t, "TESTAPITOKEN")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	token, err := client.APITokens.Rotate(ctx, &swarm.RotateOptions{
+		Persist: func(context.Context, swarm.APIToken) error {
+			cancel()
+			return nil
+		},
+		GracePeriod: time.Hour,
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Equal(t, string(token), apiKey(t, client))
+	require.Equal(t, []swarm.APIToken{"TESTAPITOKEN", token}, server.APITokens())
+}
+
+func TestAPITokens_RotateCredentialsFail(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client(swarm.WithCredentials(swarm.EnvCredentials("SWARM_TEST_UNSET_KEY")))
+
+	_, err := client.APITokens.Rotate(context.Background(), nil)
+	require.EqualError(t, err, "environment variable SWARM_TEST_UNSET_KEY is not set")
+	require.Zero(t, server.Writes())
+}
+
+func TestAPITokens_RevokeAllExcept(t *testing.T) {
+	server, client := newRotateServer(t, "TESTAPITOKEN", "A", "B", "C")
+
+	revoked, err := client.APITokens.RevokeAllExcept(context.Background(), "B")
+	require.NoError(t, err)
+	require.Equal(t, []swarm.APIToken{"A", "C"}, revoked)
+	require.Equal(t, []swarm.APIToken{"TESTAPITOKEN", "B"}, server.APITokens())
+}
+
+func apiKey(t *testing.T, client *swarm.Client) string {
+	key, err := client.APIKey(context.Background())
+	require.NoError(t, err)
+	return key
+}
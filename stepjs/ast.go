@@ -0,0 +1,254 @@
+package stepjs
+
+// Node is implemented by every statement and expression in a parsed program
+type Node interface {
+	Position() Pos
+}
+
+// Stmt is a statement node
+type Stmt interface {
+	Node
+	stmt()
+}
+
+// Expr is an expression node
+type Expr interface {
+	Node
+	expr()
+}
+
+// Program is a parsed step function. Its body runs as if it were wrapped in
+// a function that receives the message as its only argument.
+type Program struct {
+	Body []Stmt
+}
+
+// Statements
+
+// VarDecl is a var, let or const declaration with one or more declarators
+type VarDecl struct {
+	Pos   Pos
+	Kind  string
+	Decls []*Declarator
+}
+
+// Declarator is a single name = value pair in a VarDecl
+type Declarator struct {
+	Pos  Pos
+	Name string
+	Init Expr
+}
+
+// ReturnStmt returns from the step function or an arrow function body
+type ReturnStmt struct {
+	Pos   Pos
+	Value Expr
+}
+
+// IfStmt is an if statement with an optional else branch
+type IfStmt struct {
+	Pos  Pos
+	Cond Expr
+	Then Stmt
+	Else Stmt
+}
+
+// ForOfStmt iterates the values of an array, or the characters of a string
+type ForOfStmt struct {
+	Pos  Pos
+	Kind string
+	Name string
+	Iter Expr
+	Body Stmt
+}
+
+// BlockStmt is a braced list of statements with its own scope
+type BlockStmt struct {
+	Pos  Pos
+	Body []Stmt
+}
+
+// ExprStmt is an expression evaluated for its side effects
+type ExprStmt struct {
+	Pos Pos
+	X   Expr
+}
+
+// BreakStmt exits the innermost loop
+type BreakStmt struct {
+	Pos Pos
+}
+
+// ContinueStmt skips to the next iteration of the innermost loop
+type ContinueStmt struct {
+	Pos Pos
+}
+
+// EmptyStmt is a lone semicolon
+type EmptyStmt struct {
+	Pos Pos
+}
+
+// Expressions
+
+// Ident is a reference to a variable
+type Ident struct {
+	Pos  Pos
+	Name string
+}
+
+// Literal is a number, string, boolean, null or undefined literal
+type Literal struct {
+	Pos   Pos
+	Value interface{}
+}
+
+// TemplateLit is a backtick string. Quasis always has one more element than
+// Exprs.
+type TemplateLit struct {
+	Pos    Pos
+	Quasis []string
+	Exprs  []Expr
+}
+
+// ArrayLit is an array literal
+type ArrayLit struct {
+	Pos   Pos
+	Elems []Expr
+}
+
+// ObjectLit is an object literal
+type ObjectLit struct {
+	Pos   Pos
+	Props []*Property
+}
+
+// Property is a key: value pair in an ObjectLit
+type Property struct {
+	Pos   Pos
+	Key   string
+	Value Expr
+}
+
+// MemberExpr is a property access, either obj.name or obj[expr]. For the dot
+// form Property is a string Literal.
+type MemberExpr struct {
+	Pos      Pos
+	Object   Expr
+	Property Expr
+	Computed bool
+	Optional bool
+}
+
+// CallExpr is a function or method call
+type CallExpr struct {
+	Pos      Pos
+	Callee   Expr
+	Args     []Expr
+	Optional bool
+}
+
+// UnaryExpr is a prefix operator: !, -, +, typeof or delete
+type UnaryExpr struct {
+	Pos Pos
+	Op  string
+	X   Expr
+}
+
+// UpdateExpr is ++ or -- in prefix or postfix position
+type UpdateExpr struct {
+	Pos    Pos
+	Op     string
+	Prefix bool
+	X      Expr
+}
+
+// BinaryExpr is an arithmetic or comparison operator
+type BinaryExpr struct {
+	Pos Pos
+	Op  string
+	X   Expr
+	Y   Expr
+}
+
+// LogicalExpr is a short circuiting &&, || or ?? operator
+type LogicalExpr struct {
+	Pos Pos
+	Op  string
+	X   Expr
+	Y   Expr
+}
+
+// CondExpr is the ternary operator
+type CondExpr struct {
+	Pos  Pos
+	Test Expr
+	Cons Expr
+	Alt  Expr
+}
+
+// AssignExpr is = or a compound assignment such as +=
+type AssignExpr struct {
+	Pos    Pos
+	Op     string
+	Target Expr
+	Value  Expr
+}
+
+// ArrowFunc is an arrow function. Body is either an Expr or a *BlockStmt.
+type ArrowFunc struct {
+	Pos    Pos
+	Params []string
+	Body   Node
+}
+
+func (n *VarDecl) Position() Pos      { return n.Pos }
+func (n *Declarator) Position() Pos   { return n.Pos }
+func (n *ReturnStmt) Position() Pos   { return n.Pos }
+func (n *IfStmt) Position() Pos       { return n.Pos }
+func (n *ForOfStmt) Position() Pos    { return n.Pos }
+func (n *BlockStmt) Position() Pos    { return n.Pos }
+func (n *ExprStmt) Position() Pos     { return n.Pos }
+func (n *BreakStmt) Position() Pos    { return n.Pos }
+func (n *ContinueStmt) Position() Pos { return n.Pos }
+func (n *EmptyStmt) Position() Pos    { return n.Pos }
+func (n *Ident) Position() Pos        { return n.Pos }
+func (n *Literal) Position() Pos      { return n.Pos }
+func (n *TemplateLit) Position() Pos  { return n.Pos }
+func (n *ArrayLit) Position() Pos     { return n.Pos }
+func (n *ObjectLit) Position() Pos    { return n.Pos }
+func (n *Property) Position() Pos     { return n.Pos }
+func (n *MemberExpr) Position() Pos   { return n.Pos }
+func (n *CallExpr) Position() Pos     { return n.Pos }
+func (n *UnaryExpr) Position() Pos    { return n.Pos }
+func (n *UpdateExpr) Position() Pos   { return n.Pos }
+func (n *BinaryExpr) Position() Pos   { return n.Pos }
+func (n *LogicalExpr) Position() Pos  { return n.Pos }
+func (n *CondExpr) Position() Pos     { return n.Pos }
+func (n *AssignExpr) Position() Pos   { return n.Pos }
+func (n *ArrowFunc) Position() Pos    { return n.Pos }
+
+func (*VarDecl) stmt()      {}
+func (*ReturnStmt) stmt()   {}
+func (*IfStmt) stmt()       {}
+func (*ForOfStmt) stmt()    {}
+func (*BlockStmt) stmt()    {}
+func (*ExprStmt) stmt()     {}
+func (*BreakStmt) stmt()    {}
+func (*ContinueStmt) stmt() {}
+func (*EmptyStmt) stmt()    {}
+
+func (*Ident) expr()       {}
+func (*Literal) expr()     {}
+func (*TemplateLit) expr() {}
+func (*ArrayLit) expr()    {}
+func (*ObjectLit) expr()   {}
+func (*MemberExpr) expr()  {}
+func (*CallExpr) expr()    {}
+func (*UnaryExpr) expr()   {}
+func (*UpdateExpr) expr()  {}
+func (*BinaryExpr) expr()  {}
+func (*LogicalExpr) expr() {}
+func (*CondExpr) expr()    {}
+func (*AssignExpr) expr()  {}
+func (*ArrowFunc) expr()   {}
@@ -0,0 +1,724 @@
+package stepjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// globalNames lists the identifiers every step function can use without
+// declaring them
+var globalNames = []string{
+	"Array", "Boolean", "JSON", "Math", "NaN", "Infinity", "Number", "Object", "String",
+	"isNaN", "parseFloat", "parseInt",
+}
+
+// IsGlobal reports whether name is a built in global available to step
+// functions, such as Math or JSON.
+func IsGlobal(name string) bool {
+	for _, g := range globalNames {
+		if g == name {
+			return true
+		}
+	}
+	return false
+}
+
+func native(name string, fn func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error)) *nativeFunc {
+	return &nativeFunc{name: name, fn: fn}
+}
+
+// pure wraps a built in that only depends on its arguments
+func pure(name string, fn func(args []interface{}) interface{}) *nativeFunc {
+	return native(name, func(_ *interp, _ interface{}, args []interface{}, _ Pos) (interface{}, error) {
+		return fn(args), nil
+	})
+}
+
+func arg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return Undefined
+}
+
+// globalScope builds a fresh global scope for each run so that a function
+// mutating a built in object cannot leak state into the next message
+func globalScope() *scope {
+	g := newScope(nil)
+	define := func(name string, v interface{}) {
+		g.vars[name] = &binding{value: v, constant: true}
+	}
+
+	define("NaN", math.NaN())
+	define("Infinity", math.Inf(1))
+
+	mathObj := newObject()
+	for name, fn := range map[string]func(float64) float64{
+		"abs":   math.Abs,
+		"ceil":  math.Ceil,
+		"floor": math.Floor,
+		"round": func(f float64) float64 { return math.Floor(f + 0.5) },
+		"sqrt":  math.Sqrt,
+		"trunc": math.Trunc,
+	} {
+		fn := fn
+		mathObj.set(name, pure("Math."+name, func(args []interface{}) interface{} {
+			return fn(toNumber(arg(args, 0)))
+		}))
+	}
+	mathObj.set("pow", pure("Math.pow", func(args []interface{}) interface{} {
+		return math.Pow(toNumber(arg(args, 0)), toNumber(arg(args, 1)))
+	}))
+	mathObj.set("min", pure("Math.min", func(args []interface{}) interface{} {
+		r := math.Inf(1)
+		for _, a := range args {
+			r = math.Min(r, toNumber(a))
+		}
+		return r
+	}))
+	mathObj.set("max", pure("Math.max", func(args []interface{}) interface{} {
+		r := math.Inf(-1)
+		for _, a := range args {
+			r = math.Max(r, toNumber(a))
+		}
+		return r
+	}))
+	mathObj.set("PI", math.Pi)
+	define("Math", mathObj)
+
+	jsonObj := newObject()
+	jsonObj.set("stringify", native("JSON.stringify", func(_ *interp, _ interface{}, args []interface{}, pos Pos) (interface{}, error) {
+		v := arg(args, 0)
+		if v == Undefined {
+			return Undefined, nil
+		}
+		b, err := json.Marshal(toGo(v))
+		if err != nil {
+			return nil, &Error{Pos: pos, Msg: err.Error()}
+		}
+		return string(b), nil
+	}))
+	jsonObj.set("parse", native("JSON.parse", func(_ *interp, _ interface{}, args []interface{}, pos Pos) (interface{}, error) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(toString(arg(args, 0))), &v); err != nil {
+			return nil, &Error{Pos: pos, Msg: fmt.Sprintf("JSON.parse: %s", err)}
+		}
+		return fromGo(v)
+	}))
+	define("JSON", jsonObj)
+
+	objectObj := newObject()
+	objectObj.set("keys", pure("Object.keys", func(args []interface{}) interface{} {
+		a := &array{}
+		if o, ok := arg(args, 0).(*object); ok {
+			for _, k := range o.keys {
+				a.elems = append(a.elems, k)
+			}
+		}
+		return a
+	}))
+	objectObj.set("values", pure("Object.values", func(args []interface{}) interface{} {
+		a := &array{}
+		if o, ok := arg(args, 0).(*object); ok {
+			for _, k := range o.keys {
+				a.elems = append(a.elems, o.vals[k])
+			}
+		}
+		return a
+	}))
+	objectObj.set("entries", pure("Object.entries", func(args []interface{}) interface{} {
+		a := &array{}
+		if o, ok := arg(args, 0).(*object); ok {
+			for _, k := range o.keys {
+				a.elems = append(a.elems, &array{elems: []interface{}{k, o.vals[k]}})
+			}
+		}
+		return a
+	}))
+	objectObj.set("assign", native("Object.assign", func(_ *interp, _ interface{}, args []interface{}, pos Pos) (interface{}, error) {
+		target, ok := arg(args, 0).(*object)
+		if !ok {
+			return nil, &Error{Pos: pos, Msg: "Object.assign target must be an object"}
+		}
+		for _, src := range args[1:] {
+			if o, ok := src.(*object); ok {
+				for _, k := range o.keys {
+					target.set(k, o.vals[k])
+				}
+			}
+		}
+		return target, nil
+	}))
+	define("Object", objectObj)
+
+	arrayObj := newObject()
+	arrayObj.set("isArray", pure("Array.isArray", func(args []interface{}) interface{} {
+		_, ok := arg(args, 0).(*array)
+		return ok
+	}))
+	define("Array", arrayObj)
+
+	define("String", pure("String", func(args []interface{}) interface{} {
+		if len(args) == 0 {
+			return ""
+		}
+		return toString(args[0])
+	}))
+	define("Number", pure("Number", func(args []interface{}) interface{} {
+		if len(args) == 0 {
+			return 0.0
+		}
+		return toNumber(args[0])
+	}))
+	define("Boolean", pure("Boolean", func(args []interface{}) interface{} {
+		return truthy(arg(args, 0))
+	}))
+	define("isNaN", pure("isNaN", func(args []interface{}) interface{} {
+		return math.IsNaN(toNumber(arg(args, 0)))
+	}))
+	define("parseFloat", pure("parseFloat", func(args []interface{}) interface{} {
+		return parseFloatPrefix(strings.TrimSpace(toString(arg(args, 0))))
+	}))
+	define("parseInt", pure("parseInt", func(args []interface{}) interface{} {
+		radix := 10
+		if r := arg(args, 1); r != Undefined {
+			radix = int(toNumber(r))
+		}
+		return parseIntPrefix(strings.TrimSpace(toString(arg(args, 0))), radix)
+	}))
+	return g
+}
+
+func parseFloatPrefix(s string) float64 {
+	end := 0
+	for i := 1; i <= len(s); i++ {
+		if _, err := strconv.ParseFloat(s[:i], 64); err == nil {
+			end = i
+		}
+	}
+	if end == 0 {
+		return math.NaN()
+	}
+	f, _ := strconv.ParseFloat(s[:end], 64)
+	return f
+}
+
+func parseIntPrefix(s string, radix int) float64 {
+	if radix < 2 || radix > 36 {
+		return math.NaN()
+	}
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if radix == 16 {
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	}
+	end := 0
+	for end < len(s) {
+		if _, err := strconv.ParseInt(s[end:end+1], radix, 64); err != nil {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		return math.NaN()
+	}
+	n, err := strconv.ParseInt(s[:end], radix, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	if neg {
+		n = -n
+	}
+	return float64(n)
+}
+
+var objectMethods = map[string]*nativeFunc{
+	"hasOwnProperty": native("hasOwnProperty", func(_ *interp, this interface{}, args []interface{}, _ Pos) (interface{}, error) {
+		o, ok := this.(*object)
+		if !ok {
+			return false, nil
+		}
+		_, has := o.get(toString(arg(args, 0)))
+		return has, nil
+	}),
+}
+
+var numberMethods = map[string]*nativeFunc{
+	"toFixed": native("toFixed", func(_ *interp, this interface{}, args []interface{}, _ Pos) (interface{}, error) {
+		digits := 0
+		if d := arg(args, 0); d != Undefined {
+			digits = int(toNumber(d))
+		}
+		return strconv.FormatFloat(toNumber(this), 'f', digits, 64), nil
+	}),
+	"toString": native("toString", func(_ *interp, this interface{}, _ []interface{}, _ Pos) (interface{}, error) {
+		return toString(this), nil
+	}),
+}
+
+func thisString(this interface{}) string {
+	return toString(this)
+}
+
+// relIndex resolves a possibly negative index argument against a length, the
+// way slice and friends do
+func relIndex(v interface{}, length int, def int) int {
+	if v == Undefined {
+		return def
+	}
+	f := toNumber(v)
+	if math.IsNaN(f) {
+		return 0
+	}
+	i := int(math.Trunc(f))
+	if i < 0 {
+		i += length
+		if i < 0 {
+			i = 0
+		}
+	}
+	if i > length {
+		i = length
+	}
+	return i
+}
+
+func stringMethod(name string, fn func(s string, args []interface{}) interface{}) *nativeFunc {
+	return native(name, func(_ *interp, this interface{}, args []interface{}, _ Pos) (interface{}, error) {
+		return fn(thisString(this), args), nil
+	})
+}
+
+var stringMethods = map[string]*nativeFunc{
+	"charAt": stringMethod("charAt", func(s string, args []interface{}) interface{} {
+		r := []rune(s)
+		i := int(toNumber(arg(args, 0)))
+		if i < 0 || i >= len(r) {
+			return ""
+		}
+		return string(r[i])
+	}),
+	"concat": stringMethod("concat", func(s string, args []interface{}) interface{} {
+		for _, a := range args {
+			s += toString(a)
+		}
+		return s
+	}),
+	"endsWith": stringMethod("endsWith", func(s string, args []interface{}) interface{} {
+		return strings.HasSuffix(s, toString(arg(args, 0)))
+	}),
+	"includes": stringMethod("includes", func(s string, args []interface{}) interface{} {
+		return strings.Contains(s, toString(arg(args, 0)))
+	}),
+	"indexOf": stringMethod("indexOf", func(s string, args []interface{}) interface{} {
+		return float64(runeIndex(s, strings.Index(s, toString(arg(args, 0)))))
+	}),
+	"lastIndexOf": stringMethod("lastIndexOf", func(s string, args []interface{}) interface{} {
+		return float64(runeIndex(s, strings.LastIndex(s, toString(arg(args, 0)))))
+	}),
+	"padEnd": native("padEnd", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+		return pad(thisString(this), args, false, pos)
+	}),
+	"padStart": native("padStart", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+		return pad(thisString(this), args, true, pos)
+	}),
+	"repeat": native("repeat", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+		s := thisString(this)
+		n := toNumber(arg(args, 0))
+		if n < 0 || math.IsInf(n, 0) {
+			return nil, &Error{Pos: pos, Msg: fmt.Sprintf("RangeError: invalid count value %s", toString(n))}
+		}
+		if s == "" || !(n >= 1) {
+			return "", nil
+		}
+		if n > float64(maxStringLength/len([]rune(s))) {
+			return nil, lengthError(pos)
+		}
+		return strings.Repeat(s, int(n)), nil
+	}),
+	"replace": stringMethod("replace", func(s string, args []interface{}) interface{} {
+		return strings.Replace(s, toString(arg(args, 0)), toString(arg(args, 1)), 1)
+	}),
+	"replaceAll": stringMethod("replaceAll", func(s string, args []interface{}) interface{} {
+		return strings.ReplaceAll(s, toString(arg(args, 0)), toString(arg(args, 1)))
+	}),
+	"slice": stringMethod("slice", func(s string, args []interface{}) interface{} {
+		r := []rune(s)
+		start := relIndex(arg(args, 0), len(r), 0)
+		end := relIndex(arg(args, 1), len(r), len(r))
+		if start >= end {
+			return ""
+		}
+		return string(r[start:end])
+	}),
+	"split": stringMethod("split", func(s string, args []interface{}) interface{} {
+		a := &array{}
+		sep := arg(args, 0)
+		if sep == Undefined {
+			a.elems = append(a.elems, s)
+			return a
+		}
+		for _, part := range strings.Split(s, toString(sep)) {
+			a.elems = append(a.elems, part)
+		}
+		return a
+	}),
+	"startsWith": stringMethod("startsWith", func(s string, args []interface{}) interface{} {
+		return strings.HasPrefix(s, toString(arg(args, 0)))
+	}),
+	"substring": stringMethod("substring", func(s string, args []interface{}) interface{} {
+		r := []rune(s)
+		clamp := func(v interface{}, def int) int {
+			if v == Undefined {
+				return def
+			}
+			i := int(toNumber(v))
+			if i < 0 {
+				return 0
+			}
+			if i > len(r) {
+				return len(r)
+			}
+			return i
+		}
+		start, end := clamp(arg(args, 0), 0), clamp(arg(args, 1), len(r))
+		if start > end {
+			start, end = end, start
+		}
+		return string(r[start:end])
+	}),
+	"toLowerCase": stringMethod("toLowerCase", func(s string, _ []interface{}) interface{} {
+		return strings.ToLower(s)
+	}),
+	"toString": stringMethod("toString", func(s string, _ []interface{}) interface{} {
+		return s
+	}),
+	"toUpperCase": stringMethod("toUpperCase", func(s string, _ []interface{}) interface{} {
+		return strings.ToUpper(s)
+	}),
+	"trim": stringMethod("trim", func(s string, _ []interface{}) interface{} {
+		return strings.TrimSpace(s)
+	}),
+	"trimEnd": stringMethod("trimEnd", func(s string, _ []interface{}) interface{} {
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	}),
+	"trimStart": stringMethod("trimStart", func(s string, _ []interface{}) interface{} {
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	}),
+}
+
+func runeIndex(s string, byteIndex int) int {
+	if byteIndex < 0 {
+		return -1
+	}
+	return len([]rune(s[:byteIndex]))
+}
+
+func pad(s string, args []interface{}, start bool, pos Pos) (string, error) {
+	width := toNumber(arg(args, 0))
+	if width > maxStringLength {
+		return "", lengthError(pos)
+	}
+	fill := " "
+	if f := arg(args, 1); f != Undefined {
+		fill = toString(f)
+	}
+	if !(width > 0) || fill == "" {
+		return s, nil
+	}
+	n := int(width) - len([]rune(s))
+	if n <= 0 {
+		return s, nil
+	}
+	padding := []rune(strings.Repeat(fill, n/len([]rune(fill))+1))[:n]
+	if start {
+		return string(padding) + s, nil
+	}
+	return s + string(padding), nil
+}
+
+// lengthError is the RangeError of a string that would exceed
+// maxStringLength
+func lengthError(pos Pos) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf("RangeError: string longer than %d characters", maxStringLength)}
+}
+
+func thisArray(this interface{}, name string, pos Pos) (*array, error) {
+	a, ok := this.(*array)
+	if !ok {
+		return nil, &Error{Pos: pos, Msg: fmt.Sprintf("%s called on a non array", name)}
+	}
+	return a, nil
+}
+
+func callback(args []interface{}, name string, pos Pos) (function, error) {
+	fn, ok := arg(args, 0).(function)
+	if !ok {
+		return nil, &Error{Pos: pos, Msg: fmt.Sprintf("%s expects a function argument", name)}
+	}
+	return fn, nil
+}
+
+// iterate calls fn for every element of the receiver array, stopping early
+// when visit returns false
+func iterate(in *interp, this interface{}, args []interface{}, name string, pos Pos, visit func(i int, elem, result interface{}) bool) error {
+	a, err := thisArray(this, name, pos)
+	if err != nil {
+		return err
+	}
+	fn, err := callback(args, name, pos)
+	if err != nil {
+		return err
+	}
+	elems := append([]interface{}(nil), a.elems...)
+	for i, e := range elems {
+		r, err := fn.call(in, nil, []interface{}{e, float64(i), a}, pos)
+		if err != nil {
+			return err
+		}
+		if !visit(i, e, r) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// arrayMethods is populated in init because the callback based methods
+// reach back into the evaluator, which itself looks up arrayMethods
+var arrayMethods map[string]*nativeFunc
+
+func init() {
+	arrayMethods = map[string]*nativeFunc{
+		"concat": native("concat", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "concat", pos)
+			if err != nil {
+				return nil, err
+			}
+			out := &array{elems: append([]interface{}(nil), a.elems...)}
+			for _, v := range args {
+				if other, ok := v.(*array); ok {
+					out.elems = append(out.elems, other.elems...)
+				} else {
+					out.elems = append(out.elems, v)
+				}
+			}
+			return out, nil
+		}),
+		"every": native("every", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			result := true
+			err := iterate(in, this, args, "every", pos, func(_ int, _, r interface{}) bool {
+				result = truthy(r)
+				return result
+			})
+			return result, err
+		}),
+		"filter": native("filter", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			out := &array{}
+			err := iterate(in, this, args, "filter", pos, func(_ int, e, r interface{}) bool {
+				if truthy(r) {
+					out.elems = append(out.elems, e)
+				}
+				return true
+			})
+			return out, err
+		}),
+		"find": native("find", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			var found interface{} = Undefined
+			err := iterate(in, this, args, "find", pos, func(_ int, e, r interface{}) bool {
+				if truthy(r) {
+					found = e
+					return false
+				}
+				return true
+			})
+			return found, err
+		}),
+		"findIndex": native("findIndex", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			found := -1.0
+			err := iterate(in, this, args, "findIndex", pos, func(i int, _, r interface{}) bool {
+				if truthy(r) {
+					found = float64(i)
+					return false
+				}
+				return true
+			})
+			return found, err
+		}),
+		"forEach": native("forEach", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			err := iterate(in, this, args, "forEach", pos, func(int, interface{}, interface{}) bool { return true })
+			return Undefined, err
+		}),
+		"includes": native("includes", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "includes", pos)
+			if err != nil {
+				return nil, err
+			}
+			want := arg(args, 0)
+			for _, e := range a.elems {
+				if strictEquals(e, want) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}),
+		"indexOf": native("indexOf", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "indexOf", pos)
+			if err != nil {
+				return nil, err
+			}
+			want := arg(args, 0)
+			for i, e := range a.elems {
+				if strictEquals(e, want) {
+					return float64(i), nil
+				}
+			}
+			return -1.0, nil
+		}),
+		"join": native("join", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "join", pos)
+			if err != nil {
+				return nil, err
+			}
+			sep := ","
+			if s := arg(args, 0); s != Undefined {
+				sep = toString(s)
+			}
+			parts := make([]string, len(a.elems))
+			for i, e := range a.elems {
+				if e != nil && e != Undefined {
+					parts[i] = toString(e)
+				}
+			}
+			return strings.Join(parts, sep), nil
+		}),
+		"map": native("map", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			out := &array{}
+			err := iterate(in, this, args, "map", pos, func(_ int, _, r interface{}) bool {
+				out.elems = append(out.elems, r)
+				return true
+			})
+			return out, err
+		}),
+		"pop": native("pop", func(_ *interp, this interface{}, _ []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "pop", pos)
+			if err != nil {
+				return nil, err
+			}
+			if len(a.elems) == 0 {
+				return Undefined, nil
+			}
+			last := a.elems[len(a.elems)-1]
+			a.elems = a.elems[:len(a.elems)-1]
+			return last, nil
+		}),
+		"push": native("push", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "push", pos)
+			if err != nil {
+				return nil, err
+			}
+			a.elems = append(a.elems, args...)
+			return float64(len(a.elems)), nil
+		}),
+		"reduce": native("reduce", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "reduce", pos)
+			if err != nil {
+				return nil, err
+			}
+			fn, err := callback(args, "reduce", pos)
+			if err != nil {
+				return nil, err
+			}
+			elems := a.elems
+			var acc interface{}
+			if len(args) > 1 {
+				acc = args[1]
+			} else {
+				if len(elems) == 0 {
+					return nil, &Error{Pos: pos, Msg: "reduce of empty array with no initial value"}
+				}
+				acc, elems = elems[0], elems[1:]
+			}
+			for i, e := range elems {
+				acc, err = fn.call(in, nil, []interface{}{acc, e, float64(i), a}, pos)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return acc, nil
+		}),
+		"reverse": native("reverse", func(_ *interp, this interface{}, _ []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "reverse", pos)
+			if err != nil {
+				return nil, err
+			}
+			for i, j := 0, len(a.elems)-1; i < j; i, j = i+1, j-1 {
+				a.elems[i], a.elems[j] = a.elems[j], a.elems[i]
+			}
+			return a, nil
+		}),
+		"shift": native("shift", func(_ *interp, this interface{}, _ []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "shift", pos)
+			if err != nil {
+				return nil, err
+			}
+			if len(a.elems) == 0 {
+				return Undefined, nil
+			}
+			first := a.elems[0]
+			a.elems = a.elems[1:]
+			return first, nil
+		}),
+		"slice": native("slice", func(_ *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "slice", pos)
+			if err != nil {
+				return nil, err
+			}
+			start := relIndex(arg(args, 0), len(a.elems), 0)
+			end := relIndex(arg(args, 1), len(a.elems), len(a.elems))
+			out := &array{}
+			if start < end {
+				out.elems = append(out.elems, a.elems[start:end]...)
+			}
+			return out, nil
+		}),
+		"some": native("some", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			result := false
+			err := iterate(in, this, args, "some", pos, func(_ int, _, r interface{}) bool {
+				result = truthy(r)
+				return !result
+			})
+			return result, err
+		}),
+		"sort": native("sort", func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+			a, err := thisArray(this, "sort", pos)
+			if err != nil {
+				return nil, err
+			}
+			fn, _ := arg(args, 0).(function)
+			var sortErr error
+			sort.SliceStable(a.elems, func(i, j int) bool {
+				if sortErr != nil {
+					return false
+				}
+				if fn == nil {
+					return toString(a.elems[i]) < toString(a.elems[j])
+				}
+				r, err := fn.call(in, nil, []interface{}{a.elems[i], a.elems[j]}, pos)
+				if err != nil {
+					sortErr = err
+					return false
+				}
+				return toNumber(r) < 0
+			})
+			return a, sortErr
+		}),
+	}
+}
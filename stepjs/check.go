@@ -0,0 +1,236 @@
+package stepjs
+
+// FieldRef is a static property path read from or written to the message,
+// such as message.user.name
+type FieldRef struct {
+	Pos  Pos
+	Path []string
+	// Assigned is set when the path is the target of an assignment or
+	// delete, so its last element does not need to exist beforehand
+	Assigned bool
+}
+
+// Analysis is the result of statically checking a program
+type Analysis struct {
+	// Undeclared lists every identifier read or written without being
+	// declared, other than message and the built in globals
+	Undeclared []*Ident
+	// Returns is true when the program body, outside of nested arrow
+	// functions, contains a return statement with a value
+	Returns bool
+	// Fields lists the static paths read from message
+	Fields []FieldRef
+}
+
+type checkScope struct {
+	names  map[string]bool
+	parent *checkScope
+}
+
+func (s *checkScope) has(name string) bool {
+	for sc := s; sc != nil; sc = sc.parent {
+		if sc.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+type checker struct {
+	a     *Analysis
+	depth int
+}
+
+// Analyze walks the program without running it
+func Analyze(p *Program) *Analysis {
+	c := &checker{a: &Analysis{}}
+	root := &checkScope{names: map[string]bool{"message": true}}
+	c.stmts(p.Body, root)
+	return c.a
+}
+
+// declared collects the names declared directly in a statement list, so a
+// function may refer to a name before the line that declares it
+func declared(stmts []Stmt, sc *checkScope) *checkScope {
+	inner := &checkScope{names: map[string]bool{}, parent: sc}
+	for _, s := range stmts {
+		if d, ok := s.(*VarDecl); ok {
+			for _, decl := range d.Decls {
+				inner.names[decl.Name] = true
+			}
+		}
+	}
+	return inner
+}
+
+func (c *checker) stmts(stmts []Stmt, sc *checkScope) {
+	inner := declared(stmts, sc)
+	for _, s := range stmts {
+		c.stmt(s, inner)
+	}
+}
+
+func (c *checker) stmt(s Stmt, sc *checkScope) {
+	switch s := s.(type) {
+	case *ExprStmt:
+		c.expr(s.X, sc)
+	case *VarDecl:
+		for _, d := range s.Decls {
+			if d.Init != nil {
+				c.expr(d.Init, sc)
+			}
+		}
+	case *ReturnStmt:
+		if s.Value != nil {
+			if c.depth == 0 {
+				c.a.Returns = true
+			}
+			c.expr(s.Value, sc)
+		}
+	case *IfStmt:
+		c.expr(s.Cond, sc)
+		c.stmt(s.Then, sc)
+		if s.Else != nil {
+			c.stmt(s.Else, sc)
+		}
+	case *BlockStmt:
+		c.stmts(s.Body, sc)
+	case *ForOfStmt:
+		c.expr(s.Iter, sc)
+		body := &checkScope{names: map[string]bool{s.Name: true}, parent: sc}
+		c.stmt(s.Body, body)
+	}
+}
+
+func (c *checker) expr(x Expr, sc *checkScope) {
+	switch x := x.(type) {
+	case *Ident:
+		if !sc.has(x.Name) && !IsGlobal(x.Name) {
+			c.a.Undeclared = append(c.a.Undeclared, x)
+		}
+	case *TemplateLit:
+		for _, e := range x.Exprs {
+			c.expr(e, sc)
+		}
+	case *ArrayLit:
+		for _, e := range x.Elems {
+			c.expr(e, sc)
+		}
+	case *ObjectLit:
+		for _, p := range x.Props {
+			c.expr(p.Value, sc)
+		}
+	case *MemberExpr:
+		c.field(x, sc, false)
+	case *CallExpr:
+		c.expr(x.Callee, sc)
+		for _, a := range x.Args {
+			c.expr(a, sc)
+		}
+	case *UnaryExpr:
+		if m, ok := x.X.(*MemberExpr); ok && x.Op == "delete" {
+			c.field(m, sc, true)
+			return
+		}
+		if id, ok := x.X.(*Ident); ok && x.Op == "typeof" && !sc.has(id.Name) {
+			// typeof is the one safe way to probe an undeclared name
+			return
+		}
+		c.expr(x.X, sc)
+	case *UpdateExpr:
+		c.expr(x.X, sc)
+	case *BinaryExpr:
+		c.expr(x.X, sc)
+		c.expr(x.Y, sc)
+	case *LogicalExpr:
+		c.expr(x.X, sc)
+		c.expr(x.Y, sc)
+	case *CondExpr:
+		c.expr(x.Test, sc)
+		c.expr(x.Cons, sc)
+		c.expr(x.Alt, sc)
+	case *AssignExpr:
+		if m, ok := x.Target.(*MemberExpr); ok {
+			c.field(m, sc, true)
+		} else {
+			c.expr(x.Target, sc)
+		}
+		c.expr(x.Value, sc)
+	case *ArrowFunc:
+		fn := &checkScope{names: map[string]bool{}, parent: sc}
+		for _, p := range x.Params {
+			fn.names[p] = true
+		}
+		c.depth++
+		switch body := x.Body.(type) {
+		case *BlockStmt:
+			c.stmts(body.Body, fn)
+		case Expr:
+			c.expr(body, fn)
+		}
+		c.depth--
+	}
+}
+
+// field records static message paths and walks any sub expressions of a
+// member expression
+func (c *checker) field(m *MemberExpr, sc *checkScope, assigned bool) {
+	if path, ok := messagePath(m, sc); ok {
+		c.a.Fields = append(c.a.Fields, FieldRef{Pos: m.Pos, Path: path, Assigned: assigned})
+	}
+	for x := Expr(m); ; {
+		mem, ok := x.(*MemberExpr)
+		if !ok {
+			c.expr(x, sc)
+			return
+		}
+		if mem.Computed {
+			c.expr(mem.Property, sc)
+		}
+		x = mem.Object
+	}
+}
+
+// messagePath returns the dotted path of a member expression rooted at the
+// message variable. Segments beyond a computed access are dropped, and
+// optional chains are skipped entirely since they are written to tolerate
+// missing fields.
+func messagePath(m *MemberExpr, sc *checkScope) ([]string, bool) {
+	var rev []string
+	var x Expr = m
+	for {
+		mem, ok := x.(*MemberExpr)
+		if !ok {
+			break
+		}
+		if mem.Optional {
+			return nil, false
+		}
+		if mem.Computed {
+			rev = rev[:0]
+		} else {
+			rev = append(rev, mem.Property.(*Literal).Value.(string))
+		}
+		x = mem.Object
+	}
+
+	// a local variable named message shadows the step's argument
+	id, ok := x.(*Ident)
+	if !ok || id.Name != "message" || sc.declaresBelowRoot("message") || len(rev) == 0 {
+		return nil, false
+	}
+	path := make([]string, len(rev))
+	for i, p := range rev {
+		path[len(rev)-1-i] = p
+	}
+	return path, true
+}
+
+func (s *checkScope) declaresBelowRoot(name string) bool {
+	for sc := s; sc != nil && sc.parent != nil; sc = sc.parent {
+		if sc.names[name] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package stepjs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	prog, err := Parse(`
+		const limit = 10
+		const names = message.items.map(item => item.name.trim())
+		message.count = names.length
+		delete message.secret
+		if (typeof debug !== 'undefined') {
+			return total
+		}
+		return message.user?.id || message.meta[key].value || Math.min(limit, later)
+		var later = 1
+	`)
+	require.NoError(t, err)
+
+	a := Analyze(prog)
+	require.True(t, a.Returns)
+
+	var undeclared []string
+	for _, id := range a.Undeclared {
+		undeclared = append(undeclared, id.Name)
+	}
+	require.Equal(t, []string{"total", "key"}, undeclared)
+
+	var fields [][]string
+	var assigned []bool
+	for _, f := range a.Fields {
+		fields = append(fields, f.Path)
+		assigned = append(assigned, f.Assigned)
+	}
+	require.Equal(t, [][]string{{"items", "map"}, {"count"}, {"secret"}, {"meta"}}, fields)
+	require.Equal(t, []bool{false, true, true, false}, assigned)
+}
+
+func TestAnalyze_NestedReturnOnly(t *testing.T) {
+	prog, err := Parse(`message.items.forEach(item => { return item })`)
+	require.NoError(t, err)
+	require.False(t, Analyze(prog).Returns)
+}
@@ -0,0 +1,23 @@
+package stepjs
+
+import "fmt"
+
+// Pos is a line and column in the step function source, both starting at 1
+type Pos struct {
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Error is a syntax or runtime error in a step function
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %s: %s", e.Pos, e.Msg)
+}
@@ -0,0 +1,674 @@
+package stepjs
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// maxSteps bounds the work a single Run may do so a runaway function
+	// cannot hang a test
+	maxSteps = 1000000
+	// maxCallDepth bounds recursion through arrow functions
+	maxCallDepth = 200
+	// maxStringLength bounds the characters repeat and padding may produce
+	// so a function cannot exhaust memory with a single call
+	maxStringLength = 1 << 22
+)
+
+type binding struct {
+	value    interface{}
+	constant bool
+}
+
+type scope struct {
+	vars   map[string]*binding
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: map[string]*binding{}, parent: parent}
+}
+
+func (s *scope) lookup(name string) *binding {
+	for sc := s; sc != nil; sc = sc.parent {
+		if b, ok := sc.vars[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+type control int
+
+const (
+	ctrlNone control = iota
+	ctrlReturn
+	ctrlBreak
+	ctrlContinue
+)
+
+type interp struct {
+	steps int
+	depth int
+}
+
+type closure struct {
+	fn  *ArrowFunc
+	env *scope
+}
+
+func (c *closure) call(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+	in.depth++
+	defer func() { in.depth-- }()
+	if in.depth > maxCallDepth {
+		return nil, &Error{Pos: pos, Msg: "maximum call depth exceeded"}
+	}
+
+	env := newScope(c.env)
+	for i, name := range c.fn.Params {
+		var v interface{} = Undefined
+		if i < len(args) {
+			v = args[i]
+		}
+		env.vars[name] = &binding{value: v}
+	}
+
+	switch body := c.fn.Body.(type) {
+	case *BlockStmt:
+		ctrl, v, err := in.execList(body.Body, env)
+		if err != nil {
+			return nil, err
+		}
+		if ctrl == ctrlReturn {
+			return v, nil
+		}
+		return Undefined, nil
+	case Expr:
+		return in.eval(body, env)
+	}
+	return Undefined, nil
+}
+
+// Run executes the program with message bound to the message variable and
+// returns the value of its return statement converted to plain Go types.
+// The message is deep copied first, so the caller's value is never modified.
+// A program that finishes without returning yields Undefined.
+func (p *Program) Run(message interface{}) (interface{}, error) {
+	ret, _, err := p.run(message)
+	return ret, err
+}
+
+// RunMutating is like Run but also returns the message as the program left
+// it, which is what a transform that edits message in place produces.
+func (p *Program) RunMutating(message interface{}) (interface{}, interface{}, error) {
+	return p.run(message)
+}
+
+func (p *Program) run(message interface{}) (interface{}, interface{}, error) {
+	msg, err := fromGo(message)
+	if err != nil {
+		return nil, nil, fmt.Errorf("converting message: %w", err)
+	}
+
+	in := &interp{}
+	env := newScope(globalScope())
+	env.vars["message"] = &binding{value: msg}
+
+	ctrl, v, err := in.execList(p.Body, newScope(env))
+	if err != nil {
+		return nil, nil, err
+	}
+	// message may have been reassigned by the program
+	msg = env.vars["message"].value
+	switch ctrl {
+	case ctrlBreak, ctrlContinue:
+		return nil, nil, &Error{Pos: Pos{Line: 1, Column: 1}, Msg: "break or continue outside of a loop"}
+	case ctrlNone:
+		return Undefined, toGo(msg), nil
+	}
+	if v == Undefined {
+		return Undefined, toGo(msg), nil
+	}
+	return toGo(v), toGo(msg), nil
+}
+
+// Truthy reports whether a value returned by Run counts as true in
+// JavaScript
+func Truthy(v interface{}) bool {
+	return truthy(v)
+}
+
+func (in *interp) tick(pos Pos) error {
+	in.steps++
+	if in.steps > maxSteps {
+		return &Error{Pos: pos, Msg: "execution step limit exceeded"}
+	}
+	return nil
+}
+
+func (in *interp) execList(stmts []Stmt, env *scope) (control, interface{}, error) {
+	for _, s := range stmts {
+		ctrl, v, err := in.exec(s, env)
+		if err != nil || ctrl != ctrlNone {
+			return ctrl, v, err
+		}
+	}
+	return ctrlNone, nil, nil
+}
+
+func (in *interp) exec(s Stmt, env *scope) (control, interface{}, error) {
+	if err := in.tick(s.Position()); err != nil {
+		return ctrlNone, nil, err
+	}
+
+	switch s := s.(type) {
+	case *EmptyStmt:
+		return ctrlNone, nil, nil
+	case *ExprStmt:
+		_, err := in.eval(s.X, env)
+		return ctrlNone, nil, err
+	case *VarDecl:
+		for _, d := range s.Decls {
+			if _, exists := env.vars[d.Name]; exists && s.Kind != "var" {
+				return ctrlNone, nil, &Error{Pos: d.Pos, Msg: fmt.Sprintf("identifier %q has already been declared", d.Name)}
+			}
+			var v interface{} = Undefined
+			if d.Init != nil {
+				var err error
+				v, err = in.eval(d.Init, env)
+				if err != nil {
+					return ctrlNone, nil, err
+				}
+			}
+			env.vars[d.Name] = &binding{value: v, constant: s.Kind == "const"}
+		}
+		return ctrlNone, nil, nil
+	case *ReturnStmt:
+		if s.Value == nil {
+			return ctrlReturn, Undefined, nil
+		}
+		v, err := in.eval(s.Value, env)
+		if err != nil {
+			return ctrlNone, nil, err
+		}
+		return ctrlReturn, v, nil
+	case *IfStmt:
+		cond, err := in.eval(s.Cond, env)
+		if err != nil {
+			return ctrlNone, nil, err
+		}
+		if truthy(cond) {
+			return in.exec(s.Then, newScope(env))
+		}
+		if s.Else != nil {
+			return in.exec(s.Else, newScope(env))
+		}
+		return ctrlNone, nil, nil
+	case *BlockStmt:
+		return in.execList(s.Body, newScope(env))
+	case *ForOfStmt:
+		iter, err := in.eval(s.Iter, env)
+		if err != nil {
+			return ctrlNone, nil, err
+		}
+		var items []interface{}
+		switch t := iter.(type) {
+		case *array:
+			items = append(items, t.elems...)
+		case string:
+			for _, r := range t {
+				items = append(items, string(r))
+			}
+		default:
+			return ctrlNone, nil, &Error{Pos: s.Iter.Position(), Msg: fmt.Sprintf("%s is not iterable", describe(iter))}
+		}
+		for _, item := range items {
+			body := newScope(env)
+			body.vars[s.Name] = &binding{value: item, constant: s.Kind == "const"}
+			ctrl, v, err := in.exec(s.Body, body)
+			if err != nil {
+				return ctrlNone, nil, err
+			}
+			switch ctrl {
+			case ctrlReturn:
+				return ctrl, v, nil
+			case ctrlBreak:
+				return ctrlNone, nil, nil
+			}
+		}
+		return ctrlNone, nil, nil
+	case *BreakStmt:
+		return ctrlBreak, nil, nil
+	case *ContinueStmt:
+		return ctrlContinue, nil, nil
+	}
+	return ctrlNone, nil, &Error{Pos: s.Position(), Msg: fmt.Sprintf("unsupported statement %T", s)}
+}
+
+func (in *interp) eval(x Expr, env *scope) (interface{}, error) {
+	if err := in.tick(x.Position()); err != nil {
+		return nil, err
+	}
+
+	switch x := x.(type) {
+	case *Literal:
+		return x.Value, nil
+	case *Ident:
+		b := env.lookup(x.Name)
+		if b == nil {
+			return nil, &Error{Pos: x.Pos, Msg: fmt.Sprintf("%s is not defined", x.Name)}
+		}
+		return b.value, nil
+	case *TemplateLit:
+		var b strings.Builder
+		for i, q := range x.Quasis {
+			b.WriteString(q)
+			if i < len(x.Exprs) {
+				v, err := in.eval(x.Exprs[i], env)
+				if err != nil {
+					return nil, err
+				}
+				b.WriteString(toString(v))
+			}
+		}
+		return b.String(), nil
+	case *ArrayLit:
+		a := &array{}
+		for _, e := range x.Elems {
+			v, err := in.eval(e, env)
+			if err != nil {
+				return nil, err
+			}
+			a.elems = append(a.elems, v)
+		}
+		return a, nil
+	case *ObjectLit:
+		o := newObject()
+		for _, p := range x.Props {
+			v, err := in.eval(p.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			o.set(p.Key, v)
+		}
+		return o, nil
+	case *ArrowFunc:
+		return &closure{fn: x, env: env}, nil
+	case *MemberExpr:
+		obj, err := in.eval(x.Object, env)
+		if err != nil {
+			return nil, err
+		}
+		if x.Optional && (obj == nil || obj == Undefined) {
+			return Undefined, nil
+		}
+		key, err := in.propertyKey(x, env)
+		if err != nil {
+			return nil, err
+		}
+		return getProperty(obj, key, x.Pos)
+	case *CallExpr:
+		return in.call(x, env)
+	case *UnaryExpr:
+		return in.unary(x, env)
+	case *UpdateExpr:
+		return in.update(x, env)
+	case *BinaryExpr:
+		l, err := in.eval(x.X, env)
+		if err != nil {
+			return nil, err
+		}
+		r, err := in.eval(x.Y, env)
+		if err != nil {
+			return nil, err
+		}
+		return binaryOp(x.Op, l, r, x.Pos)
+	case *LogicalExpr:
+		l, err := in.eval(x.X, env)
+		if err != nil {
+			return nil, err
+		}
+		switch x.Op {
+		case "&&":
+			if !truthy(l) {
+				return l, nil
+			}
+		case "||":
+			if truthy(l) {
+				return l, nil
+			}
+		case "??":
+			if l != nil && l != Undefined {
+				return l, nil
+			}
+		}
+		return in.eval(x.Y, env)
+	case *CondExpr:
+		t, err := in.eval(x.Test, env)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(t) {
+			return in.eval(x.Cons, env)
+		}
+		return in.eval(x.Alt, env)
+	case *AssignExpr:
+		return in.assign(x, env)
+	}
+	return nil, &Error{Pos: x.Position(), Msg: fmt.Sprintf("unsupported expression %T", x)}
+}
+
+func (in *interp) propertyKey(m *MemberExpr, env *scope) (interface{}, error) {
+	if !m.Computed {
+		return m.Property.(*Literal).Value, nil
+	}
+	return in.eval(m.Property, env)
+}
+
+func getProperty(obj interface{}, key interface{}, pos Pos) (interface{}, error) {
+	switch t := obj.(type) {
+	case nil, undefined:
+		return nil, &Error{Pos: pos, Msg: fmt.Sprintf("cannot read property %s of %s", describe(key), toString(obj))}
+	case *object:
+		k := toString(key)
+		if v, ok := t.get(k); ok {
+			return v, nil
+		}
+		if m, ok := objectMethods[k]; ok {
+			return m, nil
+		}
+		return Undefined, nil
+	case *array:
+		if f, ok := key.(float64); ok {
+			return index(t.elems, f), nil
+		}
+		k := toString(key)
+		if k == "length" {
+			return float64(len(t.elems)), nil
+		}
+		if m, ok := arrayMethods[k]; ok {
+			return m, nil
+		}
+		return Undefined, nil
+	case string:
+		if f, ok := key.(float64); ok {
+			runes := []rune(t)
+			if f >= 0 && f == math.Trunc(f) && int(f) < len(runes) {
+				return string(runes[int(f)]), nil
+			}
+			return Undefined, nil
+		}
+		k := toString(key)
+		if k == "length" {
+			return float64(len([]rune(t))), nil
+		}
+		if m, ok := stringMethods[k]; ok {
+			return m, nil
+		}
+	case float64:
+		if m, ok := numberMethods[toString(key)]; ok {
+			return m, nil
+		}
+	}
+	return Undefined, nil
+}
+
+func index(elems []interface{}, f float64) interface{} {
+	if f >= 0 && f == math.Trunc(f) && int(f) < len(elems) {
+		return elems[int(f)]
+	}
+	return Undefined
+}
+
+func setProperty(obj interface{}, key interface{}, v interface{}, pos Pos) error {
+	switch t := obj.(type) {
+	case *object:
+		t.set(toString(key), v)
+		return nil
+	case *array:
+		f, ok := key.(float64)
+		if !ok || f < 0 || f != math.Trunc(f) {
+			return &Error{Pos: pos, Msg: fmt.Sprintf("invalid array index %s", describe(key))}
+		}
+		i := int(f)
+		for len(t.elems) <= i {
+			t.elems = append(t.elems, Undefined)
+		}
+		t.elems[i] = v
+		return nil
+	case nil, undefined:
+		return &Error{Pos: pos, Msg: fmt.Sprintf("cannot set property %s of %s", describe(key), toString(obj))}
+	}
+	return &Error{Pos: pos, Msg: fmt.Sprintf("cannot set property %s on %s", describe(key), typeOf(obj))}
+}
+
+func (in *interp) call(x *CallExpr, env *scope) (interface{}, error) {
+	var fn, this interface{}
+	switch callee := x.Callee.(type) {
+	case *MemberExpr:
+		obj, err := in.eval(callee.Object, env)
+		if err != nil {
+			return nil, err
+		}
+		if callee.Optional && (obj == nil || obj == Undefined) {
+			return Undefined, nil
+		}
+		key, err := in.propertyKey(callee, env)
+		if err != nil {
+			return nil, err
+		}
+		fn, err = getProperty(obj, key, callee.Pos)
+		if err != nil {
+			return nil, err
+		}
+		this = obj
+	default:
+		var err error
+		fn, err = in.eval(x.Callee, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if x.Optional && (fn == nil || fn == Undefined) {
+		return Undefined, nil
+	}
+	f, ok := fn.(function)
+	if !ok {
+		return nil, &Error{Pos: x.Pos, Msg: fmt.Sprintf("%s is not a function", calleeName(x.Callee))}
+	}
+
+	args := make([]interface{}, len(x.Args))
+	for i, a := range x.Args {
+		v, err := in.eval(a, env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return f.call(in, this, args, x.Pos)
+}
+
+func calleeName(x Expr) string {
+	switch t := x.(type) {
+	case *Ident:
+		return t.Name
+	case *MemberExpr:
+		if !t.Computed {
+			return calleeName(t.Object) + "." + toString(t.Property.(*Literal).Value)
+		}
+		return calleeName(t.Object) + "[...]"
+	}
+	return "expression"
+}
+
+func (in *interp) unary(x *UnaryExpr, env *scope) (interface{}, error) {
+	if x.Op == "delete" {
+		m := x.X.(*MemberExpr)
+		obj, err := in.eval(m.Object, env)
+		if err != nil {
+			return nil, err
+		}
+		key, err := in.propertyKey(m, env)
+		if err != nil {
+			return nil, err
+		}
+		switch t := obj.(type) {
+		case *object:
+			t.del(toString(key))
+		case *array:
+			if f, ok := key.(float64); ok && f >= 0 && f == math.Trunc(f) && int(f) < len(t.elems) {
+				t.elems[int(f)] = Undefined
+			}
+		case nil, undefined:
+			return nil, &Error{Pos: x.Pos, Msg: fmt.Sprintf("cannot delete property %s of %s", describe(key), toString(obj))}
+		}
+		return true, nil
+	}
+
+	if x.Op == "typeof" {
+		// typeof tolerates undeclared identifiers
+		if id, ok := x.X.(*Ident); ok && env.lookup(id.Name) == nil {
+			return "undefined", nil
+		}
+	}
+
+	v, err := in.eval(x.X, env)
+	if err != nil {
+		return nil, err
+	}
+	switch x.Op {
+	case "!":
+		return !truthy(v), nil
+	case "-":
+		return -toNumber(v), nil
+	case "+":
+		return toNumber(v), nil
+	case "typeof":
+		return typeOf(v), nil
+	}
+	return nil, &Error{Pos: x.Pos, Msg: fmt.Sprintf("unsupported operator %s", x.Op)}
+}
+
+func (in *interp) update(x *UpdateExpr, env *scope) (interface{}, error) {
+	old, err := in.eval(x.X, env)
+	if err != nil {
+		return nil, err
+	}
+	n := toNumber(old)
+	next := n + 1
+	if x.Op == "--" {
+		next = n - 1
+	}
+	if err := in.store(x.X, next, env, x.Pos); err != nil {
+		return nil, err
+	}
+	if x.Prefix {
+		return next, nil
+	}
+	return n, nil
+}
+
+func (in *interp) assign(x *AssignExpr, env *scope) (interface{}, error) {
+	v, err := in.eval(x.Value, env)
+	if err != nil {
+		return nil, err
+	}
+	if x.Op != "=" {
+		cur, err := in.eval(x.Target, env)
+		if err != nil {
+			return nil, err
+		}
+		v, err = binaryOp(strings.TrimSuffix(x.Op, "="), cur, v, x.Pos)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := in.store(x.Target, v, env, x.Pos); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (in *interp) store(target Expr, v interface{}, env *scope, pos Pos) error {
+	switch t := target.(type) {
+	case *Ident:
+		b := env.lookup(t.Name)
+		if b == nil {
+			return &Error{Pos: t.Pos, Msg: fmt.Sprintf("%s is not defined", t.Name)}
+		}
+		if b.constant {
+			return &Error{Pos: pos, Msg: fmt.Sprintf("assignment to constant variable %s", t.Name)}
+		}
+		b.value = v
+		return nil
+	case *MemberExpr:
+		obj, err := in.eval(t.Object, env)
+		if err != nil {
+			return err
+		}
+		key, err := in.propertyKey(t, env)
+		if err != nil {
+			return err
+		}
+		return setProperty(obj, key, v, t.Pos)
+	}
+	return &Error{Pos: pos, Msg: "invalid assignment target"}
+}
+
+func binaryOp(op string, l, r interface{}, pos Pos) (interface{}, error) {
+	switch op {
+	case "===":
+		return strictEquals(l, r), nil
+	case "!==":
+		return !strictEquals(l, r), nil
+	case "==":
+		return looseEquals(l, r), nil
+	case "!=":
+		return !looseEquals(l, r), nil
+	case "+":
+		lp, rp := toPrimitive(l), toPrimitive(r)
+		_, ls := lp.(string)
+		_, rs := rp.(string)
+		if ls || rs {
+			return toString(lp) + toString(rp), nil
+		}
+		return toNumber(lp) + toNumber(rp), nil
+	case "-":
+		return toNumber(l) - toNumber(r), nil
+	case "*":
+		return toNumber(l) * toNumber(r), nil
+	case "/":
+		return toNumber(l) / toNumber(r), nil
+	case "%":
+		return math.Mod(toNumber(l), toNumber(r)), nil
+	case "<", ">", "<=", ">=":
+		lp, rp := toPrimitive(l), toPrimitive(r)
+		ls, lok := lp.(string)
+		rs, rok := rp.(string)
+		if lok && rok {
+			switch op {
+			case "<":
+				return ls < rs, nil
+			case ">":
+				return ls > rs, nil
+			case "<=":
+				return ls <= rs, nil
+			}
+			return ls >= rs, nil
+		}
+		ln, rn := toNumber(lp), toNumber(rp)
+		switch op {
+		case "<":
+			return ln < rn, nil
+		case ">":
+			return ln > rn, nil
+		case "<=":
+			return ln <= rn, nil
+		}
+		return ln >= rn, nil
+	}
+	return nil, &Error{Pos: pos, Msg: fmt.Sprintf("unsupported operator %s", op)}
+}
@@ -0,0 +1,189 @@
+package stepjs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func run(t *testing.T, src string, message interface{}) interface{} {
+	t.Helper()
+	prog, err := Parse(src)
+	require.NoError(t, err)
+	v, err := prog.Run(message)
+	require.NoError(t, err)
+	return v
+}
+
+func TestRun_Expressions(t *testing.T) {
+	message := map[string]interface{}{
+		"hello": "prod",
+		"count": 3.0,
+		"tags":  []interface{}{"a", "b"},
+		"user":  map[string]interface{}{"name": "Ada", "email": " ADA@EXAMPLE.COM "},
+	}
+
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{`return message.hello == 'prod';`, true},
+		{`return message.hello === "dev"`, false},
+		{`return message.count > 2 && message.count <= 3`, true},
+		{`return !message.missing`, true},
+		{`return message.missing === undefined`, true},
+		{`return message.missing ?? 'default'`, "default"},
+		{`return message.user?.address?.city`, Undefined},
+		{`return message['user']['name']`, "Ada"},
+		{`return message.tags.length`, 2.0},
+		{`return message.tags.includes('b')`, true},
+		{`return message.tags.join('-')`, "a-b"},
+		{`return message.user.email.trim().toLowerCase()`, "ada@example.com"},
+		{`return message.hello.startsWith('pr') ? 1 : 2`, 1.0},
+		{`return message.count + 1 + "x"`, "4x"},
+		{`return "1" == 1`, true},
+		{`return "1" === 1`, false},
+		{`return message.count % 2`, 1.0},
+		{`return typeof message.count`, "number"},
+		{"return `${message.user.name}-${message.count * 2}`", "Ada-6"},
+		{`return message.tags.map(t => t.toUpperCase())`, []interface{}{"A", "B"}},
+		{`return message.tags.filter((t, i) => i > 0)`, []interface{}{"b"}},
+		{`return [1, 2, 3].reduce((acc, n) => acc + n, 0)`, 6.0},
+		{`return Object.keys(message.user)`, []interface{}{"email", "name"}},
+		{`return Math.max(1, message.count, 2)`, 3.0},
+		{`return parseInt("42px")`, 42.0},
+		{`return JSON.stringify({a: 1})`, `{"a":1}`},
+		{`return 'ab'.repeat(2.5)`, "abab"},
+		{`return '7'.padStart(3, '0')`, "007"},
+		{`return 'ab'.padEnd(5, 'xy')`, "abxyx"},
+		{`return 'ab'.padStart(-1)`, "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			require.Equal(t, tt.want, run(t, tt.src, message))
+		})
+	}
+}
+
+func TestRun_Statements(t *testing.T) {
+	src := `
+		let total = 0
+		const seen = []
+		for (const item of message.items) {
+			if (item.skip) continue
+			total += item.qty * item.price
+			seen.push(item.sku)
+		}
+		if (total > 100) {
+			return { total, skus: seen, big: true }
+		} else {
+			return { total, skus: seen }
+		}
+	`
+	message := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"sku": "A", "qty": 2.0, "price": 30.0},
+			map[string]interface{}{"sku": "B", "qty": 1.0, "price": 50.0, "skip": true},
+			map[string]interface{}{"sku": "C", "qty": 1.0, "price": 45.0},
+		},
+	}
+
+	got := run(t, src, message)
+	want := map[string]interface{}{
+		"total": 105.0,
+		"skus":  []interface{}{"A", "C"},
+		"big":   true,
+	}
+	require.Equal(t, want, got)
+}
+
+func TestRunMutating(t *testing.T) {
+	prog, err := Parse(`
+		message.status = message.status.toUpperCase()
+		delete message.secret
+	`)
+	require.NoError(t, err)
+
+	input := map[string]interface{}{"status": "ok", "secret": "x"}
+	ret, msg, err := prog.RunMutating(input)
+	require.NoError(t, err)
+	require.Equal(t, Undefined, ret)
+	require.Equal(t, map[string]interface{}{"status": "OK"}, msg)
+
+	// the caller's message is never modified
+	require.Equal(t, map[string]interface{}{"status": "ok", "secret": "x"}, input)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+	}{
+		{"return message.hello ==", 1},
+		{"let a = 1\nreturn a +* 2", 2},
+		{"const x\nreturn x", 1},
+		{"return 'unterminated", 1},
+		{"if (true) {\n  return 1\n", 1},
+		{"let a = 1\nlet b = `${a +}`", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			require.Error(t, err)
+			var e *Error
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, tt.line, e.Pos.Line)
+		})
+	}
+}
+
+func TestRun_RuntimeErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos Pos
+	}{
+		{"return message.a.b.c", Pos{Line: 1, Column: 17}},
+		{"const x = 1\nx = 2", Pos{Line: 2, Column: 3}},
+		{"return nope", Pos{Line: 1, Column: 8}},
+		{"return message.hello()", Pos{Line: 1, Column: 21}},
+		{"const f = n => f(n)\nreturn f(1)", Pos{Line: 1, Column: 17}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			prog, err := Parse(tt.src)
+			require.NoError(t, err)
+			_, err = prog.Run(map[string]interface{}{"hello": "world"})
+			var e *Error
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, tt.pos, e.Pos)
+		})
+	}
+}
+
+func TestRun_StringLengthLimits(t *testing.T) {
+	tests := []struct {
+		src string
+		msg string
+	}{
+		{"return 'ab'.repeat(-1)", "RangeError: invalid count value -1"},
+		{"return 'ab'.repeat(1 / 0)", "RangeError: invalid count value Infinity"},
+		{"return 'ab'.repeat(1e18)", "RangeError: string longer than 4194304 characters"},
+		{"return 'x'.repeat(3e9)", "RangeError: string longer than 4194304 characters"},
+		{"return 'ab'.padStart(1e18)", "RangeError: string longer than 4194304 characters"},
+		{"return 'ab'.padEnd(1 / 0, 'x')", "RangeError: string longer than 4194304 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			prog, err := Parse(tt.src)
+			require.NoError(t, err)
+			_, err = prog.Run(map[string]interface{}{})
+			var e *Error
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, tt.msg, e.Msg)
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package stepjs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokKeyword
+	tokNumber
+	tokString
+	tokTemplate
+	tokPunct
+)
+
+// keywords recognized by the lexer. Anything else that looks like an
+// identifier is treated as one.
+var keywords = map[string]bool{
+	"break":     true,
+	"const":     true,
+	"continue":  true,
+	"delete":    true,
+	"else":      true,
+	"false":     true,
+	"for":       true,
+	"if":        true,
+	"let":       true,
+	"null":      true,
+	"of":        true,
+	"return":    true,
+	"true":      true,
+	"typeof":    true,
+	"undefined": true,
+	"var":       true,
+}
+
+// punctuators sorted longest first so the lexer always takes the longest match
+var punctuators = []string{
+	"===", "!==",
+	"==", "!=", "<=", ">=", "&&", "||", "??", "?.", "=>", "+=", "-=", "*=", "/=", "%=", "++", "--",
+	"+", "-", "*", "/", "%", "<", ">", "!", "=", "(", ")", "[", "]", "{", "}", ",", ".", ";", ":", "?",
+}
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   Pos
+	num   float64
+	str   string
+	parts []templatePart
+	// nl is set when at least one line break precedes the token, used for
+	// automatic semicolon insertion
+	nl bool
+}
+
+// templatePart is either a literal chunk or the source of an embedded
+// ${...} expression in a template literal
+type templatePart struct {
+	text string
+	expr bool
+	pos  Pos
+}
+
+type lexer struct {
+	src  string
+	off  int
+	line int
+	col  int
+}
+
+func lex(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1, col: 1}
+	var toks []token
+	for {
+		nl, err := l.skipSpace()
+		if err != nil {
+			return nil, err
+		}
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tok.nl = nl
+		toks = append(toks, tok)
+		if tok.kind == tokEOF {
+			return toks, nil
+		}
+	}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Line: l.line, Column: l.col}
+}
+
+func (l *lexer) errorf(p Pos, format string, args ...interface{}) error {
+	return &Error{Pos: p, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.off+n < len(l.src) {
+		return l.src[l.off+n]
+	}
+	return 0
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+// skipSpace consumes whitespace and comments, reporting whether a line break
+// was seen
+func (l *lexer) skipSpace() (bool, error) {
+	nl := false
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		switch {
+		case c == '\n':
+			nl = true
+			l.advance()
+		case c == ' ' || c == '\t' || c == '\r':
+			l.advance()
+		case c == '/' && l.peek(1) == '/':
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance()
+			}
+		case c == '/' && l.peek(1) == '*':
+			start := l.pos()
+			l.advance()
+			l.advance()
+			closed := false
+			for l.off < len(l.src) {
+				if l.src[l.off] == '*' && l.peek(1) == '/' {
+					l.advance()
+					l.advance()
+					closed = true
+					break
+				}
+				if l.src[l.off] == '\n' {
+					nl = true
+				}
+				l.advance()
+			}
+			if !closed {
+				return nl, l.errorf(start, "unterminated comment")
+			}
+		default:
+			return nl, nil
+		}
+	}
+	return nl, nil
+}
+
+func (l *lexer) next() (token, error) {
+	p := l.pos()
+	if l.off >= len(l.src) {
+		return token{kind: tokEOF, pos: p}, nil
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[l.off:])
+	switch {
+	case isIdentStart(r):
+		start := l.off
+		for l.off < len(l.src) {
+			r, _ := utf8.DecodeRuneInString(l.src[l.off:])
+			if !isIdentPart(r) {
+				break
+			}
+			l.advance()
+		}
+		text := l.src[start:l.off]
+		if keywords[text] {
+			return token{kind: tokKeyword, text: text, pos: p}, nil
+		}
+		return token{kind: tokIdent, text: text, pos: p}, nil
+	case r >= '0' && r <= '9', r == '.' && l.peek(1) >= '0' && l.peek(1) <= '9':
+		return l.number(p)
+	case r == '"' || r == '\'':
+		return l.string(p, byte(r))
+	case r == '`':
+		return l.template(p)
+	}
+
+	for _, punct := range punctuators {
+		if strings.HasPrefix(l.src[l.off:], punct) {
+			for range punct {
+				l.advance()
+			}
+			return token{kind: tokPunct, text: punct, pos: p}, nil
+		}
+	}
+	return token{}, l.errorf(p, "unexpected character %q", r)
+}
+
+func (l *lexer) number(p Pos) (token, error) {
+	start := l.off
+	if l.src[l.off] == '0' && (l.peek(1) == 'x' || l.peek(1) == 'X') {
+		l.advance()
+		l.advance()
+		for l.off < len(l.src) && isHexDigit(l.src[l.off]) {
+			l.advance()
+		}
+		text := l.src[start:l.off]
+		n, err := strconv.ParseUint(text[2:], 16, 64)
+		if err != nil {
+			return token{}, l.errorf(p, "invalid number %q", text)
+		}
+		return token{kind: tokNumber, text: text, pos: p, num: float64(n)}, nil
+	}
+
+	for l.off < len(l.src) && isDigit(l.src[l.off]) {
+		l.advance()
+	}
+	if l.off < len(l.src) && l.src[l.off] == '.' {
+		l.advance()
+		for l.off < len(l.src) && isDigit(l.src[l.off]) {
+			l.advance()
+		}
+	}
+	if l.off < len(l.src) && (l.src[l.off] == 'e' || l.src[l.off] == 'E') {
+		l.advance()
+		if l.off < len(l.src) && (l.src[l.off] == '+' || l.src[l.off] == '-') {
+			l.advance()
+		}
+		for l.off < len(l.src) && isDigit(l.src[l.off]) {
+			l.advance()
+		}
+	}
+	text := l.src[start:l.off]
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, l.errorf(p, "invalid number %q", text)
+	}
+	return token{kind: tokNumber, text: text, pos: p, num: n}, nil
+}
+
+func (l *lexer) string(p Pos, quote byte) (token, error) {
+	start := l.off
+	l.advance()
+	var b strings.Builder
+	for {
+		if l.off >= len(l.src) || l.src[l.off] == '\n' {
+			return token{}, l.errorf(p, "unterminated string")
+		}
+		c := l.src[l.off]
+		if c == quote {
+			l.advance()
+			break
+		}
+		if c == '\\' {
+			if err := l.escape(&b); err != nil {
+				return token{}, err
+			}
+			continue
+		}
+		b.WriteRune(l.advance())
+	}
+	return token{kind: tokString, text: l.src[start:l.off], pos: p, str: b.String()}, nil
+}
+
+func (l *lexer) template(p Pos) (token, error) {
+	start := l.off
+	l.advance()
+	var parts []templatePart
+	var b strings.Builder
+	for {
+		if l.off >= len(l.src) {
+			return token{}, l.errorf(p, "unterminated template literal")
+		}
+		c := l.src[l.off]
+		if c == '`' {
+			l.advance()
+			break
+		}
+		if c == '\\' {
+			if err := l.escape(&b); err != nil {
+				return token{}, err
+			}
+			continue
+		}
+		if c == '$' && l.peek(1) == '{' {
+			parts = append(parts, templatePart{text: b.String()})
+			b.Reset()
+			l.advance()
+			l.advance()
+			exprPos := l.pos()
+			exprStart := l.off
+			depth := 0
+			for {
+				if l.off >= len(l.src) {
+					return token{}, l.errorf(p, "unterminated template expression")
+				}
+				c := l.src[l.off]
+				if c == '}' && depth == 0 {
+					break
+				}
+				if c == '{' {
+					depth++
+				} else if c == '}' {
+					depth--
+				}
+				l.advance()
+			}
+			parts = append(parts, templatePart{text: l.src[exprStart:l.off], expr: true, pos: exprPos})
+			l.advance()
+			continue
+		}
+		b.WriteRune(l.advance())
+	}
+	parts = append(parts, templatePart{text: b.String()})
+	return token{kind: tokTemplate, text: l.src[start:l.off], pos: p, parts: parts}, nil
+}
+
+func (l *lexer) escape(b *strings.Builder) error {
+	p := l.pos()
+	l.advance()
+	if l.off >= len(l.src) {
+		return l.errorf(p, "unterminated escape sequence")
+	}
+	c := l.advance()
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'v':
+		b.WriteByte('\v')
+	case '0':
+		b.WriteByte(0)
+	case 'u':
+		if l.off+4 > len(l.src) {
+			return l.errorf(p, "invalid unicode escape")
+		}
+		n, err := strconv.ParseUint(l.src[l.off:l.off+4], 16, 32)
+		if err != nil {
+			return l.errorf(p, "invalid unicode escape")
+		}
+		for i := 0; i < 4; i++ {
+			l.advance()
+		}
+		b.WriteRune(rune(n))
+	case '\n':
+		// line continuation
+	default:
+		b.WriteRune(c)
+	}
+	return nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,717 @@
+package stepjs
+
+import (
+	"fmt"
+)
+
+// binary operator precedence, higher binds tighter
+var binaryPrec = map[string]int{
+	"??":  1,
+	"||":  2,
+	"&&":  3,
+	"==":  7,
+	"!=":  7,
+	"===": 7,
+	"!==": 7,
+	"<":   8,
+	">":   8,
+	"<=":  8,
+	">=":  8,
+	"+":   10,
+	"-":   10,
+	"*":   11,
+	"/":   11,
+	"%":   11,
+}
+
+var assignOps = map[string]bool{
+	"=":  true,
+	"+=": true,
+	"-=": true,
+	"*=": true,
+	"/=": true,
+	"%=": true,
+}
+
+type parser struct {
+	toks []token
+	i    int
+	// line and column offsets applied when parsing an embedded template
+	// expression, so errors point into the original source
+	base Pos
+}
+
+// Parse parses the source of a step function. Syntax errors are returned as
+// an *Error carrying the line and column of the problem.
+func Parse(src string) (*Program, error) {
+	return parseAt(src, Pos{})
+}
+
+func parseAt(src string, base Pos) (*Program, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, rebase(err, base)
+	}
+	p := &parser{toks: toks, base: base}
+	for i := range p.toks {
+		p.toks[i].pos = p.shift(p.toks[i].pos)
+	}
+
+	prog := &Program{}
+	for p.cur().kind != tokEOF {
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		prog.Body = append(prog.Body, s)
+	}
+	return prog, nil
+}
+
+// shift converts a position relative to an embedded expression into a
+// position in the enclosing source
+func (p *parser) shift(pos Pos) Pos {
+	if p.base.Line == 0 {
+		return pos
+	}
+	if pos.Line == 1 {
+		return Pos{Line: p.base.Line, Column: p.base.Column + pos.Column - 1}
+	}
+	return Pos{Line: p.base.Line + pos.Line - 1, Column: pos.Column}
+}
+
+func rebase(err error, base Pos) error {
+	e, ok := err.(*Error)
+	if !ok || base.Line == 0 {
+		return err
+	}
+	shifted := (&parser{base: base}).shift(e.Pos)
+	return &Error{Pos: shifted, Msg: e.Msg}
+}
+
+func (p *parser) cur() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) peekTok(n int) token {
+	if p.i+n < len(p.toks) {
+		return p.toks[p.i+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) advance() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) is(kind tokenKind, text string) bool {
+	t := p.cur()
+	return t.kind == kind && t.text == text
+}
+
+func (p *parser) isPunct(text string) bool {
+	return p.is(tokPunct, text)
+}
+
+func (p *parser) isKeyword(text string) bool {
+	return p.is(tokKeyword, text)
+}
+
+func (p *parser) errorf(pos Pos, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) unexpected() error {
+	t := p.cur()
+	if t.kind == tokEOF {
+		return p.errorf(t.pos, "unexpected end of input")
+	}
+	return p.errorf(t.pos, "unexpected token %q", t.text)
+}
+
+func (p *parser) expectPunct(text string) (token, error) {
+	if !p.isPunct(text) {
+		t := p.cur()
+		if t.kind == tokEOF {
+			return t, p.errorf(t.pos, "expected %q but reached end of input", text)
+		}
+		return t, p.errorf(t.pos, "expected %q but found %q", text, t.text)
+	}
+	return p.advance(), nil
+}
+
+func (p *parser) expectIdent() (token, error) {
+	t := p.cur()
+	if t.kind != tokIdent {
+		return t, p.errorf(t.pos, "expected identifier but found %q", t.text)
+	}
+	return p.advance(), nil
+}
+
+// endStatement consumes an optional semicolon. When there is none, the next
+// token must start on a new line or close the enclosing block.
+func (p *parser) endStatement() error {
+	if p.isPunct(";") {
+		p.advance()
+		return nil
+	}
+	t := p.cur()
+	if t.kind == tokEOF || t.nl || p.isPunct("}") {
+		return nil
+	}
+	return p.unexpected()
+}
+
+func (p *parser) statement() (Stmt, error) {
+	t := p.cur()
+	switch {
+	case p.isPunct(";"):
+		p.advance()
+		return &EmptyStmt{Pos: t.pos}, nil
+	case p.isPunct("{"):
+		return p.block()
+	case p.isKeyword("var"), p.isKeyword("let"), p.isKeyword("const"):
+		d, err := p.varDecl()
+		if err != nil {
+			return nil, err
+		}
+		return d, p.endStatement()
+	case p.isKeyword("return"):
+		p.advance()
+		r := &ReturnStmt{Pos: t.pos}
+		next := p.cur()
+		if next.kind == tokEOF || next.nl || p.isPunct(";") || p.isPunct("}") {
+			return r, p.endStatement()
+		}
+		v, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		r.Value = v
+		return r, p.endStatement()
+	case p.isKeyword("if"):
+		return p.ifStmt()
+	case p.isKeyword("for"):
+		return p.forOf()
+	case p.isKeyword("break"):
+		p.advance()
+		return &BreakStmt{Pos: t.pos}, p.endStatement()
+	case p.isKeyword("continue"):
+		p.advance()
+		return &ContinueStmt{Pos: t.pos}, p.endStatement()
+	}
+
+	x, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &ExprStmt{Pos: t.pos, X: x}, p.endStatement()
+}
+
+func (p *parser) block() (*BlockStmt, error) {
+	open, err := p.expectPunct("{")
+	if err != nil {
+		return nil, err
+	}
+	b := &BlockStmt{Pos: open.pos}
+	for !p.isPunct("}") {
+		if p.cur().kind == tokEOF {
+			return nil, p.errorf(open.pos, "unterminated block")
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		b.Body = append(b.Body, s)
+	}
+	p.advance()
+	return b, nil
+}
+
+func (p *parser) varDecl() (*VarDecl, error) {
+	kw := p.advance()
+	d := &VarDecl{Pos: kw.pos, Kind: kw.text}
+	for {
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		decl := &Declarator{Pos: name.pos, Name: name.text}
+		if p.isPunct("=") {
+			p.advance()
+			decl.Init, err = p.assignment()
+			if err != nil {
+				return nil, err
+			}
+		} else if kw.text == "const" {
+			return nil, p.errorf(name.pos, "missing initializer in const declaration %q", name.text)
+		}
+		d.Decls = append(d.Decls, decl)
+		if !p.isPunct(",") {
+			return d, nil
+		}
+		p.advance()
+	}
+}
+
+func (p *parser) ifStmt() (Stmt, error) {
+	kw := p.advance()
+	if _, err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	cond, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	then, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	s := &IfStmt{Pos: kw.pos, Cond: cond, Then: then}
+	if p.isKeyword("else") {
+		p.advance()
+		s.Else, err = p.statement()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) forOf() (Stmt, error) {
+	kw := p.advance()
+	if _, err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	if !(p.isKeyword("var") || p.isKeyword("let") || p.isKeyword("const")) {
+		return nil, p.errorf(p.cur().pos, "only for (const x of items) loops are supported")
+	}
+	kind := p.advance().text
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isKeyword("of") {
+		return nil, p.errorf(p.cur().pos, "only for (const x of items) loops are supported")
+	}
+	p.advance()
+	iter, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return &ForOfStmt{Pos: kw.pos, Kind: kind, Name: name.text, Iter: iter, Body: body}, nil
+}
+
+func (p *parser) expression() (Expr, error) {
+	return p.assignment()
+}
+
+func (p *parser) assignment() (Expr, error) {
+	if fn, ok, err := p.tryArrow(); ok || err != nil {
+		return fn, err
+	}
+
+	start := p.cur()
+	x, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.cur(); t.kind == tokPunct && assignOps[t.text] {
+		switch x.(type) {
+		case *Ident, *MemberExpr:
+		default:
+			return nil, p.errorf(start.pos, "invalid assignment target")
+		}
+		p.advance()
+		v, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return &AssignExpr{Pos: t.pos, Op: t.text, Target: x, Value: v}, nil
+	}
+	return x, nil
+}
+
+// tryArrow parses an arrow function if one starts at the current token,
+// otherwise it leaves the parser untouched
+func (p *parser) tryArrow() (Expr, bool, error) {
+	start := p.cur()
+	var params []string
+	switch {
+	case start.kind == tokIdent && p.peekTok(1).kind == tokPunct && p.peekTok(1).text == "=>":
+		params = []string{start.text}
+		p.advance()
+	case p.isPunct("("):
+		j := p.i + 1
+		for {
+			t := p.toks[j]
+			if t.kind == tokPunct && t.text == ")" {
+				break
+			}
+			if t.kind != tokIdent {
+				return nil, false, nil
+			}
+			params = append(params, t.text)
+			j++
+			if p.toks[j].kind == tokPunct && p.toks[j].text == "," {
+				j++
+			}
+		}
+		if next := p.toks[j+1]; next.kind != tokPunct || next.text != "=>" {
+			return nil, false, nil
+		}
+		p.i = j + 1
+	default:
+		return nil, false, nil
+	}
+	p.advance() // =>
+
+	fn := &ArrowFunc{Pos: start.pos, Params: params}
+	if p.isPunct("{") {
+		body, err := p.block()
+		if err != nil {
+			return nil, true, err
+		}
+		fn.Body = body
+		return fn, true, nil
+	}
+	body, err := p.assignment()
+	if err != nil {
+		return nil, true, err
+	}
+	fn.Body = body
+	return fn, true, nil
+}
+
+func (p *parser) conditional() (Expr, error) {
+	test, err := p.binary(1)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isPunct("?") {
+		return test, nil
+	}
+	q := p.advance()
+	cons, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expectPunct(":"); err != nil {
+		return nil, err
+	}
+	alt, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	return &CondExpr{Pos: q.pos, Test: test, Cons: cons, Alt: alt}, nil
+}
+
+func (p *parser) binary(minPrec int) (Expr, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.cur()
+		prec, ok := binaryPrec[t.text]
+		if t.kind != tokPunct || !ok || prec < minPrec {
+			return x, nil
+		}
+		p.advance()
+		y, err := p.binary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "&&", "||", "??":
+			x = &LogicalExpr{Pos: t.pos, Op: t.text, X: x, Y: y}
+		default:
+			x = &BinaryExpr{Pos: t.pos, Op: t.text, X: x, Y: y}
+		}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	t := p.cur()
+	switch {
+	case p.isPunct("!"), p.isPunct("-"), p.isPunct("+"), p.isKeyword("typeof"), p.isKeyword("delete"):
+		p.advance()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "delete" {
+			if _, ok := x.(*MemberExpr); !ok {
+				return nil, p.errorf(t.pos, "delete requires a property reference")
+			}
+		}
+		return &UnaryExpr{Pos: t.pos, Op: t.text, X: x}, nil
+	case p.isPunct("++"), p.isPunct("--"):
+		p.advance()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkUpdateTarget(t, x); err != nil {
+			return nil, err
+		}
+		return &UpdateExpr{Pos: t.pos, Op: t.text, Prefix: true, X: x}, nil
+	}
+
+	x, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.cur(); (p.isPunct("++") || p.isPunct("--")) && !t.nl {
+		if err := p.checkUpdateTarget(t, x); err != nil {
+			return nil, err
+		}
+		p.advance()
+		return &UpdateExpr{Pos: t.pos, Op: t.text, X: x}, nil
+	}
+	return x, nil
+}
+
+func (p *parser) checkUpdateTarget(op token, x Expr) error {
+	switch x.(type) {
+	case *Ident, *MemberExpr:
+		return nil
+	}
+	return p.errorf(op.pos, "invalid operand for %s", op.text)
+}
+
+func (p *parser) postfix() (Expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.cur()
+		switch {
+		case p.isPunct("."):
+			p.advance()
+			name := p.cur()
+			if name.kind != tokIdent && name.kind != tokKeyword {
+				return nil, p.errorf(name.pos, "expected property name after '.'")
+			}
+			p.advance()
+			x = &MemberExpr{Pos: t.pos, Object: x, Property: &Literal{Pos: name.pos, Value: name.text}}
+		case p.isPunct("?."):
+			p.advance()
+			switch {
+			case p.isPunct("["):
+				p.advance()
+				prop, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				if _, err := p.expectPunct("]"); err != nil {
+					return nil, err
+				}
+				x = &MemberExpr{Pos: t.pos, Object: x, Property: prop, Computed: true, Optional: true}
+			case p.isPunct("("):
+				args, err := p.arguments()
+				if err != nil {
+					return nil, err
+				}
+				x = &CallExpr{Pos: t.pos, Callee: x, Args: args, Optional: true}
+			default:
+				name := p.cur()
+				if name.kind != tokIdent && name.kind != tokKeyword {
+					return nil, p.errorf(name.pos, "expected property name after '?.'")
+				}
+				p.advance()
+				x = &MemberExpr{Pos: t.pos, Object: x, Property: &Literal{Pos: name.pos, Value: name.text}, Optional: true}
+			}
+		case p.isPunct("["):
+			p.advance()
+			prop, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expectPunct("]"); err != nil {
+				return nil, err
+			}
+			x = &MemberExpr{Pos: t.pos, Object: x, Property: prop, Computed: true}
+		case p.isPunct("("):
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			x = &CallExpr{Pos: t.pos, Callee: x, Args: args}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) arguments() ([]Expr, error) {
+	if _, err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var args []Expr
+	for !p.isPunct(")") {
+		a, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+		if !p.isPunct(",") {
+			break
+		}
+		p.advance()
+	}
+	if _, err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (p *parser) primary() (Expr, error) {
+	t := p.cur()
+	switch t.kind {
+	case tokIdent:
+		p.advance()
+		return &Ident{Pos: t.pos, Name: t.text}, nil
+	case tokNumber:
+		p.advance()
+		return &Literal{Pos: t.pos, Value: t.num}, nil
+	case tokString:
+		p.advance()
+		return &Literal{Pos: t.pos, Value: t.str}, nil
+	case tokTemplate:
+		p.advance()
+		return p.templateLit(t)
+	case tokKeyword:
+		switch t.text {
+		case "true", "false":
+			p.advance()
+			return &Literal{Pos: t.pos, Value: t.text == "true"}, nil
+		case "null":
+			p.advance()
+			return &Literal{Pos: t.pos, Value: nil}, nil
+		case "undefined":
+			p.advance()
+			return &Literal{Pos: t.pos, Value: Undefined}, nil
+		}
+	case tokPunct:
+		switch t.text {
+		case "(":
+			p.advance()
+			x, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case "[":
+			return p.arrayLit()
+		case "{":
+			return p.objectLit()
+		}
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) templateLit(t token) (Expr, error) {
+	lit := &TemplateLit{Pos: t.pos}
+	for _, part := range t.parts {
+		if !part.expr {
+			lit.Quasis = append(lit.Quasis, part.text)
+			continue
+		}
+		sub, err := parseAt(part.text, p.shift(part.pos))
+		if err != nil {
+			return nil, err
+		}
+		if len(sub.Body) != 1 {
+			return nil, p.errorf(p.shift(part.pos), "template expression must be a single expression")
+		}
+		es, ok := sub.Body[0].(*ExprStmt)
+		if !ok {
+			return nil, p.errorf(p.shift(part.pos), "template expression must be a single expression")
+		}
+		lit.Exprs = append(lit.Exprs, es.X)
+	}
+	return lit, nil
+}
+
+func (p *parser) arrayLit() (Expr, error) {
+	open := p.advance()
+	lit := &ArrayLit{Pos: open.pos}
+	for !p.isPunct("]") {
+		e, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		lit.Elems = append(lit.Elems, e)
+		if !p.isPunct(",") {
+			break
+		}
+		p.advance()
+	}
+	if _, err := p.expectPunct("]"); err != nil {
+		return nil, err
+	}
+	return lit, nil
+}
+
+func (p *parser) objectLit() (Expr, error) {
+	open := p.advance()
+	lit := &ObjectLit{Pos: open.pos}
+	for !p.isPunct("}") {
+		t := p.cur()
+		var key string
+		switch t.kind {
+		case tokIdent, tokKeyword:
+			key = t.text
+		case tokString:
+			key = t.str
+		case tokNumber:
+			key = formatNumber(t.num)
+		default:
+			return nil, p.errorf(t.pos, "expected property name but found %q", t.text)
+		}
+		p.advance()
+
+		prop := &Property{Pos: t.pos, Key: key}
+		if p.isPunct(":") {
+			p.advance()
+			v, err := p.assignment()
+			if err != nil {
+				return nil, err
+			}
+			prop.Value = v
+		} else if t.kind == tokIdent {
+			// shorthand { name }
+			prop.Value = &Ident{Pos: t.pos, Name: t.text}
+		} else {
+			return nil, p.errorf(p.cur().pos, "expected ':' after property name %q", key)
+		}
+		lit.Props = append(lit.Props, prop)
+		if !p.isPunct(",") {
+			break
+		}
+		p.advance()
+	}
+	if _, err := p.expectPunct("}"); err != nil {
+		return nil, err
+	}
+	return lit, nil
+}
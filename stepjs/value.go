@@ -0,0 +1,359 @@
+package stepjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type undefined struct{}
+
+// Undefined is the JavaScript undefined value. It is returned for missing
+// properties and from functions that do not return a value.
+var Undefined = undefined{}
+
+// array gives JavaScript arrays reference semantics so push and index
+// assignment are visible through every reference
+type array struct {
+	elems []interface{}
+}
+
+// object gives JavaScript objects reference semantics and remembers key
+// insertion order
+type object struct {
+	keys []string
+	vals map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{vals: map[string]interface{}{}}
+}
+
+func (o *object) get(k string) (interface{}, bool) {
+	v, ok := o.vals[k]
+	return v, ok
+}
+
+func (o *object) set(k string, v interface{}) {
+	if _, ok := o.vals[k]; !ok {
+		o.keys = append(o.keys, k)
+	}
+	o.vals[k] = v
+}
+
+func (o *object) del(k string) {
+	if _, ok := o.vals[k]; !ok {
+		return
+	}
+	delete(o.vals, k)
+	for i, key := range o.keys {
+		if key == k {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// MarshalJSON keeps insertion order when an object is serialized, which makes
+// transformed output stable and easy to compare
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		b.Write(kb)
+		b.WriteByte(':')
+		vb, err := json.Marshal(toGo(o.vals[k]))
+		if err != nil {
+			return nil, err
+		}
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+// function is implemented by arrow functions and built ins
+type function interface {
+	call(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error)
+}
+
+type nativeFunc struct {
+	name string
+	fn   func(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error)
+}
+
+func (f *nativeFunc) call(in *interp, this interface{}, args []interface{}, pos Pos) (interface{}, error) {
+	return f.fn(in, this, args, pos)
+}
+
+// fromGo converts a Go value into the interpreter's representation. Structs
+// and other types are converted through their JSON encoding.
+func fromGo(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case nil, bool, string, float64, undefined:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	case []interface{}:
+		a := &array{elems: make([]interface{}, len(t))}
+		for i, e := range t {
+			c, err := fromGo(e)
+			if err != nil {
+				return nil, err
+			}
+			a.elems[i] = c
+		}
+		return a, nil
+	case map[string]interface{}:
+		o := newObject()
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			c, err := fromGo(t[k])
+			if err != nil {
+				return nil, err
+			}
+			o.set(k, c)
+		}
+		return o, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return fromGo(generic)
+}
+
+// toGo converts an interpreter value back to plain Go types as produced by
+// encoding/json: map[string]interface{}, []interface{}, float64, string,
+// bool and nil. Undefined becomes nil and functions are dropped.
+func toGo(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *array:
+		out := make([]interface{}, len(t.elems))
+		for i, e := range t.elems {
+			out[i] = toGo(e)
+		}
+		return out
+	case *object:
+		out := make(map[string]interface{}, len(t.keys))
+		for _, k := range t.keys {
+			val := t.vals[k]
+			if val == Undefined {
+				continue
+			}
+			if _, ok := val.(function); ok {
+				continue
+			}
+			out[k] = toGo(val)
+		}
+		return out
+	case undefined, function:
+		return nil
+	}
+	return v
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0 && !math.IsNaN(t)
+	case string:
+		return t != ""
+	}
+	return true
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case undefined:
+		return "undefined"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case function:
+		return "function"
+	}
+	return "object"
+}
+
+func formatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == math.Trunc(f) && math.Abs(f) < 1e21:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case undefined:
+		return "undefined"
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return formatNumber(t)
+	case string:
+		return t
+	case *array:
+		parts := make([]string, len(t.elems))
+		for i, e := range t.elems {
+			if e == nil || e == Undefined {
+				continue
+			}
+			parts[i] = toString(e)
+		}
+		return strings.Join(parts, ",")
+	case function:
+		return "function"
+	}
+	return "[object Object]"
+}
+
+func toNumber(v interface{}) float64 {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if t {
+			return 1
+		}
+		return 0
+	case float64:
+		return t
+	case string:
+		s := strings.TrimSpace(t)
+		if s == "" {
+			return 0
+		}
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			n, err := strconv.ParseUint(s[2:], 16, 64)
+			if err != nil {
+				return math.NaN()
+			}
+			return float64(n)
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			switch s {
+			case "Infinity", "+Infinity":
+				return math.Inf(1)
+			case "-Infinity":
+				return math.Inf(-1)
+			}
+			return math.NaN()
+		}
+		return f
+	case *array:
+		switch len(t.elems) {
+		case 0:
+			return 0
+		case 1:
+			return toNumber(toString(t.elems[0]))
+		}
+	}
+	return math.NaN()
+}
+
+// toPrimitive converts arrays and objects the way JavaScript does before
+// comparing them with primitives
+func toPrimitive(v interface{}) interface{} {
+	switch v.(type) {
+	case *array, *object, function:
+		return toString(v)
+	}
+	return v
+}
+
+func strictEquals(a, b interface{}) bool {
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case undefined:
+		return b == Undefined
+	case bool, string:
+		return a == b
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	}
+	// reference types compare by identity
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func looseEquals(a, b interface{}) bool {
+	aNull := a == nil || a == Undefined
+	bNull := b == nil || b == Undefined
+	if aNull || bNull {
+		return aNull && bNull
+	}
+	if typeOf(a) == typeOf(b) {
+		return strictEquals(a, b)
+	}
+	if _, ok := a.(bool); ok {
+		return looseEquals(toNumber(a), b)
+	}
+	if _, ok := b.(bool); ok {
+		return looseEquals(a, toNumber(b))
+	}
+	switch a.(type) {
+	case *array, *object, function:
+		return looseEquals(toPrimitive(a), b)
+	}
+	switch b.(type) {
+	case *array, *object, function:
+		return looseEquals(a, toPrimitive(b))
+	}
+	return toNumber(a) == toNumber(b)
+}
+
+// describe renders a value for error messages
+func describe(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strconv.Quote(t)
+	case *array:
+		return "array"
+	case *object:
+		return "object"
+	}
+	return fmt.Sprint(toString(v))
+}
@@ -0,0 +1,161 @@
+// Package steptest runs a Pipeline's step functions locally against sample
+// messages, so filters and transforms can be unit tested without deploying
+// the pipeline and publishing to it.
+package steptest
+
+import (
+	"fmt"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/stepjs"
+)
+
+// StepError is returned when a step function fails to compile or run. Err is
+// usually a *stepjs.Error carrying the line and column of the problem.
+type StepError struct {
+	Step int
+	Type swarm.StepType
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d (%s): %s", e.Step, e.Type, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// StepResult describes what a single step did to a message
+type StepResult struct {
+	Step int
+	Type swarm.StepType
+	// Passed is false when a filter rejected the message or the step failed
+	Passed bool
+	// Output is the message after the step ran
+	Output interface{}
+	Err    error
+}
+
+// Result describes a message's path through every step of a pipeline
+type Result struct {
+	Input interface{}
+	// Output is the message after all steps ran. It is nil when the message
+	// was filtered out or failed.
+	Output interface{}
+	// Passed is true when the message made it through every step
+	Passed bool
+	Steps  []StepResult
+	// Err is the error of the required step that failed the message, if any
+	Err error
+}
+
+type compiledStep struct {
+	step swarm.PipelineSteps
+	prog *stepjs.Program
+}
+
+// Runner evaluates the steps of a single pipeline. Steps are compiled once
+// when the Runner is created and reused for every message.
+type Runner struct {
+	steps []compiledStep
+}
+
+// New compiles every step function of the pipeline. Syntax errors and
+// unsupported step types are returned as a *StepError.
+func New(p *swarm.Pipeline) (*Runner, error) {
+	r := &Runner{}
+	for i, s := range p.Steps {
+		switch s.Type {
+		case swarm.StepTypeFilter, swarm.StepTypeTransform:
+		default:
+			return nil, &StepError{Step: i, Type: s.Type, Err: fmt.Errorf("unsupported step type %q", s.Type)}
+		}
+		prog, err := stepjs.Parse(s.Function)
+		if err != nil {
+			return nil, &StepError{Step: i, Type: s.Type, Err: err}
+		}
+		r.steps = append(r.steps, compiledStep{step: s, prog: prog})
+	}
+	return r, nil
+}
+
+// Run passes a message through every step in order. Required filters stop
+// the message when their function returns a falsy value, transforms replace
+// it with the returned value, or keep the mutated message when nothing is
+// returned. A filter rejecting the message or an error in a step that is not
+// Required is recorded and the message continues unchanged, as it does on
+// the platform.
+func (r *Runner) Run(message interface{}) *Result {
+	res := &Result{Input: message}
+	current := message
+	for i, cs := range r.steps {
+		sr := StepResult{Step: i, Type: cs.step.Type}
+		out, err := r.runStep(cs, current)
+		if err != nil {
+			sr.Err = &StepError{Step: i, Type: cs.step.Type, Err: err}
+			sr.Output = current
+			res.Steps = append(res.Steps, sr)
+			if cs.step.Required {
+				res.Err = sr.Err
+				return res
+			}
+			continue
+		}
+
+		if cs.step.Type == swarm.StepTypeFilter && !out.passed {
+			sr.Output = current
+			res.Steps = append(res.Steps, sr)
+			if cs.step.Required {
+				return res
+			}
+			continue
+		}
+		current = out.message
+		sr.Passed = true
+		sr.Output = current
+		res.Steps = append(res.Steps, sr)
+	}
+	res.Passed = true
+	res.Output = current
+	return res
+}
+
+type stepOutput struct {
+	passed  bool
+	message interface{}
+}
+
+func (r *Runner) runStep(cs compiledStep, message interface{}) (stepOutput, error) {
+	switch cs.step.Type {
+	case swarm.StepTypeFilter:
+		v, err := cs.prog.Run(message)
+		if err != nil {
+			return stepOutput{}, err
+		}
+		return stepOutput{passed: stepjs.Truthy(v), message: message}, nil
+	case swarm.StepTypeTransform:
+		v, mutated, err := cs.prog.RunMutating(message)
+		if err != nil {
+			return stepOutput{}, err
+		}
+		if v == stepjs.Undefined {
+			v = mutated
+		}
+		return stepOutput{passed: true, message: v}, nil
+	}
+	return stepOutput{}, fmt.Errorf("unsupported step type %q", cs.step.Type)
+}
+
+// Run compiles the pipeline and runs every message through it
+func Run(p *swarm.Pipeline, messages ...interface{}) ([]*Result, error) {
+	r, err := New(p)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Result, len(messages))
+	for i, m := range messages {
+		results[i] = r.Run(m)
+	}
+	return results, nil
+}
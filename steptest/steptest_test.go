@@ -0,0 +1,114 @@
+package steptest
+
+import (
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/stepjs"
+	"github.com/stretchr/testify/require"
+)
+
+var testPipeline = &swarm.Pipeline{
+	Name: "pipeline1",
+	Steps: []swarm.PipelineSteps{
+		{
+			Function: "return message.hello == 'prod';",
+			Required: true,
+			Type:     "filter",
+		},
+		{
+			Function: "return { greeting: message.hello.toUpperCase(), size: message.size ?? 0 }",
+			Required: true,
+			Type:     "transform",
+		},
+	},
+}
+
+func TestRun(t *testing.T) {
+	results, err := Run(testPipeline,
+		map[string]interface{}{"hello": "prod", "size": 3},
+		map[string]interface{}{"hello": "dev"},
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	require.True(t, results[0].Passed)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, map[string]interface{}{"greeting": "PROD", "size": 3.0}, results[0].Output)
+	require.Len(t, results[0].Steps, 2)
+
+	require.False(t, results[1].Passed)
+	require.NoError(t, results[1].Err)
+	require.Nil(t, results[1].Output)
+	require.Len(t, results[1].Steps, 1)
+	require.False(t, results[1].Steps[0].Passed)
+}
+
+func TestRun_TransformInPlace(t *testing.T) {
+	p := &swarm.Pipeline{Steps: []swarm.PipelineSteps{{
+		Function: "message.total = message.qty * message.price",
+		Type:     "transform",
+	}}}
+
+	results, err := Run(p, map[string]interface{}{"qty": 2, "price": 4.5})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"qty": 2.0, "price": 4.5, "total": 9.0}, results[0].Output)
+}
+
+func TestRun_OptionalFilter(t *testing.T) {
+	p := &swarm.Pipeline{Steps: []swarm.PipelineSteps{
+		{Function: "return message.priority == 'high'", Type: "filter"},
+		{Function: "message.seen = true", Type: "transform", Required: true},
+	}}
+
+	// a filter that is not required rejecting the message does not stop it
+	results, err := Run(p, map[string]interface{}{"priority": "low"})
+	require.NoError(t, err)
+	require.True(t, results[0].Passed)
+	require.False(t, results[0].Steps[0].Passed)
+	require.True(t, results[0].Steps[1].Passed)
+	require.Equal(t, map[string]interface{}{"priority": "low", "seen": true}, results[0].Output)
+}
+
+func TestRun_StepErrors(t *testing.T) {
+	p := &swarm.Pipeline{Steps: []swarm.PipelineSteps{
+		{Function: "message.tag = message.meta.tag", Type: "transform"},
+		{Function: "return message.id > 0", Type: "filter", Required: true},
+	}}
+
+	// the optional transform fails but the message continues unchanged
+	results, err := Run(p, map[string]interface{}{"id": 1})
+	require.NoError(t, err)
+	require.True(t, results[0].Passed)
+	require.Error(t, results[0].Steps[0].Err)
+	require.Equal(t, map[string]interface{}{"id": 1}, results[0].Output)
+
+	// a required step failing stops the message
+	p.Steps[0].Required = true
+	results, err = Run(p, map[string]interface{}{"id": 1})
+	require.NoError(t, err)
+	require.False(t, results[0].Passed)
+
+	var stepErr *StepError
+	require.ErrorAs(t, results[0].Err, &stepErr)
+	require.Equal(t, 0, stepErr.Step)
+	var jsErr *stepjs.Error
+	require.ErrorAs(t, results[0].Err, &jsErr)
+	require.Equal(t, 1, jsErr.Pos.Line)
+}
+
+func TestNew_CompileErrors(t *testing.T) {
+	p := &swarm.Pipeline{Steps: []swarm.PipelineSteps{
+		{Function: "return true", Type: "filter"},
+		{Function: "let x = 1\nreturn x +", Type: "transform"},
+	}}
+	_, err := New(p)
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	require.Equal(t, 1, stepErr.Step)
+	require.Contains(t, err.Error(), "line 2:")
+
+	p = &swarm.Pipeline{Steps: []swarm.PipelineSteps{{Function: "return true", Type: "mystery"}}}
+	_, err = New(p)
+	require.ErrorAs(t, err, &stepErr)
+}
@@ -0,0 +1,500 @@
+package swarm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamFormat is the encoding of a stream of records to publish
+type StreamFormat string
+
+const (
+	// StreamJSON is a JSON array of records, or one or more JSON values one
+	// after the other
+	StreamJSON StreamFormat = "json"
+	// StreamNDJSON is one JSON value per line. A malformed line fails only
+	// that record.
+	StreamNDJSON StreamFormat = "ndjson"
+	// StreamCSV is comma separated values with a header row. Every row
+	// becomes an object keyed by the header, with string values.
+	StreamCSV StreamFormat = "csv"
+)
+
+// StreamFormatFromPath guesses the format of a file from its extension,
+// defaulting to StreamJSON
+func StreamFormatFromPath(path string) StreamFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return StreamNDJSON
+	case ".csv":
+		return StreamCSV
+	default:
+		return StreamJSON
+	}
+}
+
+// Record is a message read from a stream
+type Record struct {
+	// Index is the position of the record in the stream, starting at 0
+	Index int
+	// Line is the line the record starts on, or 0 when it is not known
+	Line int
+	// Data is the record as JSON. For a RecordError it is the text that
+	// failed to parse, or for CSV the row that did not match the header,
+	// encoded as CSV.
+	Data json.RawMessage
+}
+
+func (r Record) String() string {
+	if r.Line > 0 {
+		return fmt.Sprintf("line %d", r.Line)
+	}
+	return fmt.Sprintf("record %d", r.Index+1)
+}
+
+// RecordError is returned by RecordReader.Next for a record that could not be
+// parsed. The reader can continue with the next record.
+type RecordError struct {
+	Record Record
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Record, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// RecordReader reads records from a JSON, NDJSON or CSV stream
+type RecordReader struct {
+	next  func() (Record, error)
+	index int
+}
+
+// NewRecordReader returns a reader of records in the given format
+func NewRecordReader(r io.Reader, format StreamFormat) (*RecordReader, error) {
+	rr := &RecordReader{}
+	switch format {
+	case StreamJSON:
+		rr.next = jsonRecords(r)
+	case StreamNDJSON:
+		rr.next = ndjsonRecords(r)
+	case StreamCSV:
+		rr.next = csvRecords(r)
+	default:
+		return nil, fmt.Errorf("unknown stream format %q", format)
+	}
+	return rr, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the stream. A
+// *RecordError fails only that record; other errors end the stream.
+func (rr *RecordReader) Next() (Record, error) {
+	rec, err := rr.next()
+	var recErr *RecordError
+	if err != nil && !errors.As(err, &recErr) {
+		return Record{}, err
+	}
+	rec.Index = rr.index
+	rr.index++
+	if recErr != nil {
+		recErr.Record.Index = rec.Index
+		return rec, recErr
+	}
+	return rec, nil
+}
+
+func jsonRecords(r io.Reader) func() (Record, error) {
+	var dec *json.Decoder
+	started, inArray := false, false
+	return func() (Record, error) {
+		if !started {
+			started = true
+			// peek at the first character to tell an array from values
+			br := bufio.NewReader(r)
+			for {
+				c, err := br.ReadByte()
+				if err != nil {
+					return Record{}, err
+				}
+				if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+					continue
+				}
+				if err := br.UnreadByte(); err != nil {
+					return Record{}, err
+				}
+				inArray = c == '['
+				break
+			}
+			dec = json.NewDecoder(br)
+			if inArray {
+				if _, err := dec.Token(); err != nil {
+					return Record{}, err
+				}
+			}
+		}
+		if inArray && !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return Record{}, err
+			}
+			return Record{}, io.EOF
+		}
+		var data json.RawMessage
+		if err := dec.Decode(&data); err != nil {
+			return Record{}, err
+		}
+		return Record{Data: data}, nil
+	}
+}
+
+func ndjsonRecords(r io.Reader) func() (Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	line := 0
+	return func() (Record, error) {
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			rec := Record{Line: line, Data: append(json.RawMessage{}, text...)}
+			if !json.Valid(text) {
+				return rec, &RecordError{Record: rec, Err: errors.New("invalid JSON")}
+			}
+			return rec, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return Record{}, err
+		}
+		return Record{}, io.EOF
+	}
+}
+
+// csvRecords reads a header row and then one record per row, an object
+// mapping the column names to the row's values. Rows with the wrong number
+// of fields fail with a *RecordError whose Data is the row encoded as CSV.
+func csvRecords(r io.Reader) func() (Record, error) {
+	cr := csv.NewReader(r)
+	// the field count is checked here so a bad row does not end the stream
+	cr.FieldsPerRecord = -1
+	var header []string
+	return func() (Record, error) {
+		if header == nil {
+			h, err := cr.Read()
+			if err == io.EOF {
+				return Record{}, io.EOF
+			}
+			if err != nil {
+				return Record{}, err
+			}
+			seen := map[string]bool{}
+			for _, name := range h {
+				if name == "" || seen[name] {
+					return Record{}, fmt.Errorf("CSV header has an empty or duplicate column %q", name)
+				}
+				seen[name] = true
+			}
+			header = h
+		}
+		row, err := cr.Read()
+		if err != nil {
+			return Record{}, err
+		}
+		line, _ := cr.FieldPos(0)
+		if len(row) != len(header) {
+			buf := &bytes.Buffer{}
+			w := csv.NewWriter(buf)
+			if err := w.Write(row); err != nil {
+				return Record{}, err
+			}
+			w.Flush()
+			rec := Record{Line: line, Data: bytes.TrimRight(buf.Bytes(), "\r\n")}
+			return rec, &RecordError{Record: rec, Err: fmt.Errorf("%d fields, the header has %d", len(row), len(header))}
+		}
+		obj := make(map[string]string, len(header))
+		for i, name := range header {
+			obj[name] = row[i]
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return Record{}, err
+		}
+		return Record{Line: line, Data: data}, nil
+	}
+}
+
+// PublishState records the progress of PublishStream so an interrupted run
+// can be resumed. It encodes to JSON for saving between runs.
+type PublishState struct {
+	// Done is the number of records from the start of the stream that have
+	// all been published or rejected
+	Done int `json:"done"`
+	// Published is the number of records published
+	Published int `json:"published"`
+	// Rejected is the number of records that failed and were rejected
+	Rejected int `json:"rejected"`
+	// Finished holds the indexes of the records past Done that have been
+	// published or rejected, in ascending order, so they are not published
+	// or counted again on resume
+	Finished []int `json:"finished,omitempty"`
+}
+
+// PublishStreamOptions configures PublishStream. Exactly one of PipelineName
+// and PipelineID is required.
+type PublishStreamOptions struct {
+	PipelineName string
+	PipelineID   string
+	// Format is the encoding of the stream, StreamJSON by default
+	Format StreamFormat
+	// Concurrency is how many records are published at once, 1 by default
+	Concurrency int
+	// Rate limits how many records are published per second. Zero is
+	// unlimited.
+	Rate float64
+	// Reject is called with every record that could not be parsed or
+	// published. The stream continues unless it returns an error. Without
+	// it the first failure stops the stream.
+	Reject func(rec Record, err error) error
+	// State resumes an interrupted run by skipping the records it counts as
+	// done or finished. It is updated in place.
+	State *PublishState
+	// Checkpoint is called with the state after every record that is
+	// published or rejected, to save it. An error stops the stream.
+	Checkpoint func(*PublishState) error
+	// Progress is called with the state after every record
+	Progress func(PublishState)
+}
+
+type publishResult struct {
+	rec Record
+	err error
+}
+
+// PublishStream publishes every record read from r to a pipeline. Records
+// are published concurrently, so they may arrive out of order.
+//
+// The returned state counts the records that were done. To resume after an
+// error, pass the state back in opts. Records that finished are skipped on
+// resume, so the counts stay exact, but a record that was being published
+// when the run stopped may be published again. When ctx is canceled it returns
+// without waiting for a read from r that is in progress, such as on a
+// terminal.
+func (s *PublishService) PublishStream(ctx context.Context, r io.Reader, opts *PublishStreamOptions) (*PublishState, error) {
+	if opts == nil || (opts.PipelineName == "") == (opts.PipelineID == "") {
+		return nil, errors.New("exactly one of PipelineName and PipelineID is required")
+	}
+	format := opts.Format
+	if format == "" {
+		format = StreamJSON
+	}
+	records, err := NewRecordReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	state := opts.State
+	if state == nil {
+		state = &PublishState{}
+	}
+	var limit *rateLimiter
+	if opts.Rate > 0 {
+		limit = &rateLimiter{interval: time.Duration(float64(time.Second) / opts.Rate)}
+	}
+	publish := func(ctx context.Context, data json.RawMessage) error {
+		if opts.PipelineID != "" {
+			_, err := s.PublishByID(ctx, opts.PipelineID, data)
+			return err
+		}
+		_, err := s.Publish(ctx, opts.PipelineName, data)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan publishResult)
+	results := make(chan publishResult)
+	var readErr error
+	// finished holds records done out of order, until those before them are
+	finished := map[int]bool{}
+	skipped := map[int]bool{}
+	for _, i := range state.Finished {
+		finished[i] = true
+		skipped[i] = true
+	}
+	for finished[state.Done] {
+		delete(finished, state.Done)
+		state.Done++
+	}
+	state.Finished = sortedIndexes(finished)
+	skip := state.Done
+	go func() {
+		defer close(jobs)
+		for {
+			rec, err := records.Next()
+			if err == io.EOF {
+				return
+			}
+			var recErr *RecordError
+			if err != nil && !errors.As(err, &recErr) {
+				readErr = err
+				return
+			}
+			if rec.Index < skip || skipped[rec.Index] {
+				continue
+			}
+			select {
+			case jobs <- publishResult{rec: rec, err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var job publishResult
+				select {
+				case j, ok := <-jobs:
+					if !ok {
+						return
+					}
+					job = j
+				case <-ctx.Done():
+					// the reader may be blocked reading r, so do not
+					// wait for it to close jobs
+					return
+				}
+				if job.err == nil {
+					if job.err = limit.wait(ctx); job.err == nil {
+						if job.err = publish(ctx, job.rec.Data); job.err != nil {
+							job.err = fmt.Errorf("%s: %w", job.rec, job.err)
+						}
+					}
+				}
+				results <- job
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var firstErr error
+	for res := range results {
+		if firstErr != nil {
+			continue
+		}
+		if res.err != nil && ctx.Err() != nil {
+			// interrupted, the record is not at fault
+			firstErr = ctx.Err()
+			continue
+		}
+		if res.err != nil {
+			if opts.Reject == nil {
+				firstErr = res.err
+				cancel()
+				continue
+			}
+			if err := opts.Reject(res.rec, res.err); err != nil {
+				firstErr = err
+				cancel()
+				continue
+			}
+			state.Rejected++
+		} else {
+			state.Published++
+		}
+		finished[res.rec.Index] = true
+		for finished[state.Done] {
+			delete(finished, state.Done)
+			state.Done++
+		}
+		state.Finished = sortedIndexes(finished)
+		if opts.Checkpoint != nil {
+			if err := opts.Checkpoint(state); err != nil {
+				firstErr = err
+				cancel()
+				continue
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(*state)
+		}
+	}
+	if firstErr != nil {
+		return state, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		// readErr may still be written by a reader blocked on r
+		return state, err
+	}
+	return state, readErr
+}
+
+func sortedIndexes(set map[int]bool) []int {
+	if len(set) == 0 {
+		return nil
+	}
+	out := make([]int, 0, len(set))
+	for i := range set {
+		out = append(out, i)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// rateLimiter spaces calls to wait at least interval apart. A nil limiter
+// does not wait.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
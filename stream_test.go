@@ -0,0 +1,231 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// publishRecorder records the bodies published, failing those containing
+// "fail"
+type publishRecorder struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newPublishRecorder(t *testing.T, mux *http.ServeMux) *publishRecorder {
+	p := &publishRecorder{}
+	mux.HandleFunc("/authenticated/publish", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "orders", r.URL.Query().Get("name"))
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body := strings.TrimSpace(string(b))
+		if strings.Contains(body, "fail") {
+			http.Error(w, "rejected", http.StatusBadRequest)
+			return
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.bodies = append(p.bodies, body)
+	})
+	return p
+}
+
+func (p *publishRecorder) sorted() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bodies := append([]string{}, p.bodies...)
+	sort.Strings(bodies)
+	return bodies
+}
+
+func readRecords(t *testing.T, input string, format StreamFormat) ([]string, []error) {
+	rr, err := NewRecordReader(strings.NewReader(input), format)
+	require.NoError(t, err)
+	var data []string
+	var errs []error
+	for {
+		rec, err := rr.Next()
+		if err == io.EOF {
+			return data, errs
+		}
+		var recErr *RecordError
+		if errors.As(err, &recErr) {
+			errs = append(errs, err)
+			continue
+		}
+		require.NoError(t, err)
+		data = append(data, string(rec.Data))
+	}
+}
+
+func TestRecordReader(t *testing.T) {
+	data, _ := readRecords(t, ` [{"a": 1}, {"a": 2}]`, StreamJSON)
+	require.Equal(t, []string{`{"a": 1}`, `{"a": 2}`}, data)
+	data, _ = readRecords(t, `{"a": 1} {"a": 2}`, StreamJSON)
+	require.Equal(t, []string{`{"a": 1}`, `{"a": 2}`}, data)
+	data, _ = readRecords(t, "", StreamJSON)
+	require.Empty(t, data)
+
+	data, errs := readRecords(t, "{\"a\": 1}\n\n{\"a\":\n{\"a\": 3}\n", StreamNDJSON)
+	require.Equal(t, []string{`{"a": 1}`, `{"a": 3}`}, data)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "line 3: invalid JSON")
+
+	data, _ = readRecords(t, "id,name\n1,\"Smith, Jo\"\n2,Lee\n", StreamCSV)
+	require.Equal(t, []string{`{"id":"1","name":"Smith, Jo"}`, `{"id":"2","name":"Lee"}`}, data)
+	data, errs = readRecords(t, "id,name\n1\n\"Smith, Jo\",Lee,x\n2,Lee\n", StreamCSV)
+	require.Equal(t, []string{`{"id":"2","name":"Lee"}`}, data)
+	require.Len(t, errs, 2)
+	require.EqualError(t, errs[0], "line 2: 1 fields, the header has 2")
+	var recErr *RecordError
+	require.True(t, errors.As(errs[1], &recErr))
+	require.Equal(t, `"Smith, Jo",Lee,x`, string(recErr.Record.Data))
+	rr, err := NewRecordReader(strings.NewReader("id,id\n1,2\n"), StreamCSV)
+	require.NoError(t, err)
+	_, err = rr.Next()
+	require.EqualError(t, err, `CSV header has an empty or duplicate column "id"`)
+
+	_, err = NewRecordReader(strings.NewReader(""), "xml")
+	require.EqualError(t, err, `unknown stream format "xml"`)
+	require.Equal(t, StreamNDJSON, StreamFormatFromPath("events.jsonl"))
+	require.Equal(t, StreamCSV, StreamFormatFromPath("events.CSV"))
+	require.Equal(t, StreamJSON, StreamFormatFromPath("-"))
+}
+
+func TestPublishStream(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	recorder := newPublishRecorder(t, mux)
+	ctx := context.Background()
+
+	input := `{"n": 1}
+{"n": "fail"}
+not json
+{"n": 4}
+`
+	var rejected []string
+	var checkpoints []int
+	progress := 0
+	state, err := client.Publish.PublishStream(ctx, strings.NewReader(input), &PublishStreamOptions{
+		PipelineName: "orders",
+		Format:       StreamNDJSON,
+		Concurrency:  3,
+		Reject: func(rec Record, err error) error {
+			rejected = append(rejected, err.Error())
+			return nil
+		},
+		Checkpoint: func(s *PublishState) error {
+			checkpoints = append(checkpoints, s.Done)
+			return nil
+		},
+		Progress: func(PublishState) { progress++ },
+	})
+	require.NoError(t, err)
+	require.Equal(t, &PublishState{Done: 4, Published: 2, Rejected: 2}, state)
+	require.Equal(t, []string{`{"n":1}`, `{"n":4}`}, recorder.sorted())
+	sort.Strings(rejected)
+	require.Len(t, rejected, 2)
+	require.Contains(t, rejected[0], "line 2: ")
+	require.Equal(t, "line 3: invalid JSON", rejected[1])
+	require.Equal(t, 4, checkpoints[len(checkpoints)-1])
+	require.True(t, sort.IntsAreSorted(checkpoints))
+	require.Equal(t, 4, progress)
+}
+
+func TestPublishStream_Resume(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	recorder := newPublishRecorder(t, mux)
+	ctx := context.Background()
+	input := `[{"n": 1}, {"n": "fail"}, {"n": 3}]`
+
+	// without a reject func the first failure stops the stream
+	state, err := client.Publish.PublishStream(ctx, strings.NewReader(input), &PublishStreamOptions{PipelineName: "orders"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "record 2: ")
+	require.Equal(t, &PublishState{Done: 1, Published: 1}, state)
+
+	state.Done++
+	state, err = client.Publish.PublishStream(ctx, strings.NewReader(input), &PublishStreamOptions{PipelineName: "orders", State: state})
+	require.NoError(t, err)
+	require.Equal(t, &PublishState{Done: 3, Published: 2}, state)
+	// the third record may have gone out before the failure stopped the
+	// first run, so it is published at least once
+	published := recorder.sorted()
+	require.Equal(t, `{"n":1}`, published[0])
+	require.Equal(t, `{"n":3}`, published[len(published)-1])
+
+	_, err = client.Publish.PublishStream(ctx, strings.NewReader(input), &PublishStreamOptions{})
+	require.EqualError(t, err, "exactly one of PipelineName and PipelineID is required")
+}
+
+func TestPublishStream_ResumeFinished(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	recorder := newPublishRecorder(t, mux)
+	input := `[{"n": 1}, {"n": 2}, {"n": 3}, {"n": 4}]`
+
+	// the first and third records finished before the run stopped
+	state := &PublishState{Done: 1, Published: 2, Finished: []int{2}}
+	state, err := client.Publish.PublishStream(context.Background(), strings.NewReader(input), &PublishStreamOptions{
+		PipelineName: "orders",
+		Concurrency:  2,
+		State:        state,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &PublishState{Done: 4, Published: 4}, state)
+	require.Equal(t, []string{`{"n":2}`, `{"n":4}`}, recorder.sorted())
+
+	// finished records right after Done count as done at once
+	state = &PublishState{Done: 1, Published: 2, Finished: []int{1}}
+	state, err = client.Publish.PublishStream(context.Background(), strings.NewReader(`[{}, {}]`), &PublishStreamOptions{
+		PipelineName: "orders",
+		State:        state,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &PublishState{Done: 2, Published: 2}, state)
+}
+
+func TestPublishStream_Rate(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	newPublishRecorder(t, mux)
+
+	start := time.Now()
+	input := strings.Repeat("{}\n", 5)
+	state, err := client.Publish.PublishStream(context.Background(), strings.NewReader(input), &PublishStreamOptions{
+		PipelineName: "orders",
+		Format:       StreamNDJSON,
+		Concurrency:  5,
+		Rate:         50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 5, state.Published)
+	// the first record goes out at once and the rest 20ms apart
+	require.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
+
+func TestPublishStream_Canceled(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+	newPublishRecorder(t, mux)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rejects := 0
+	_, err := client.Publish.PublishStream(ctx, strings.NewReader(`{}`), &PublishStreamOptions{
+		PipelineName: "orders",
+		Reject:       func(Record, error) error { rejects++; return nil },
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Zero(t, rejects)
+}
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -19,9 +21,26 @@ const (
 
 // Client is the primary interface for all Swarm service handlers
 type Client struct {
-	apiKey     string
+	customerID string
 	httpClient *http.Client
 
+	// supplies the API key of each request. SetAPIKey can replace it while
+	// requests are running.
+	mu          sync.RWMutex
+	credentials CredentialsProvider
+
+	// skip client side validation before Create and Update requests
+	skipValidation bool
+
+	// reports fields in responses that this client does not know about
+	reportUnknown func(*UnknownFieldsError)
+
+	// refuse to create resources whose name is already in use
+	uniqueNames bool
+
+	// require confirmation for DeleteAll
+	safeMode bool
+
 	// Base URL for most API requests
 	BaseURL *url.URL
 
@@ -40,8 +59,43 @@ type service struct {
 	client *Client
 }
 
+// ClientOption configures optional Client behavior in NewClient
+type ClientOption func(*Client)
+
+// SkipValidation disables the client side validation services run before
+// Create and Update requests, leaving all checks to the API.
+func SkipValidation() ClientOption {
+	return func(c *Client) {
+		c.skipValidation = true
+	}
+}
+
+// StrictDecoding makes the client call report with an *UnknownFieldsError
+// whenever a response contains fields this client does not know about, which
+// is useful for noticing when the API has changed. Requests still succeed and
+// return the decoded value, since the API did what was asked; a failed Create
+// would invite a retry creating a duplicate. A nil report logs the fields
+// with the log package.
+func StrictDecoding(report func(*UnknownFieldsError)) ClientOption {
+	return func(c *Client) {
+		if report == nil {
+			report = func(err *UnknownFieldsError) { log.Printf("swarm: %s", err) }
+		}
+		c.reportUnknown = report
+	}
+}
+
+// WithHTTPClient makes the client send requests with hc, for example to
+// share a transport between clients or to add metrics and middleware as an
+// http.RoundTripper
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
 // NewClient is a constructor for Client
-func NewClient(customerID string, apiKey string) *Client {
+func NewClient(customerID string, apiKey string, opts ...ClientOption) *Client {
 	baseURL, err := url.Parse(baseURLv1)
 	if err != nil {
 		panic(err)
@@ -54,7 +108,8 @@ func NewClient(customerID string, apiKey string) *Client {
 
 	c := &Client{
 		BaseURL:     baseURL,
-		apiKey:      apiKey,
+		customerID:  customerID,
+		credentials: StaticCredentials(apiKey),
 		CustomerURL: customerURL,
 		httpClient: &http.Client{
 			Timeout: time.Minute,
@@ -66,30 +121,71 @@ func NewClient(customerID string, apiKey string) *Client {
 	c.Publish = &PublishService{client: c}
 	c.WebhookActions = &WebhookActionsService{client: c}
 
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	return c
 }
 
+// APIKey returns the API key requests are authenticated with. It takes a
+// context and returns an error because the key comes from the client's
+// CredentialsProvider, which may have to read or fetch it.
+func (s *Client) APIKey(ctx context.Context) (string, error) {
+	return s.provider().APIKey(ctx)
+}
+
+// SetAPIKey makes requests sent from now on authenticate with apiKey,
+// replacing the credentials provider. It is safe to call while other
+// requests are running.
+func (s *Client) SetAPIKey(apiKey string) {
+	s.setProvider(StaticCredentials(apiKey))
+}
+
+func (s *Client) setProvider(p CredentialsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials = p
+}
+
+func (s *Client) provider() CredentialsProvider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.credentials
+}
+
 // NewRequestWithBaseURL builds an http.Request using the BaseURL.
 func (s *Client) NewRequestWithBaseURL(method string, path string, body interface{}) (*http.Request, error) {
-	u, err := s.BaseURL.Parse(path)
-	if err != nil {
-		return nil, err
-	}
-	return s.NewRequest(method, u, body)
+	return s.newRequest(context.Background(), s.BaseURL, method, path, body)
 }
 
 // NewRequestWithCustomerURL builds an http.Request using the CustomerURL.
 func (s *Client) NewRequestWithCustomerURL(method string, path string, body interface{}) (*http.Request, error) {
-	u, err := s.CustomerURL.Parse(path)
+	return s.newRequest(context.Background(), s.CustomerURL, method, path, body)
+}
+
+// newRequest builds a request for path relative to base, see
+// NewRequestWithContext
+func (s *Client) newRequest(ctx context.Context, base *url.URL, method string, path string, body interface{}) (*http.Request, error) {
+	u, err := base.Parse(path)
 	if err != nil {
 		return nil, err
 	}
-	return s.NewRequest(method, u, body)
+	return s.NewRequestWithContext(ctx, method, u, body)
 }
 
 // NewRequest builds an http.Request object. The body parameter will
-// automatically be encoded to json to send in a request.
+// automatically be encoded to json to send in a request. The request is
+// authenticated with the key the client's credentials provider supplies now,
+// see NewRequestWithContext to bound how long that may take.
 func (s *Client) NewRequest(method string, u *url.URL, body interface{}) (*http.Request, error) {
+	return s.NewRequestWithContext(context.Background(), method, u, body)
+}
+
+// NewRequestWithContext is NewRequest for a request carrying ctx, which is
+// also passed to the credentials provider, so a slow provider gives up when
+// ctx is done.
+func (s *Client) NewRequestWithContext(ctx context.Context, method string, u *url.URL, body interface{}) (*http.Request, error) {
 	var buf io.ReadWriter
 	if body != nil {
 		buf = &bytes.Buffer{}
@@ -101,7 +197,7 @@ func (s *Client) NewRequest(method string, u *url.URL, body interface{}) (*http.
 		}
 	}
 
-	req, err := http.NewRequest(method, u.String(), buf)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
 	if err != nil {
 		return nil, err
 	}
@@ -110,17 +206,21 @@ func (s *Client) NewRequest(method string, u *url.URL, body interface{}) (*http.
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+	if err := s.authenticate(ctx, req); err != nil {
+		return nil, err
+	}
 
 	return req, nil
 }
 
 // DoRequest will execute an http.Request. The entire http.Response will be
 // returned. The JSON response will be decoded into the value pointed to v.
+// The Authorization header of req is only replaced when the API answers 401
+// and the credentials are refreshed.
 func (s *Client) DoRequest(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
 	req = req.WithContext(ctx)
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -146,7 +246,48 @@ func (s *Client) DoRequest(ctx context.Context, req *http.Request, v interface{}
 		}
 		if decErr != nil {
 			err = decErr
+		} else if s.reportUnknown != nil {
+			if fields := UnknownFields(v); len(fields) > 0 {
+				s.reportUnknown(&UnknownFieldsError{Method: req.Method, Path: req.URL.Path, Fields: fields})
+			}
 		}
 	}
 	return resp, err
 }
+
+// send sends req. When the API answers 401 and the credentials provider can
+// refresh, it is refreshed and the request is sent once more with the new key.
+func (s *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	resp, err := s.httpClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	refresher, ok := s.provider().(Refresher)
+	if !ok || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	if err := refresher.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("refreshing credentials after a 401 response: %w", err)
+	}
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.authenticate(ctx, retry); err != nil {
+		return nil, err
+	}
+	return s.httpClient.Do(retry)
+}
+
+func (s *Client) authenticate(ctx context.Context, req *http.Request) error {
+	key, err := s.APIKey(ctx)
+	if err != nil {
+		return fmt.Errorf("getting API key: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	return nil
+}
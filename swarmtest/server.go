@@ -0,0 +1,374 @@
+// Package swarmtest provides an in-memory stand-in for the Swarm API, for
+// testing code that uses the swarm package without a real account.
+package swarmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+)
+
+const (
+	pipelinesPath      = "/authenticated/pipelines"
+	webhookActionsPath = "/authenticated/webhookactions"
+	apiTokensPath      = "/authenticated/apitokens"
+	publishPath        = "/authenticated/publish"
+)
+
+// Message is a message received by the publish endpoint. Exactly one of
+// PipelineName and PipelineID is set.
+type Message struct {
+	PipelineName string
+	PipelineID   string
+	Body         json.RawMessage
+}
+
+// Server serves the pipelines, webhook actions, API tokens and publish
+// endpoints from memory. Resources are kept as decoded JSON so fields the
+// swarm package does not know about survive updates like they do in the API.
+// Server is safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the server
+	URL string
+
+	server    *httptest.Server
+	mu        sync.Mutex
+	resources map[string][]map[string]interface{}
+	tokens    []string
+	published []Message
+	nextID    int
+	writes    int
+}
+
+// NewServer starts a server. Close it when done.
+func NewServer() *Server {
+	s := &Server{resources: map[string][]map[string]interface{}{}}
+	mux := http.NewServeMux()
+	for _, path := range []string{pipelinesPath, webhookActionsPath} {
+		path := path
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) { s.serveCollection(w, r, path) })
+		mux.HandleFunc(path+"/", func(w http.ResponseWriter, r *http.Request) {
+			s.serveItem(w, r, path, strings.TrimPrefix(r.URL.Path, path+"/"))
+		})
+	}
+	mux.HandleFunc(apiTokensPath, s.serveTokens)
+	mux.HandleFunc(apiTokensPath+"/", s.serveTokens)
+	mux.HandleFunc(publishPath, s.servePublish)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Profile returns a configuration profile for the server, named "swarmtest"
+// like its customer ID and API key
+func (s *Server) Profile() *swarm.Profile {
+	return &swarm.Profile{
+		Name:        "swarmtest",
+		CustomerID:  "swarmtest",
+		APIKey:      "swarmtest",
+		BaseURL:     s.URL,
+		CustomerURL: s.URL,
+	}
+}
+
+// Client returns a client created from Profile, the way
+// swarm.NewClientFromConfig creates clients
+func (s *Server) Client(opts ...swarm.ClientOption) *swarm.Client {
+	c, err := s.Profile().NewClient(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// WriteConfig writes a configuration file, see swarm.LoadConfig, whose
+// default profile is Profile, so code loading its client with
+// swarm.NewClientFromConfig can be pointed at the server through SWARM_CONFIG
+func (s *Server) WriteConfig(path string) error {
+	p := s.Profile()
+	b, err := json.Marshal(&swarm.Config{
+		DefaultProfile: p.Name,
+		Profiles:       map[string]*swarm.Profile{p.Name: p},
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+// AddPipeline stores a pipeline as if it had been created and returns it
+// with its ID. An ID is generated unless p already has one.
+func (s *Server) AddPipeline(p *swarm.Pipeline) *swarm.Pipeline {
+	out := new(swarm.Pipeline)
+	s.add(pipelinesPath, p, out)
+	return out
+}
+
+// AddWebhookAction stores a webhook action as if it had been created and
+// returns it with its ID. An ID is generated unless a already has one.
+func (s *Server) AddWebhookAction(a *swarm.WebhookAction) *swarm.WebhookAction {
+	out := new(swarm.WebhookAction)
+	s.add(webhookActionsPath, a, out)
+	return out
+}
+
+// AddAPIToken stores an API token
+func (s *Server) AddAPIToken(token swarm.APIToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = append(s.tokens, string(token))
+}
+
+// Pipelines returns the stored pipelines in creation order
+func (s *Server) Pipelines() []*swarm.Pipeline {
+	var p []*swarm.Pipeline
+	s.list(pipelinesPath, &p)
+	return p
+}
+
+// WebhookActions returns the stored webhook actions in creation order
+func (s *Server) WebhookActions() []*swarm.WebhookAction {
+	var a []*swarm.WebhookAction
+	s.list(webhookActionsPath, &a)
+	return a
+}
+
+// APITokens returns the stored API tokens in creation order
+func (s *Server) APITokens() []swarm.APIToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := make([]swarm.APIToken, len(s.tokens))
+	for i, t := range s.tokens {
+		tokens[i] = swarm.APIToken(t)
+	}
+	return tokens
+}
+
+// Published returns the messages received by the publish endpoint in the
+// order they arrived
+func (s *Server) Published() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.published...)
+}
+
+// Writes returns the number of create, update and delete requests served
+func (s *Server) Writes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writes
+}
+
+func (s *Server) add(path string, in interface{}, out interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := mustDecode(mustEncode(in))
+	if id, _ := item["id"].(string); id == "" {
+		item["id"] = s.newID()
+	}
+	s.resources[path] = append(s.resources[path], item)
+	if err := json.Unmarshal(mustEncode(item), out); err != nil {
+		panic(err)
+	}
+}
+
+func (s *Server) list(path string, out interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := json.Unmarshal(mustEncode(s.items(path)), out); err != nil {
+		panic(err)
+	}
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprintf("SWARMTEST%017d", s.nextID)
+}
+
+func (s *Server) items(path string) []map[string]interface{} {
+	if items := s.resources[path]; items != nil {
+		return items
+	}
+	return []map[string]interface{}{}
+}
+
+func (s *Server) serveCollection(w http.ResponseWriter, r *http.Request, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case "GET":
+		writeJSON(w, s.items(path))
+	case "POST":
+		item, ok := decodeBody(w, r)
+		if !ok {
+			return
+		}
+		s.writes++
+		item["id"] = s.newID()
+		s.resources[path] = append(s.resources[path], item)
+		writeJSON(w, item)
+	case "PUT":
+		item, ok := decodeBody(w, r)
+		if !ok {
+			return
+		}
+		id, _ := item["id"].(string)
+		s.put(w, path, id, item)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) serveItem(w http.ResponseWriter, r *http.Request, path string, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == "DELETE" && id == "all" {
+		s.writes++
+		delete(s.resources, path)
+		return
+	}
+
+	i := s.index(path, id)
+	if i < 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, s.resources[path][i])
+	case "PUT":
+		item, ok := decodeBody(w, r)
+		if !ok {
+			return
+		}
+		s.put(w, path, id, item)
+	case "DELETE":
+		s.writes++
+		items := s.resources[path]
+		s.resources[path] = append(items[:i:i], items[i+1:]...)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) put(w http.ResponseWriter, path string, id string, item map[string]interface{}) {
+	i := s.index(path, id)
+	if i < 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.writes++
+	item["id"] = id
+	s.resources[path][i] = item
+	writeJSON(w, item)
+}
+
+func (s *Server) index(path string, id string) int {
+	for i, item := range s.resources[path] {
+		if item["id"] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) serveTokens(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, apiTokensPath), "/")
+	switch {
+	case r.Method == "GET" && token == "":
+		tokens := s.tokens
+		if tokens == nil {
+			tokens = []string{}
+		}
+		writeJSON(w, tokens)
+	case r.Method == "POST" && token == "":
+		s.writes++
+		t := s.newID()
+		s.tokens = append(s.tokens, t)
+		writeJSON(w, t)
+	case r.Method == "DELETE" && token == "all":
+		s.writes++
+		s.tokens = nil
+	case r.Method == "DELETE":
+		for i, t := range s.tokens {
+			if t == token {
+				s.writes++
+				s.tokens = append(s.tokens[:i:i], s.tokens[i+1:]...)
+				return
+			}
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) servePublish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m := Message{
+		PipelineName: r.URL.Query().Get("name"),
+		PipelineID:   r.URL.Query().Get("id"),
+		Body:         body,
+	}
+	if (m.PipelineName == "") == (m.PipelineID == "") {
+		http.Error(w, "exactly one of name and id is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published = append(s.published, m)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	var item map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return item, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func mustEncode(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func mustDecode(b []byte) map[string]interface{} {
+	var item map[string]interface{}
+	if err := json.Unmarshal(b, &item); err != nil {
+		panic(err)
+	}
+	return item
+}
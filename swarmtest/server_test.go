@@ -0,0 +1,68 @@
+package swarmtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	action := server.AddWebhookAction(&swarm.WebhookAction{Name: "hook", URL: "https://example.com", Method: "POST"})
+	require.NotEmpty(t, action.ID)
+
+	p, _, err := client.Pipelines.Create(ctx, &swarm.Pipeline{
+		Name:    "orders",
+		Steps:   []swarm.PipelineSteps{swarm.FilterStep("return true;")},
+		Outputs: []string{action.ID},
+	})
+	require.NoError(t, err)
+	got, _, err := client.Pipelines.Get(ctx, p.ID)
+	require.NoError(t, err)
+	require.Equal(t, p, got)
+
+	p.Name = "renamed"
+	_, _, err = client.Pipelines.UpdateByID(ctx, p.ID, p)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", server.Pipelines()[0].Name)
+
+	_, err = client.WebhookActions.Delete(ctx, action.ID)
+	require.NoError(t, err)
+	require.Empty(t, server.WebhookActions())
+	_, _, err = client.WebhookActions.Get(ctx, action.ID)
+	require.Error(t, err)
+
+	token, _, err := client.APITokens.Create(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []swarm.APIToken{*token}, server.APITokens())
+
+	_, err = client.Publish.Publish(ctx, "renamed", map[string]int{"a": 1})
+	require.NoError(t, err)
+	require.Equal(t, []Message{{PipelineName: "renamed", Body: []byte(`{"a":1}`)}}, server.Published())
+	require.Equal(t, 4, server.Writes())
+}
+
+func TestServer_WriteConfig(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddPipeline(&swarm.Pipeline{Name: "orders"})
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, server.WriteConfig(path))
+	t.Setenv(swarm.EnvConfig, path)
+	t.Setenv(swarm.EnvProfile, "")
+	t.Setenv(swarm.EnvCustomerID, "")
+
+	client, err := swarm.NewClientFromConfig("")
+	require.NoError(t, err)
+	pipelines, _, err := client.Pipelines.List(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, server.Pipelines(), pipelines)
+}
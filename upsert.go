@@ -0,0 +1,140 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// UpsertResult reports what Upsert did
+type UpsertResult int
+
+const (
+	// UpsertUnchanged means the existing resource already matched and no
+	// write was made
+	UpsertUnchanged UpsertResult = iota
+	// UpsertCreated means no resource had the name and one was created
+	UpsertCreated
+	// UpsertUpdated means the resource with the name was updated
+	UpsertUpdated
+)
+
+func (r UpsertResult) String() string {
+	switch r {
+	case UpsertUnchanged:
+		return "unchanged"
+	case UpsertCreated:
+		return "created"
+	case UpsertUpdated:
+		return "updated"
+	}
+	return "unknown"
+}
+
+// Upsert creates the pipeline if no pipeline has its name, or updates the
+// one that does. The ID of desired is ignored. Nothing is written when the
+// existing pipeline already encodes the same as desired, treating null and
+// empty lists alike. Unknown fields of the existing pipeline, its steps and
+// its stitch configs are kept unless desired sets its own. Updates go through
+// CompareAndUpdate, so a concurrent change returns ErrConflict, and several
+// pipelines with the name return ErrAmbiguousName. The result is
+// UpsertUnchanged whenever an error is returned.
+func (s *PipelinesService) Upsert(ctx context.Context, desired *Pipeline) (*Pipeline, UpsertResult, *http.Response, error) {
+	if desired == nil {
+		return nil, UpsertUnchanged, nil, nilError("pipeline")
+	}
+	existing, resp, err := s.GetByName(ctx, desired.Name)
+	if errors.Is(err, ErrNotFound) {
+		p, resp, err := s.Create(ctx, desired)
+		if err != nil {
+			return nil, UpsertUnchanged, resp, err
+		}
+		return p, UpsertCreated, resp, nil
+	}
+	if err != nil {
+		return nil, UpsertUnchanged, resp, err
+	}
+
+	spec := *desired
+	spec.ID = existing.ID
+	spec.Extra = keepExtra(spec.Extra, existing.Extra)
+	// copies keep empty lists empty, so they are not sent as null
+	if desired.Steps != nil {
+		spec.Steps = make([]PipelineSteps, len(desired.Steps))
+		copy(spec.Steps, desired.Steps)
+	}
+	for i := range spec.Steps {
+		if i < len(existing.Steps) {
+			spec.Steps[i].Extra = keepExtra(spec.Steps[i].Extra, existing.Steps[i].Extra)
+		}
+	}
+	if desired.StitchConfigs != nil {
+		spec.StitchConfigs = make([]PipelineStitchConfig, len(desired.StitchConfigs))
+		copy(spec.StitchConfigs, desired.StitchConfigs)
+	}
+	for i := range spec.StitchConfigs {
+		if i < len(existing.StitchConfigs) {
+			spec.StitchConfigs[i].Extra = keepExtra(spec.StitchConfigs[i].Extra, existing.StitchConfigs[i].Extra)
+		}
+	}
+	if equalJSON(existing, &spec) {
+		return existing, UpsertUnchanged, resp, nil
+	}
+
+	p, resp, err := s.CompareAndUpdate(ctx, existing, &spec)
+	if err != nil {
+		return nil, UpsertUnchanged, resp, err
+	}
+	return p, UpsertUpdated, resp, nil
+}
+
+// Upsert creates the webhook action if no action has its name, or updates
+// the one that does, see PipelinesService.Upsert. Unknown fields of the
+// existing action and its headers are kept.
+func (s *WebhookActionsService) Upsert(ctx context.Context, desired *WebhookAction) (*WebhookAction, UpsertResult, *http.Response, error) {
+	if desired == nil {
+		return nil, UpsertUnchanged, nil, nilError("webhook action")
+	}
+	existing, resp, err := s.GetByName(ctx, desired.Name)
+	if errors.Is(err, ErrNotFound) {
+		a, resp, err := s.Create(ctx, desired)
+		if err != nil {
+			return nil, UpsertUnchanged, resp, err
+		}
+		return a, UpsertCreated, resp, nil
+	}
+	if err != nil {
+		return nil, UpsertUnchanged, resp, err
+	}
+
+	spec := *desired
+	spec.ID = existing.ID
+	spec.Extra = keepExtra(spec.Extra, existing.Extra)
+	if desired.Headers != nil {
+		spec.Headers = make([]WebhookActionsHeader, len(desired.Headers))
+		copy(spec.Headers, desired.Headers)
+	}
+	for i := range spec.Headers {
+		if i < len(existing.Headers) {
+			spec.Headers[i].Extra = keepExtra(spec.Headers[i].Extra, existing.Headers[i].Extra)
+		}
+	}
+	if equalJSON(existing, &spec) {
+		return existing, UpsertUnchanged, resp, nil
+	}
+
+	a, resp, err := s.CompareAndUpdate(ctx, existing, &spec)
+	if err != nil {
+		return nil, UpsertUnchanged, resp, err
+	}
+	return a, UpsertUpdated, resp, nil
+}
+
+// keepExtra returns extra, or existing when extra is nil
+func keepExtra(extra, existing map[string]json.RawMessage) map[string]json.RawMessage {
+	if extra == nil {
+		return existing
+	}
+	return extra
+}
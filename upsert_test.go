@@ -0,0 +1,180 @@
+package swarm_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	swarm "github.com/catalystsquad/swarm-client-go"
+	"github.com/catalystsquad/swarm-client-go/swarmtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelines_Upsert(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	desired, err := swarm.NewPipeline("pipeline1").
+		Filter("return true;").
+		OutputTo("ACTION").
+		Build()
+	require.NoError(t, err)
+
+	got, result, _, err := client.Pipelines.Upsert(ctx, desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertCreated, result)
+	require.NotEmpty(t, got.ID)
+	require.Equal(t, 1, server.Writes())
+
+	got, result, _, err = client.Pipelines.Upsert(ctx, desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUnchanged, result)
+	require.Equal(t, 1, server.Writes())
+
+	desired.MaxRetries = 3
+	updated, result, _, err := client.Pipelines.Upsert(ctx, desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUpdated, result)
+	require.Equal(t, got.ID, updated.ID)
+	require.Equal(t, 3, updated.MaxRetries)
+	require.Len(t, server.Pipelines(), 1)
+}
+
+func TestPipelines_UpsertNilSlices(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	server.AddPipeline(&swarm.Pipeline{
+		Name:          "pipeline1",
+		Steps:         []swarm.PipelineSteps{{Function: "return true;", Type: swarm.StepTypeFilter, Outputs: []string{}}},
+		Outputs:       []string{},
+		StitchConfigs: []swarm.PipelineStitchConfig{},
+	})
+
+	// the API returns empty lists for the nil slices it was sent
+	_, result, _, err := server.Client().Pipelines.Upsert(context.Background(), &swarm.Pipeline{
+		Name:  "pipeline1",
+		Steps: []swarm.PipelineSteps{{Function: "return true;", Type: swarm.StepTypeFilter}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUnchanged, result)
+	require.Zero(t, server.Writes())
+}
+
+func TestPipelines_UpsertSendsEmptyLists(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	server.AddPipeline(&swarm.Pipeline{
+		Name:          "pipeline1",
+		Steps:         []swarm.PipelineSteps{swarm.FilterStep("return true;")},
+		StitchConfigs: []swarm.PipelineStitchConfig{{StitchPipelineID: "P2", Key: "id", TTL: 60}},
+	})
+
+	// an explicit empty list is sent as one, not as null
+	_, result, _, err := server.Client().Pipelines.Upsert(context.Background(), &swarm.Pipeline{
+		Name:          "pipeline1",
+		Steps:         []swarm.PipelineSteps{},
+		StitchConfigs: []swarm.PipelineStitchConfig{},
+	})
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUpdated, result)
+	stored := server.Pipelines()[0]
+	require.NotNil(t, stored.Steps)
+	require.Empty(t, stored.Steps)
+	require.NotNil(t, stored.StitchConfigs)
+	require.Empty(t, stored.StitchConfigs)
+}
+
+func TestPipelines_UpsertKeepsUnknownFields(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	server.AddPipeline(&swarm.Pipeline{
+		Name: "pipeline1",
+		Steps: []swarm.PipelineSteps{{
+			Function: "return true;",
+			Type:     swarm.StepTypeFilter,
+			Required: true,
+			Extra:    map[string]json.RawMessage{"timeout": json.RawMessage(`5`)},
+		}},
+		Extra: map[string]json.RawMessage{"labels": json.RawMessage(`["a"]`)},
+	})
+
+	desired, err := swarm.NewPipeline("pipeline1").Filter("return true;").Build()
+	require.NoError(t, err)
+	_, result, _, err := client.Pipelines.Upsert(context.Background(), desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUnchanged, result)
+
+	desired.PersistOutput = true
+	_, result, _, err = client.Pipelines.Upsert(context.Background(), desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUpdated, result)
+	require.Equal(t, []string{"steps[0].timeout", "labels"}, swarm.UnknownFields(server.Pipelines()[0]))
+	require.Nil(t, desired.Steps[0].Extra)
+}
+
+func TestPipelines_UpsertError(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	got, result, _, err := client.Pipelines.Upsert(context.Background(), &swarm.Pipeline{})
+	require.Error(t, err)
+	require.Nil(t, got)
+	require.Equal(t, swarm.UpsertUnchanged, result)
+
+	_, result, _, err = client.Pipelines.Upsert(context.Background(), nil)
+	require.EqualError(t, err, "validation failed: pipeline is nil")
+	require.Equal(t, swarm.UpsertUnchanged, result)
+}
+
+func TestWebhookActions_Upsert(t *testing.T) {
+	server := swarmtest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	action := &swarm.WebhookAction{
+		Name:         "webhook1",
+		URL:          "https://example.com",
+		Method:       "POST",
+		Headers:      []swarm.WebhookActionsHeader{{Name: "a", Value: "b", Extra: map[string]json.RawMessage{"secret": json.RawMessage(`true`)}}},
+		SuccessCodes: []int{200},
+	}
+	server.AddWebhookAction(action)
+	server.AddWebhookAction(action)
+	ctx := context.Background()
+
+	desired := *action
+	desired.Headers = []swarm.WebhookActionsHeader{{Name: "a", Value: "b"}}
+	_, _, _, err := client.WebhookActions.Upsert(ctx, &desired)
+	require.ErrorIs(t, err, swarm.ErrAmbiguousName)
+
+	desired.Name = "webhook2"
+	_, result, _, err := client.WebhookActions.Upsert(ctx, &desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertCreated, result)
+	require.Equal(t, "created", result.String())
+	require.Len(t, server.WebhookActions(), 3)
+
+	server.AddWebhookAction(&swarm.WebhookAction{
+		Name:    "webhook3",
+		URL:     "https://example.com",
+		Method:  "POST",
+		Headers: action.Headers,
+	})
+	desired.Name = "webhook3"
+	desired.SuccessCodes = nil
+	_, result, _, err = client.WebhookActions.Upsert(ctx, &desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUnchanged, result)
+
+	// removing every header sends an empty list
+	desired.Headers = []swarm.WebhookActionsHeader{}
+	_, result, _, err = client.WebhookActions.Upsert(ctx, &desired)
+	require.NoError(t, err)
+	require.Equal(t, swarm.UpsertUpdated, result)
+	stored := server.WebhookActions()[3]
+	require.NotNil(t, stored.Headers)
+	require.Empty(t, stored.Headers)
+}
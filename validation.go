@@ -0,0 +1,218 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FieldError describes a single invalid field. Field is the JSON path of the
+// field, such as "steps[0].function", or empty when the whole value is
+// invalid.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError is returned by the Validate methods and by services that
+// validate input before sending it. It holds every invalid field found, not
+// just the first.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// validator collects field errors
+type validator struct {
+	errs []*FieldError
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// nest adds the field errors of a nested value's Validate result
+func (v *validator) nest(field string, err error) {
+	if err == nil {
+		return
+	}
+	if verr, ok := err.(*ValidationError); ok {
+		for _, fe := range verr.Errors {
+			v.errs = append(v.errs, &FieldError{Field: field + "." + fe.Field, Message: fe.Message})
+		}
+		return
+	}
+	v.add(field, "%s", err)
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+type skipValidationKey struct{}
+
+// WithoutValidation returns a context that makes the service calls it is
+// passed to skip client side validation, like SkipValidation does for every
+// call of a client
+func WithoutValidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipValidationKey{}, true)
+}
+
+// validate runs the Validate method of a value unless the client was created
+// with SkipValidation or ctx came from WithoutValidation
+func (c *Client) validate(ctx context.Context, i interface{ Validate() error }) error {
+	if c.skipValidation || ctx.Value(skipValidationKey{}) != nil {
+		return nil
+	}
+	return i.Validate()
+}
+
+// nilError is the validation error of a nil pointer
+func nilError(kind string) error {
+	return &ValidationError{Errors: []*FieldError{{Message: kind + " is nil"}}}
+}
+
+// Validate checks a pipeline for mistakes the API would reject. It returns a
+// *ValidationError listing every invalid field.
+func (p *Pipeline) Validate() error {
+	if p == nil {
+		return nilError("pipeline")
+	}
+	v := &validator{}
+	if strings.TrimSpace(p.Name) == "" {
+		v.add("name", "is required")
+	}
+	for i, s := range p.Steps {
+		v.nest(fmt.Sprintf("steps[%d]", i), s.Validate())
+	}
+	for i, o := range p.Outputs {
+		if strings.TrimSpace(o) == "" {
+			v.add(fmt.Sprintf("outputs[%d]", i), "must not be empty")
+		}
+	}
+	for i, sc := range p.StitchConfigs {
+		v.nest(fmt.Sprintf("stitchConfigs[%d]", i), sc.Validate())
+	}
+	if p.RetryIntervalSeconds < 0 {
+		v.add("retryIntervalSeconds", "must not be negative")
+	}
+	if p.MaxRetries < -1 {
+		v.add("maxRetries", "must be -1 for infinite retries or greater")
+	}
+	return v.err()
+}
+
+// Validate checks a pipeline step. Field names in the returned
+// *ValidationError are relative to the step.
+func (s PipelineSteps) Validate() error {
+	v := &validator{}
+	if strings.TrimSpace(s.Function) == "" {
+		v.add("function", "is required")
+	}
+	if strings.TrimSpace(string(s.Type)) == "" {
+		v.add("type", "is required")
+	}
+	for i, o := range s.Outputs {
+		if strings.TrimSpace(o) == "" {
+			v.add(fmt.Sprintf("outputs[%d]", i), "must not be empty")
+		}
+	}
+	return v.err()
+}
+
+// Validate checks a stitch config. Field names in the returned
+// *ValidationError are relative to the stitch config.
+func (c PipelineStitchConfig) Validate() error {
+	v := &validator{}
+	if strings.TrimSpace(c.StitchPipelineID) == "" {
+		v.add("stitchPipelineId", "is required")
+	}
+	if strings.TrimSpace(c.Key) == "" {
+		v.add("key", "is required")
+	}
+	if c.TTL < 0 {
+		v.add("ttl", "must not be negative")
+	}
+	return v.err()
+}
+
+// webhookMethods are the HTTP methods a webhook action may use
+var webhookMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodHead,
+	http.MethodOptions,
+}
+
+// Validate checks a webhook action for mistakes the API would reject. It
+// returns a *ValidationError listing every invalid field.
+func (a *WebhookAction) Validate() error {
+	if a == nil {
+		return nilError("webhook action")
+	}
+	v := &validator{}
+	if strings.TrimSpace(a.Name) == "" {
+		v.add("name", "is required")
+	}
+
+	if strings.TrimSpace(a.URL) == "" {
+		v.add("url", "is required")
+	} else if u, err := url.Parse(a.URL); err != nil {
+		v.add("url", "is not a valid URL: %s", err)
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add("url", "must be an absolute http or https URL")
+	}
+
+	validMethod := false
+	for _, m := range webhookMethods {
+		if a.Method == m {
+			validMethod = true
+		}
+	}
+	if !validMethod {
+		v.add("method", "must be one of %s", strings.Join(webhookMethods, ", "))
+	}
+
+	for i, h := range a.Headers {
+		if strings.TrimSpace(h.Name) == "" {
+			v.add(fmt.Sprintf("headers[%d].name", i), "is required")
+		}
+	}
+	if a.MaxConcurrentRequests < 0 {
+		v.add("maxConcurrentRequests", "must not be negative")
+	}
+	for i, code := range a.SuccessCodes {
+		if code < 100 || code > 599 {
+			v.add(fmt.Sprintf("successCodes[%d]", i), "%d is not an HTTP status code between 100 and 599", code)
+		}
+	}
+	if a.RetryIntervalSeconds < 0 {
+		v.add("retryIntervalSeconds", "must not be negative")
+	}
+	if a.MaxRetries < -1 {
+		v.add("maxRetries", "must be -1 for infinite retries or greater")
+	}
+	return v.err()
+}
@@ -0,0 +1,113 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	out := map[string]string{}
+	for _, fe := range verr.Errors {
+		out[fe.Field] = fe.Message
+	}
+	return out
+}
+
+func TestPipeline_Validate(t *testing.T) {
+	require.NoError(t, testPipelineObj.Validate())
+
+	p := &Pipeline{
+		Steps:         []PipelineSteps{{Type: "filter"}, {Function: "return true", Outputs: []string{""}}},
+		StitchConfigs: []PipelineStitchConfig{{Key: "orderId", TTL: -1}},
+		MaxRetries:    -2,
+	}
+	got := fieldErrors(t, p.Validate())
+	require.Equal(t, map[string]string{
+		"name":                              "is required",
+		"steps[0].function":                 "is required",
+		"steps[1].type":                     "is required",
+		"steps[1].outputs[0]":               "must not be empty",
+		"stitchConfigs[0].stitchPipelineId": "is required",
+		"stitchConfigs[0].ttl":              "must not be negative",
+		"maxRetries":                        "must be -1 for infinite retries or greater",
+	}, got)
+}
+
+func TestWebhookAction_Validate(t *testing.T) {
+	require.NoError(t, testWebhookActionObj.Validate())
+
+	a := &WebhookAction{
+		Name:                  "webhook1",
+		URL:                   "example.com/hook",
+		Method:                "FETCH",
+		Headers:               []WebhookActionsHeader{{Value: "x"}},
+		MaxConcurrentRequests: -1,
+		SuccessCodes:          []int{200, 99, 600},
+		RetryIntervalSeconds:  -5,
+	}
+	got := fieldErrors(t, a.Validate())
+	require.Equal(t, map[string]string{
+		"url":                   "must be an absolute http or https URL",
+		"method":                "must be one of GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS",
+		"headers[0].name":       "is required",
+		"maxConcurrentRequests": "must not be negative",
+		"successCodes[1]":       "99 is not an HTTP status code between 100 and 599",
+		"successCodes[2]":       "600 is not an HTTP status code between 100 and 599",
+		"retryIntervalSeconds":  "must not be negative",
+	}, got)
+
+	got = fieldErrors(t, (&WebhookAction{Name: "w", Method: "POST"}).Validate())
+	require.Equal(t, map[string]string{"url": "is required"}, got)
+}
+
+func TestValidation_BeforeCreate(t *testing.T) {
+	client, mux, teardown := setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/authenticated/webhookactions", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, testWebhookActionJSON)
+	})
+
+	ctx := context.Background()
+	invalid := &WebhookAction{Name: "webhook1", Method: "POST"}
+	_, _, err := client.WebhookActions.Create(ctx, invalid)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, 0, requests)
+
+	// nil is rejected rather than dereferenced
+	_, _, err = client.WebhookActions.Create(ctx, nil)
+	require.EqualError(t, err, "validation failed: webhook action is nil")
+	_, _, err = client.Pipelines.Update(ctx, nil)
+	require.EqualError(t, err, "validation failed: pipeline is nil")
+	require.Equal(t, 0, requests)
+
+	_, _, err = client.WebhookActions.Create(WithoutValidation(ctx), invalid)
+	require.NoError(t, err)
+	require.Equal(t, 1, requests)
+
+	client.skipValidation = true
+	_, _, err = client.WebhookActions.Create(ctx, invalid)
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+}
+
+func TestSkipValidation(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient("TESTCUSTOMER", "TESTAPITOKEN", SkipValidation())
+	require.True(t, client.skipValidation)
+	require.NoError(t, client.validate(ctx, &Pipeline{}))
+
+	client = NewClient("TESTCUSTOMER", "TESTAPITOKEN")
+	require.Error(t, client.validate(ctx, &Pipeline{}))
+	require.NoError(t, client.validate(WithoutValidation(ctx), &Pipeline{}))
+}
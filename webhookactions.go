@@ -2,6 +2,7 @@ package swarm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -11,7 +12,9 @@ type WebhookActionsService service
 
 const actionWebhookPath = "authenticated/webhookactions"
 
-// WebhookAction is the generic type used for creating and returning webhooks
+// WebhookAction is the generic type used for creating and returning webhooks.
+// MaxRetries is MaxRetriesInfinite to retry forever, see RetryPolicy for a
+// typed view of the retry fields.
 type WebhookAction struct {
 	ID                    string                 `json:"id"`
 	Name                  string                 `json:"name"`
@@ -23,17 +26,25 @@ type WebhookAction struct {
 	SuccessCodes          []int                  `json:"successCodes"`
 	RetryIntervalSeconds  int                    `json:"retryIntervalSeconds"`
 	MaxRetries            int                    `json:"maxRetries"`
+
+	// Extra holds fields returned by the API that this client does not
+	// know about. They are sent back unchanged on Create and Update.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // WebhookActionsHeader is a nested type for the WebhookActions struct
 type WebhookActionsHeader struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
+
+	// Extra holds fields returned by the API that this client does not
+	// know about. They are sent back unchanged on Create and Update.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // List all action webhooks
 func (s *WebhookActionsService) List(ctx context.Context) ([]*WebhookAction, *http.Response, error) {
-	req, err := s.client.NewRequestWithBaseURL("GET", actionWebhookPath, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "GET", actionWebhookPath, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -50,7 +61,7 @@ func (s *WebhookActionsService) List(ctx context.Context) ([]*WebhookAction, *ht
 // Get an action webhook by ID
 func (s *WebhookActionsService) Get(ctx context.Context, id string) (*WebhookAction, *http.Response, error) {
 	path := fmt.Sprintf("%s/%s", actionWebhookPath, id)
-	req, err := s.client.NewRequestWithBaseURL("GET", path, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "GET", path, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -66,7 +77,17 @@ func (s *WebhookActionsService) Get(ctx context.Context, id string) (*WebhookAct
 
 // Create an action webhook
 func (s *WebhookActionsService) Create(ctx context.Context, i *WebhookAction) (*WebhookAction, *http.Response, error) {
-	req, err := s.client.NewRequestWithBaseURL("POST", actionWebhookPath, i)
+	if err := s.client.validate(ctx, i); err != nil {
+		return nil, nil, err
+	}
+	if s.client.uniqueNames && i != nil {
+		_, resp, err := s.GetByName(ctx, i.Name)
+		if err := duplicateNameError("webhook action", i.Name, err); err != nil {
+			return nil, resp, err
+		}
+	}
+
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "POST", actionWebhookPath, i)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -82,8 +103,12 @@ func (s *WebhookActionsService) Create(ctx context.Context, i *WebhookAction) (*
 
 // Update an action webhook
 func (s *WebhookActionsService) Update(ctx context.Context, webhookID string, i *WebhookAction) (*WebhookAction, *http.Response, error) {
+	if err := s.client.validate(ctx, i); err != nil {
+		return nil, nil, err
+	}
+
 	path := fmt.Sprintf("%s/%s", actionWebhookPath, webhookID)
-	req, err := s.client.NewRequestWithBaseURL("PUT", path, i)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "PUT", path, i)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -97,10 +122,17 @@ func (s *WebhookActionsService) Update(ctx context.Context, webhookID string, i
 	return a, resp, nil
 }
 
+// UpdateByID updates the action webhook with the given ID. It is the same as
+// Update, named like PipelinesService.UpdateByID so both services can be used
+// alike.
+func (s *WebhookActionsService) UpdateByID(ctx context.Context, id string, i *WebhookAction) (*WebhookAction, *http.Response, error) {
+	return s.Update(ctx, id, i)
+}
+
 // Delete an action webhook by ID
 func (s *WebhookActionsService) Delete(ctx context.Context, id string) (*http.Response, error) {
 	path := fmt.Sprintf("%s/%s", actionWebhookPath, id)
-	req, err := s.client.NewRequestWithBaseURL("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "DELETE", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -109,10 +141,22 @@ func (s *WebhookActionsService) Delete(ctx context.Context, id string) (*http.Re
 	return resp, err
 }
 
-// DeleteAll action webhooks
-func (s *WebhookActionsService) DeleteAll(ctx context.Context) (*http.Response, error) {
+// DeleteAll action webhooks, see PipelinesService.DeleteAll for the options
+func (s *WebhookActionsService) DeleteAll(ctx context.Context, opts ...DeleteAllOption) (*http.Response, error) {
+	ok, err := s.client.guardDeleteAll(ctx, "webhook action", opts, func() ([]string, error) {
+		actions, _, err := s.List(ctx)
+		var out []string
+		for _, a := range actions {
+			out = append(out, fmt.Sprintf("%s %q", a.ID, a.Name))
+		}
+		return out, err
+	})
+	if !ok {
+		return nil, err
+	}
+
 	path := fmt.Sprintf("%s/all", actionWebhookPath)
-	req, err := s.client.NewRequestWithBaseURL("DELETE", path, nil)
+	req, err := s.client.newRequest(ctx, s.client.BaseURL, "DELETE", path, nil)
 	if err != nil {
 		return nil, err
 	}
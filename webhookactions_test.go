@@ -111,6 +111,10 @@ func TestWebhookActions_Update(t *testing.T) {
 
 	require.NoError(t, err)
 	require.Equal(t, testWebhookActionObj, got)
+
+	got, _, err = client.WebhookActions.UpdateByID(ctx, testWebhookActionID, testWebhookActionObj)
+	require.NoError(t, err)
+	require.Equal(t, testWebhookActionObj, got)
 }
 
 func TestWebhookActions_Delete(t *testing.T) {